@@ -0,0 +1,49 @@
+// Command polkasim simulates Polka, Chirpy's payment provider. By default it
+// runs the integration scenarios against a running server and exits non-zero
+// if any fail; with -event it delivers a single signed webhook instead.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/polkasim"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+	server := flag.String("server", "http://localhost:8080", "Chirpy server URL")
+	secret := flag.String("secret", os.Getenv("POLKA_WEBHOOK_SECRET"), "Webhook signing secret")
+	event := flag.String("event", "", "Deliver a single event of this type instead of running scenarios")
+	userID := flag.Int("user", 0, "User ID for -event")
+	plan := flag.String("plan", "", "Plan for -event")
+	flag.Parse()
+	if *secret == "" {
+		log.Fatal("A webhook secret is required (-secret or POLKA_WEBHOOK_SECRET)")
+	}
+	sim := polkasim.New(*server, []byte(*secret))
+
+	if *event != "" {
+		e := sim.NewEvent(*event, *userID, *plan)
+		status, err := sim.Deliver(e)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Delivered %s (%s): %d", e.Event, e.ID, status)
+		return
+	}
+
+	failures := sim.RunAll()
+	for _, scenario := range polkasim.Scenarios {
+		if err, failed := failures[scenario.Name]; failed {
+			log.Printf("FAIL %s: %s", scenario.Name, err)
+		} else {
+			log.Printf("PASS %s", scenario.Name)
+		}
+	}
+	if len(failures) > 0 {
+		log.Fatalf("%d of %d scenarios failed", len(failures), len(polkasim.Scenarios))
+	}
+}
@@ -0,0 +1,133 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+)
+
+const (
+	ContentType    = "application/activity+json"
+	PublicAudience = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+var Context = []string{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Icon              *Image     `json:"icon,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         PublicKey  `json:"publicKey"`
+}
+
+type Image struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Activity is an incoming or outgoing activity. Object is kept raw since it
+// may be a link or an embedded object depending on the activity type.
+type Activity struct {
+	Context any             `json:"@context,omitempty"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor"`
+	To      []string        `json:"to,omitempty"`
+	CC      []string        `json:"cc,omitempty"`
+	Object  json.RawMessage `json:"object"`
+}
+
+type Note struct {
+	Context      any      `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo"`
+	Content      string   `json:"content"`
+	Published    string   `json:"published"`
+	Updated      string   `json:"updated,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	InReplyTo    string   `json:"inReplyTo,omitempty"`
+	To           []string `json:"to,omitempty"`
+	CC           []string `json:"cc,omitempty"`
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int    `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// WebFinger is a JSON Resource Descriptor as returned by WebFinger.
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+// ObjectID returns the ID of an activity's object, whether it was sent as a
+// link or embedded.
+func (a Activity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(a.Object, &object)
+	return object.ID
+}
+
+// ObjectType returns the type of an embedded object, or "" for links.
+func (a Activity) ObjectType() string {
+	var object struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(a.Object, &object)
+	return object.Type
+}
+
+// HTMLContent renders plain chirp text as note content.
+func HTMLContent(text string) string {
+	return "<p>" + strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") + "</p>"
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+var breakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p>`)
+
+// PlainText reduces remote note content, which is HTML, to text.
+func PlainText(content string) string {
+	content = breakPattern.ReplaceAllString(content, "\n")
+	return strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(content, "")))
+}
@@ -0,0 +1,200 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxResponseSize bounds documents fetched from remote servers.
+const maxResponseSize = 1 << 20
+
+const maxRedirects = 5
+
+var ErrInvalidAccount = errors.New("invalid account")
+var ErrForbiddenURL = errors.New("URL is not a public HTTPS address")
+var ErrRejected = errors.New("delivery rejected")
+
+// Signer signs outgoing requests as a local actor.
+type Signer struct {
+	KeyID string
+	Key   *rsa.PrivateKey
+}
+
+type Client struct {
+	http     *http.Client
+	insecure bool
+}
+
+// NewClient returns a client that only talks HTTPS to public addresses, since
+// remote servers choose the URLs it requests. insecure lifts both limits for
+// running servers side by side in development.
+func NewClient(insecure bool) *Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !insecure {
+		dialer.Control = refusePrivateAddress
+	}
+	c := &Client{insecure: insecure}
+	c.http = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return c.checkURL(req.URL)
+		},
+	}
+	return c
+}
+
+// checkURL refuses URLs the client should not request.
+func (c *Client) checkURL(target *url.URL) error {
+	if target.Host == "" || (target.Scheme != "https" && (target.Scheme != "http" || !c.insecure)) {
+		return fmt.Errorf("%w: %s", ErrForbiddenURL, target.Redacted())
+	}
+	return nil
+}
+
+// refusePrivateAddress stops connections to loopback, private, link-local and
+// other non-public addresses. It runs after DNS resolution, so hostnames that
+// resolve to internal addresses are caught too.
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addr.Unmap()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenURL, addr)
+	}
+	return nil
+}
+
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicAddress(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// FetchActor dereferences a remote actor. The document must describe the
+// actor at actorURL and own its key, or a server could pass off another
+// server's actors, or their keys, as its own.
+func (c *Client) FetchActor(actorURL string) (Actor, error) {
+	actor := Actor{}
+	err := c.get(actorURL, ContentType, &actor)
+	if err != nil {
+		return Actor{}, err
+	}
+	if actor.ID == "" || actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" {
+		return Actor{}, fmt.Errorf("%s is not a usable actor", actorURL)
+	}
+	if actor.ID != actorURL || actor.PublicKey.Owner != actor.ID || KeyOwner(actor.PublicKey.ID) != actor.ID {
+		return Actor{}, fmt.Errorf("%s does not own its ID or key", actorURL)
+	}
+	return actor, nil
+}
+
+// KeyOwner returns the actor a key ID belongs to, which is the key ID without
+// its fragment.
+func KeyOwner(keyID string) string {
+	owner, _, _ := strings.Cut(keyID, "#")
+	return owner
+}
+
+// ResolveAccount finds the actor URL for an account given as user@host,
+// looked up with WebFinger, or as the actor URL itself.
+func (c *Client) ResolveAccount(account string) (string, error) {
+	account = strings.TrimPrefix(account, "@")
+	if strings.HasPrefix(account, "https://") || strings.HasPrefix(account, "http://") {
+		return account, nil
+	}
+	_, host, ok := strings.Cut(account, "@")
+	if !ok || host == "" {
+		return "", ErrInvalidAccount
+	}
+	finger := WebFinger{}
+	query := url.Values{"resource": {"acct:" + account}}
+	scheme := "https://"
+	if c.insecure {
+		scheme = "http://"
+	}
+	err := c.get(scheme+host+"/.well-known/webfinger?"+query.Encode(), "application/jrd+json", &finger)
+	if err != nil {
+		return "", err
+	}
+	for _, link := range finger.Links {
+		if link.Rel == "self" && (link.Type == ContentType || strings.Contains(link.Type, "activitystreams")) {
+			return link.Href, nil
+		}
+	}
+	return "", ErrInvalidAccount
+}
+
+func (c *Client) get(target, accept string, response any) error {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return err
+	}
+	err = c.checkURL(req.URL)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("GET %s returned %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(response)
+}
+
+// Post sends a signed activity to an inbox. Errors wrapping ErrRejected or
+// ErrForbiddenURL will not be fixed by retrying.
+func (c *Client) Post(inbox string, body []byte, signer Signer) error {
+	req, err := http.NewRequest("POST", inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	err = c.checkURL(req.URL)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	err = Sign(req, body, signer.KeyID, signer.Key)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != 408 && resp.StatusCode != 429:
+		return fmt.Errorf("%w: POST %s returned %d", ErrRejected, inbox, resp.StatusCode)
+	default:
+		return fmt.Errorf("POST %s returned %d", inbox, resp.StatusCode)
+	}
+}
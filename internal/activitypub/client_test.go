@@ -0,0 +1,131 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		insecure bool
+		ok       bool
+	}{
+		{"https://example.com/users/1", false, true},
+		{"http://example.com/users/1", false, false},
+		{"http://localhost:8080/users/1", true, true},
+		{"ftp://example.com/users/1", true, false},
+		{"file:///etc/passwd", true, false},
+		{"https:///users/1", false, false},
+	}
+	for _, tt := range tests {
+		c := NewClient(tt.insecure)
+		target, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.checkURL(target)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkURL(%s) with insecure=%t: err = %v", tt.url, tt.insecure, err)
+		}
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700:4700::1111": true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"224.0.0.1":            false,
+	}
+	for address, want := range tests {
+		if got := publicAddress(netip.MustParseAddr(address)); got != want {
+			t.Errorf("publicAddress(%s) = %t, want %t", address, got, want)
+		}
+	}
+}
+
+// The address check runs at dial time, so it also catches names that resolve
+// to internal addresses and redirects to them.
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(false)
+	_, err := c.FetchActor(server.URL + "/users/1")
+	if !errors.Is(err, ErrForbiddenURL) {
+		t.Errorf("fetching from loopback: err = %v, want ErrForbiddenURL", err)
+	}
+}
+
+func TestClientRefusesRedirectsToHTTP(t *testing.T) {
+	c := NewClient(false)
+	req := httptest.NewRequest("GET", "http://example.com/users/1", nil)
+	via := []*http.Request{httptest.NewRequest("GET", "https://example.com/users/1", nil)}
+	err := c.http.CheckRedirect(req, via)
+	if !errors.Is(err, ErrForbiddenURL) {
+		t.Errorf("redirect to http: err = %v, want ErrForbiddenURL", err)
+	}
+}
+
+// FetchActor must refuse documents that describe some other actor or use a
+// key belonging to someone else, or a server could impersonate actors it
+// does not host.
+func TestFetchActorChecksOwnership(t *testing.T) {
+	var actor Actor
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(actor)
+	}))
+	defer server.Close()
+	actorURL := server.URL + "/users/alice"
+	valid := func() Actor {
+		return Actor{
+			ID:    actorURL,
+			Type:  "Person",
+			Inbox: actorURL + "/inbox",
+			PublicKey: PublicKey{
+				ID:           actorURL + "#main-key",
+				Owner:        actorURL,
+				PublicKeyPem: "key",
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Actor)
+		ok     bool
+	}{
+		{"valid", func(*Actor) {}, true},
+		{"other ID", func(a *Actor) { a.ID = "https://victim.example/users/bob" }, false},
+		{"other key owner", func(a *Actor) { a.PublicKey.Owner = "https://victim.example/users/bob" }, false},
+		{"key ID of another actor", func(a *Actor) { a.PublicKey.ID = "https://victim.example/users/bob#main-key" }, false},
+		{"no inbox", func(a *Actor) { a.Inbox = "" }, false},
+		{"no key", func(a *Actor) { a.PublicKey.PublicKeyPem = "" }, false},
+	}
+	c := NewClient(true)
+	for _, tt := range tests {
+		actor = valid()
+		tt.modify(&actor)
+		_, err := c.FetchActor(actorURL)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package activitypub
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Queue sends deliveries with one worker per remote host, so a slow or
+// unreachable server only holds up its own deliveries, and contacts at most
+// a fixed number of hosts at once. It only tracks delivery IDs: callers keep
+// the deliveries themselves and post them in send.
+type Queue struct {
+	mu      *sync.Mutex
+	send    func(id int)
+	slots   chan struct{}
+	pending map[string][]int
+}
+
+func NewQueue(workers int, send func(id int)) *Queue {
+	return &Queue{
+		mu:      &sync.Mutex{},
+		send:    send,
+		slots:   make(chan struct{}, workers),
+		pending: make(map[string][]int),
+	}
+}
+
+// Add queues a delivery to an inbox, to be sent once at has passed.
+func (q *Queue) Add(id int, inbox string, at time.Time) {
+	if wait := time.Until(at); wait > 0 {
+		time.AfterFunc(wait, func() { q.Add(id, inbox, time.Time{}) })
+		return
+	}
+	host := inbox
+	if inboxURL, err := url.Parse(inbox); err == nil {
+		host = inboxURL.Host
+	}
+	q.mu.Lock()
+	_, working := q.pending[host]
+	q.pending[host] = append(q.pending[host], id)
+	q.mu.Unlock()
+	if !working {
+		go q.work(host)
+	}
+}
+
+// work sends a host's deliveries in order until none are left.
+func (q *Queue) work(host string) {
+	for {
+		q.mu.Lock()
+		ids := q.pending[host]
+		if len(ids) == 0 {
+			delete(q.pending, host)
+			q.mu.Unlock()
+			return
+		}
+		q.pending[host] = ids[1:]
+		q.mu.Unlock()
+
+		q.slots <- struct{}{}
+		q.send(ids[0])
+		<-q.slots
+	}
+}
@@ -0,0 +1,77 @@
+package activitypub
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A slow host must hold up only its own deliveries, which go out one at a
+// time and in order.
+func TestQueueWorksPerHost(t *testing.T) {
+	var mu sync.Mutex
+	sent := map[string][]int{}
+	inFlight := map[string]int{}
+	release := make(chan struct{})
+	done := make(chan int, 10)
+
+	hosts := map[int]string{1: "slow", 2: "slow", 3: "fast", 4: "fast"}
+	q := NewQueue(4, func(id int) {
+		host := hosts[id]
+		mu.Lock()
+		inFlight[host]++
+		if inFlight[host] > 1 {
+			t.Errorf("two deliveries to %s at once", host)
+		}
+		mu.Unlock()
+		if host == "slow" {
+			<-release
+		}
+		mu.Lock()
+		inFlight[host]--
+		sent[host] = append(sent[host], id)
+		mu.Unlock()
+		done <- id
+	})
+	for _, id := range []int{1, 2, 3, 4} {
+		q.Add(id, "https://"+hosts[id]+".example/inbox", time.Time{})
+	}
+
+	for range 2 {
+		select {
+		case id := <-done:
+			if hosts[id] != "fast" {
+				t.Fatalf("delivery %d finished while its host was blocked", id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("fast host was held up by the slow one")
+		}
+	}
+	close(release)
+	for range 2 {
+		<-done
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for host, want := range map[string]string{"slow": "[1 2]", "fast": "[3 4]"} {
+		if got := fmt.Sprint(sent[host]); got != want {
+			t.Errorf("%s received %s, want %s", host, got, want)
+		}
+	}
+}
+
+func TestQueueWaitsUntilDue(t *testing.T) {
+	done := make(chan time.Time, 1)
+	q := NewQueue(1, func(int) { done <- time.Now() })
+	due := time.Now().Add(100 * time.Millisecond)
+	q.Add(1, "https://example.com/inbox", due)
+	select {
+	case sentAt := <-done:
+		if sentAt.Before(due) {
+			t.Errorf("sent %s before it was due", due.Sub(sentAt))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("delivery was never sent")
+	}
+}
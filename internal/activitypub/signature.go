@@ -0,0 +1,165 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Requests are signed with draft-cavage HTTP Signatures using rsa-sha256, as
+// Mastodon and most other servers expect.
+
+const maxClockSkew = time.Hour
+
+var ErrInvalidSignature = errors.New("invalid HTTP signature")
+
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+func GenerateKey() (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+	der := x509.MarshalPKCS1PrivateKey(key)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der})), nil
+}
+
+func ParsePrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func PublicKeyPEM(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func ParsePublicKey(pemKey string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("invalid public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return key, nil
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Sign adds Date, Digest and Signature headers to a request with the given
+// body, which may be empty for GET requests.
+func Sign(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Digest", digest(body))
+	sum := sha256.Sum256([]byte(signingString(req, req.URL.Host, signedHeaders)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+func signingString(req *http.Request, host string, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, header := range headers {
+		switch header {
+		case "(request-target)":
+			lines = append(lines, header+": "+strings.ToLower(req.Method)+" "+req.URL.RequestURI())
+		case "host":
+			lines = append(lines, "host: "+host)
+		default:
+			lines = append(lines, header+": "+req.Header.Get(header))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SignatureKeyID returns the key a request claims to be signed with.
+func SignatureKeyID(req *http.Request) (string, error) {
+	params, err := parseSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+	return params["keyId"], nil
+}
+
+// Verify checks a received request's signature against the sender's public
+// key. The signature must cover the request target, host, date and, when
+// there is a body, its digest, and the date must be recent.
+func Verify(req *http.Request, body []byte, key *rsa.PublicKey) error {
+	params, err := parseSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return err
+	}
+	if algorithm := params["algorithm"]; algorithm != "" && algorithm != "rsa-sha256" && algorithm != "hs2019" {
+		return ErrInvalidSignature
+	}
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+		if req.Header.Get("Digest") != digest(body) {
+			return ErrInvalidSignature
+		}
+	}
+	for _, header := range required {
+		if !slices.Contains(headers, header) {
+			return ErrInvalidSignature
+		}
+	}
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil || time.Since(date).Abs() > maxClockSkew {
+		return ErrInvalidSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	sum := sha256.Sum256([]byte(signingString(req, req.Host, headers)))
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature) != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func parseSignature(header string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[name] = strings.Trim(value, `"`)
+	}
+	if params["keyId"] == "" || params["signature"] == "" {
+		return nil, ErrInvalidSignature
+	}
+	return params, nil
+}
@@ -0,0 +1,129 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	pemKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(pemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signedRequest signs a POST as a client would and returns it as the
+// receiving server sees it.
+func signedRequest(t *testing.T, key *rsa.PrivateKey, body []byte) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest("POST", "https://chirpy.example/users/1/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Sign(req, body, "https://remote.example/users/alice#main-key", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = req.URL.Host
+	return req
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key := newTestKey(t)
+	body := []byte(`{"type":"Follow"}`)
+	req := signedRequest(t, key, body)
+
+	keyID, err := SignatureKeyID(req)
+	if err != nil || keyID != "https://remote.example/users/alice#main-key" {
+		t.Errorf("SignatureKeyID = %q, %v", keyID, err)
+	}
+	if KeyOwner(keyID) != "https://remote.example/users/alice" {
+		t.Errorf("KeyOwner(%q) = %q", keyID, KeyOwner(keyID))
+	}
+	err = Verify(req, body, &key.PublicKey)
+	if err != nil {
+		t.Errorf("Verify: %s", err)
+	}
+
+	pemKey, err := PublicKeyPEM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := ParsePublicKey(pemKey)
+	if err != nil || !public.Equal(&key.PublicKey) {
+		t.Errorf("public key did not round trip through PEM: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := newTestKey(t)
+	body := []byte(`{"type":"Follow"}`)
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request) ([]byte, *rsa.PublicKey)
+	}{
+		{"changed body", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			return []byte(`{"type":"Delete"}`), &key.PublicKey
+		}},
+		{"changed digest and body", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			forged := []byte(`{"type":"Delete"}`)
+			req.Header.Set("Digest", digest(forged))
+			return forged, &key.PublicKey
+		}},
+		{"other inbox", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			req.URL.Path = "/users/2/inbox"
+			return body, &key.PublicKey
+		}},
+		{"other host", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			req.Host = "elsewhere.example"
+			return body, &key.PublicKey
+		}},
+		{"stale date", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			req.Header.Set("Date", time.Now().Add(-2*maxClockSkew).UTC().Format(http.TimeFormat))
+			return body, &key.PublicKey
+		}},
+		{"wrong key", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return body, &other.PublicKey
+		}},
+		{"digest not signed", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			signature := req.Header.Get("Signature")
+			req.Header.Set("Signature", strings.Replace(signature, " digest", "", 1))
+			return body, &key.PublicKey
+		}},
+		{"unsupported algorithm", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			signature := req.Header.Get("Signature")
+			req.Header.Set("Signature", strings.Replace(signature, "rsa-sha256", "hmac-sha256", 1))
+			return body, &key.PublicKey
+		}},
+		{"no signature", func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			req.Header.Del("Signature")
+			return body, &key.PublicKey
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, key, body)
+			received, public := tt.tamper(req)
+			err := Verify(req, received, public)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
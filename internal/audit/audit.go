@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+type Event struct {
+	ID        int               `json:"id"`
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"type"`
+	ActorID   int               `json:"actor_id,omitempty"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent"`
+	Outcome   string            `json:"outcome"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+type Filter struct {
+	ActorID int
+	Type    string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+// Log is an append-only record of security events stored as JSON lines.
+// Events are never modified; they are only removed once older than the
+// retention period.
+type Log struct {
+	path      string
+	retention time.Duration
+	events    []Event
+	nextID    int
+	mu        *sync.RWMutex
+}
+
+func Open(path string, retention time.Duration) (*Log, error) {
+	l := &Log{
+		path:      path,
+		retention: retention,
+		events:    []Event{},
+		nextID:    1,
+		mu:        &sync.RWMutex{},
+	}
+	file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if json.Unmarshal(scanner.Bytes(), &event) != nil {
+			continue
+		}
+		l.events = append(l.events, event)
+		if event.ID >= l.nextID {
+			l.nextID = event.ID + 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, l.Prune()
+}
+
+func (l *Log) Record(event Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	event.ID = l.nextID
+	event.Time = time.Now().UTC()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	l.nextID++
+	l.events = append(l.events, event)
+	return nil
+}
+
+// Query returns matching events, newest first.
+func (l *Log) Query(filter Filter) []Event {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	results := []Event{}
+	for i := len(l.events) - 1; i >= 0; i-- {
+		event := l.events[i]
+		if filter.ActorID != 0 && event.ActorID != filter.ActorID {
+			continue
+		}
+		if filter.Type != "" && event.Type != filter.Type {
+			continue
+		}
+		if filter.Outcome != "" && event.Outcome != filter.Outcome {
+			continue
+		}
+		if !filter.Since.IsZero() && event.Time.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && event.Time.After(filter.Until) {
+			continue
+		}
+		results = append(results, event)
+		if filter.Limit > 0 && len(results) == filter.Limit {
+			break
+		}
+	}
+	return results
+}
+
+// Prune drops events older than the retention period and rewrites the log.
+// A zero retention keeps events forever.
+func (l *Log) Prune() error {
+	if l.retention <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cutoff := time.Now().Add(-l.retention)
+	keep := 0
+	for keep < len(l.events) && l.events[keep].Time.Before(cutoff) {
+		keep++
+	}
+	if keep == 0 {
+		return nil
+	}
+
+	tmpPath := l.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, event := range l.events[keep:] {
+		data, err := json.Marshal(event)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		writer.Write(append(data, '\n'))
+	}
+	err = writer.Flush()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpPath, l.path)
+	if err != nil {
+		return err
+	}
+	l.events = append([]Event{}, l.events[keep:]...)
+	return nil
+}
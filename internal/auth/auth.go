@@ -46,27 +46,103 @@ func IssueRefreshToken(userID int, secret []byte, db db.Database) (string, error
 	return signedToken, err
 }
 
-func AuthenticateAccessToken(r *http.Request, secret []byte) (int, error) {
-	tokenString := strings.Split(r.Header.Get("Authorization"), " ")[1]
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+// AccessClaims are carried by every access token. Tokens issued to third-party
+// OAuth clients additionally carry a client ID and space-separated scope;
+// first-party tokens leave both empty and are not restricted.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+func IssueScopedAccessToken(userID int, clientID, scope string, secret []byte) (string, AccessClaims, error) {
+	jti, err := RandomString(16)
+	if err != nil {
+		return "", AccessClaims{}, err
+	}
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy-access",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Subject:   fmt.Sprint(userID),
+			ID:        jti,
+		},
+		Scope:    scope,
+		ClientID: clientID,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString(secret)
+	return signedToken, claims, err
+}
+
+func IssueOAuthRefreshToken(userID int, clientID, scope string, secret []byte, db db.Database) (string, error) {
+	jti, err := RandomString(16)
+	if err != nil {
+		return "", err
+	}
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy-oauth-refresh",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 1440)),
+			Subject:   fmt.Sprint(userID),
+			ID:        jti,
+		},
+		Scope:    scope,
+		ClientID: clientID,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	err = db.AddToken(signedToken)
+	if err != nil {
+		return "", err
+	}
+
+	return signedToken, err
+}
+
+func BearerToken(r *http.Request) (string, error) {
+	header := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(header) != 2 || header[0] != "Bearer" {
+		return "", errors.New("no bearer token")
+	}
+	return header[1], nil
+}
+
+// ParseToken verifies the signature, expiry and issuer of any Chirpy token.
+func ParseToken(tokenString, issuer string, secret []byte) (AccessClaims, error) {
+	claims := AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
 		return secret, nil
-	})
+	}, jwt.WithValidMethods([]string{"HS256"}))
 
 	if err != nil || !token.Valid {
-		return 0, errors.New("token invalid")
+		return AccessClaims{}, errors.New("token invalid")
 	}
 
-	issuer, err := token.Claims.GetIssuer()
-	if err != nil || issuer != "chirpy-access" {
-		return 0, errors.New("issuer invalid")
+	if claims.Issuer != issuer {
+		return AccessClaims{}, errors.New("issuer invalid")
 	}
 
-	id, err := token.Claims.GetSubject()
+	return claims, nil
+}
+
+func AuthenticateAccessToken(r *http.Request, secret []byte) (int, error) {
+	tokenString, err := BearerToken(r)
+	if err != nil {
+		return 0, err
+	}
+	claims, err := ParseToken(tokenString, "chirpy-access", secret)
 	if err != nil {
 		return 0, err
 	}
 
-	idInt, err := strconv.Atoi(id)
+	idInt, err := strconv.Atoi(claims.Subject)
 	if err != nil {
 		return 0, err
 	}
@@ -75,7 +151,10 @@ func AuthenticateAccessToken(r *http.Request, secret []byte) (int, error) {
 }
 
 func AuthenticateRefreshToken(r *http.Request, secret []byte, db db.Database) (int, error) {
-	tokenString := strings.Split(r.Header.Get("Authorization"), " ")[1]
+	tokenString, err := BearerToken(r)
+	if err != nil {
+		return 0, err
+	}
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return secret, nil
 	})
@@ -105,14 +184,3 @@ func AuthenticateRefreshToken(r *http.Request, secret []byte, db db.Database) (i
 
 	return idInt, nil
 }
-
-func AuthenticateAPI(r *http.Request, expected string) error {
-	apiKey := strings.Split(r.Header.Get("Authorization"), " ")
-	if apiKey[0] != "ApiKey" {
-		return errors.New("wrong auth type")
-	}
-	if apiKey[1] != expected {
-		return errors.New("invalid api key")
-	}
-	return nil
-}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+var ErrStaleSignature = errors.New("webhook timestamp outside tolerance")
+var ErrNoWebhookSecret = errors.New("no webhook secret configured")
+
+// SignWebhook produces a signature header of the form "t=<unix>,v1=<hex>",
+// where v1 is the HMAC-SHA256 of "<unix>.<body>".
+func SignWebhook(body, secret []byte, timestamp time.Time) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(webhookMAC(t, body, secret))
+}
+
+// VerifyWebhook checks a signature header produced by SignWebhook. Several v1
+// values may be present while the sender rotates secrets. An empty secret
+// verifies nothing, as anyone could compute its signatures.
+func VerifyWebhook(header string, body, secret []byte, tolerance time.Duration, now time.Time) error {
+	if len(secret) == 0 {
+		return ErrNoWebhookSecret
+	}
+	timestamp := ""
+	signatures := [][]byte{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	expected := webhookMAC(timestamp, body, secret)
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleSignature
+	}
+	return nil
+}
+
+func webhookMAC(timestamp string, body, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
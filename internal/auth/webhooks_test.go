@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	secret := []byte("whsec")
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1700000000, 0)
+	valid := SignWebhook(body, secret, now)
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		secret  []byte
+		wantErr error
+	}{
+		{"valid", valid, body, secret, nil},
+		{"rotated secret", valid + ",v1=00ff", body, secret, nil},
+		{"spaces after commas", "t=1700000000, v1=" + valid[len("t=1700000000,v1="):], body, secret, nil},
+		{"tampered body", valid, []byte(`{"id":"evt_2"}`), secret, ErrInvalidSignature},
+		{"wrong secret", valid, body, []byte("other"), ErrInvalidSignature},
+		{"empty secret", SignWebhook(body, nil, now), body, nil, ErrNoWebhookSecret},
+		{"no signature", "t=1700000000", body, secret, ErrInvalidSignature},
+		{"no timestamp", "v1=" + valid[len("t=1700000000,v1="):], body, secret, ErrInvalidSignature},
+		{"not hex", "t=1700000000,v1=zz", body, secret, ErrInvalidSignature},
+		{"too old", SignWebhook(body, secret, now.Add(-6*time.Minute)), body, secret, ErrStaleSignature},
+		{"too new", SignWebhook(body, secret, now.Add(6*time.Minute)), body, secret, ErrStaleSignature},
+		{"within tolerance", SignWebhook(body, secret, now.Add(-4*time.Minute)), body, secret, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhook(tt.header, tt.body, tt.secret, 5*time.Minute, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
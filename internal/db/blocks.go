@@ -0,0 +1,136 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrSelfBlock = errors.New("users cannot block or mute themselves")
+var ErrNotBlocked = errors.New("user not blocked")
+var ErrNotMuted = errors.New("user not muted")
+
+// Restriction is an entry in a block or mute list.
+type Restriction struct {
+	UserID int       `json:"id"`
+	Since  time.Time `json:"since"`
+}
+
+// BlockUser blocks a user. Follows between the two users are removed in both
+// directions, as are the notifications either has caused the other.
+func (db *Database) BlockUser(blockerID, blockedID int) error {
+	if blockerID == blockedID {
+		return ErrSelfBlock
+	}
+	db.mu.Lock()
+	if _, ok := db.Users[blockedID]; !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	if _, ok := db.Blocks[blockerID][blockedID]; ok {
+		db.mu.Unlock()
+		return nil
+	}
+	if db.Blocks[blockerID] == nil {
+		db.Blocks[blockerID] = make(map[int]time.Time)
+	}
+	db.Blocks[blockerID][blockedID] = time.Now().UTC()
+	for _, pair := range [][2]int{{blockerID, blockedID}, {blockedID, blockerID}} {
+		delete(db.Follows[pair[0]], pair[1])
+		if len(db.Follows[pair[0]]) == 0 {
+			delete(db.Follows, pair[0])
+		}
+	}
+	for id, n := range db.Notifications {
+		if (n.UserID == blockerID && n.ActorID == blockedID) || (n.UserID == blockedID && n.ActorID == blockerID) {
+			delete(db.Notifications, id)
+		}
+	}
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+func (db *Database) UnblockUser(blockerID, blockedID int) error {
+	return db.removeRelation(db.Blocks, blockerID, blockedID, ErrNotBlocked)
+}
+
+func (db *Database) MuteUser(muterID, mutedID int) error {
+	if muterID == mutedID {
+		return ErrSelfBlock
+	}
+	db.mu.Lock()
+	if _, ok := db.Users[mutedID]; !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	if _, ok := db.Mutes[muterID][mutedID]; ok {
+		db.mu.Unlock()
+		return nil
+	}
+	if db.Mutes[muterID] == nil {
+		db.Mutes[muterID] = make(map[int]time.Time)
+	}
+	db.Mutes[muterID][mutedID] = time.Now().UTC()
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+func (db *Database) UnmuteUser(muterID, mutedID int) error {
+	return db.removeRelation(db.Mutes, muterID, mutedID, ErrNotMuted)
+}
+
+func (db *Database) removeRelation(relations map[int]map[int]time.Time, fromID, toID int, errMissing error) error {
+	db.mu.Lock()
+	if _, ok := relations[fromID][toID]; !ok {
+		db.mu.Unlock()
+		return errMissing
+	}
+	delete(relations[fromID], toID)
+	if len(relations[fromID]) == 0 {
+		delete(relations, fromID)
+	}
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// GetBlocked returns the users someone has blocked, most recent first.
+func (db *Database) GetBlocked(userID int) []Restriction {
+	return db.listRelation(db.Blocks, userID)
+}
+
+// GetMuted returns the users someone has muted, most recent first.
+func (db *Database) GetMuted(userID int) []Restriction {
+	return db.listRelation(db.Mutes, userID)
+}
+
+func (db *Database) listRelation(relations map[int]map[int]time.Time, userID int) []Restriction {
+	db.mu.RLock()
+	users := []Restriction{}
+	for otherID, since := range relations[userID] {
+		users = append(users, Restriction{UserID: otherID, Since: since})
+	}
+	db.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].Since.Equal(users[j].Since) {
+			return users[i].Since.After(users[j].Since)
+		}
+		return users[i].UserID < users[j].UserID
+	})
+	return users
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func (db *Database) IsBlocked(userID, otherID int) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, blocked := db.Blocks[userID][otherID]
+	_, blockedBy := db.Blocks[otherID][userID]
+	return blocked || blockedBy
+}
+
+func (db *Database) IsMuted(muterID, mutedID int) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, ok := db.Mutes[muterID][mutedID]
+	return ok
+}
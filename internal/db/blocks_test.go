@@ -0,0 +1,116 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+// Blocks work in both directions and cut the ties between the two users;
+// mutes only affect the muter.
+func TestBlocksAndMutes(t *testing.T) {
+	db := newTestDatabase(t)
+	alice, _ := db.AddUser("alice@example.com", nil)
+	bob, _ := db.AddUser("bob@example.com", nil)
+	carol, _ := db.AddUser("carol@example.com", nil)
+	for _, pair := range [][2]int{{alice.ID, bob.ID}, {bob.ID, alice.ID}, {alice.ID, carol.ID}} {
+		err := db.FollowUser(pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, n := range []Notification{
+		{UserID: alice.ID, Type: NotifyFollow, ActorID: bob.ID},
+		{UserID: bob.ID, Type: NotifyFollow, ActorID: alice.ID},
+		{UserID: carol.ID, Type: NotifyFollow, ActorID: alice.ID},
+	} {
+		_, err := db.AddNotification(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.BlockUser(alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.BlockUser(alice.ID, bob.ID)
+	if err != nil {
+		t.Errorf("blocking twice: %s", err)
+	}
+	if !db.IsBlocked(alice.ID, bob.ID) || !db.IsBlocked(bob.ID, alice.ID) {
+		t.Error("a block should apply in both directions")
+	}
+	if db.IsFollowing(alice.ID, bob.ID) || db.IsFollowing(bob.ID, alice.ID) {
+		t.Error("follows between blocked users were kept")
+	}
+	if !db.IsFollowing(alice.ID, carol.ID) {
+		t.Error("blocking removed an unrelated follow")
+	}
+	if len(db.Notifications) != 1 {
+		t.Errorf("%d notifications left, want only the one between alice and carol", len(db.Notifications))
+	}
+	if blocked := db.GetBlocked(alice.ID); len(blocked) != 1 || blocked[0].UserID != bob.ID {
+		t.Errorf("alice's blocks = %+v, want bob", blocked)
+	}
+	if blocked := db.GetBlocked(bob.ID); len(blocked) != 0 {
+		t.Errorf("bob's blocks = %+v, want none", blocked)
+	}
+
+	err = db.MuteUser(alice.ID, carol.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.IsMuted(alice.ID, carol.ID) || db.IsMuted(carol.ID, alice.ID) {
+		t.Error("a mute should only apply to the muter")
+	}
+	if db.IsBlocked(alice.ID, carol.ID) || !db.IsFollowing(alice.ID, carol.ID) {
+		t.Error("muting should not block or unfollow")
+	}
+
+	err = db.UnblockUser(alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.UnmuteUser(alice.ID, carol.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.IsBlocked(alice.ID, bob.ID) || db.IsMuted(alice.ID, carol.ID) {
+		t.Error("unblocking and unmuting did not lift the restrictions")
+	}
+	if len(db.Blocks) != 0 || len(db.Mutes) != 0 {
+		t.Errorf("empty lists were left behind: blocks %v, mutes %v", db.Blocks, db.Mutes)
+	}
+}
+
+func TestBlockAndMuteErrors(t *testing.T) {
+	db := newTestDatabase(t)
+	alice, _ := db.AddUser("alice@example.com", nil)
+	bob, _ := db.AddUser("bob@example.com", nil)
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{"block self", func() error { return db.BlockUser(alice.ID, alice.ID) }, ErrSelfBlock},
+		{"mute self", func() error { return db.MuteUser(alice.ID, alice.ID) }, ErrSelfBlock},
+		{"block unknown user", func() error { return db.BlockUser(alice.ID, 99) }, ErrInvalidUserID},
+		{"mute unknown user", func() error { return db.MuteUser(alice.ID, 99) }, ErrInvalidUserID},
+		{"unblock without a block", func() error { return db.UnblockUser(alice.ID, bob.ID) }, ErrNotBlocked},
+		{"unmute without a mute", func() error { return db.UnmuteUser(alice.ID, bob.ID) }, ErrNotMuted},
+	}
+	for _, tt := range tests {
+		if err := tt.run(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Only the blocker can lift a block.
+	err := db.BlockUser(alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UnblockUser(bob.ID, alice.ID); !errors.Is(err, ErrNotBlocked) {
+		t.Errorf("unblocking by the blocked user: err = %v, want %v", err, ErrNotBlocked)
+	}
+}
@@ -1,18 +1,81 @@
 package db
 
+import (
+	"errors"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
+)
+
+var ErrInvalidChirpID = errors.New("invalid chirp ID")
+
 type Chirp struct {
-	UserID int    `json:"author_id"`
-	Body   string `json:"body"`
-	ID     int    `json:"id"`
+	UserID      int               `json:"author_id"`
+	Body        string            `json:"body"`
+	ID          int               `json:"id"`
+	Media       []string          `json:"media,omitempty"`
+	Entities    entities.Entities `json:"entities"`
+	ReplyTo     int               `json:"reply_to,omitempty"`
+	QuoteOf     int               `json:"quote_of,omitempty"`
+	RemoteReply string            `json:"remote_reply_to,omitempty"`
+	ReplyCount  int               `json:"reply_count"`
+	LikeCount   int               `json:"like_count"`
+	RepostCount int               `json:"repost_count"`
+	QuoteCount  int               `json:"quote_count"`
+	Hidden      bool              `json:"hidden,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// DeletedChirp stands in for a deleted chirp that still has replies.
+type DeletedChirp struct {
+	ID         int       `json:"id"`
+	ReplyTo    int       `json:"reply_to,omitempty"`
+	ReplyCount int       `json:"reply_count"`
+	DeletedAt  time.Time `json:"deleted_at"`
 }
 
-func (db *Database) CreateChirp(chirpText string, userID int) (Chirp, error) {
+// ChirpRevision is a version of a chirp that has since been edited.
+type ChirpRevision struct {
+	Body      string    `json:"body"`
+	Media     []string  `json:"media,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateChirp stores a new chirp from the author, body, media, entities, reply
+// and quote fields of draft. Replied to and quoted chirps must exist; remote
+// replies are checked by the caller.
+func (db *Database) CreateChirp(draft Chirp) (Chirp, error) {
 	db.mu.Lock()
-	id := len(db.Chirps) + 1
+	_, replyOK := db.Chirps[draft.ReplyTo]
+	_, quoteOK := db.Chirps[draft.QuoteOf]
+	if (draft.ReplyTo != 0 && !replyOK) || (draft.QuoteOf != 0 && !quoteOK) {
+		db.mu.Unlock()
+		return Chirp{}, ErrInvalidChirpID
+	}
+	if replyOK {
+		parent := db.Chirps[draft.ReplyTo]
+		parent.ReplyCount++
+		db.Chirps[parent.ID] = parent
+	}
+	if quoteOK {
+		quoted := db.Chirps[draft.QuoteOf]
+		quoted.QuoteCount++
+		db.Chirps[quoted.ID] = quoted
+	}
+	id := db.allocateID("chirps", max(nextID(db.Chirps), nextID(db.DeletedChirps)))
+	now := time.Now().UTC()
 	db.Chirps[id] = Chirp{
-		ID:     id,
-		Body:   chirpText,
-		UserID: userID,
+		ID:          id,
+		Body:        draft.Body,
+		UserID:      draft.UserID,
+		Media:       draft.Media,
+		Entities:    draft.Entities,
+		ReplyTo:     draft.ReplyTo,
+		QuoteOf:     draft.QuoteOf,
+		RemoteReply: draft.RemoteReply,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 	db.mu.Unlock()
 	err := db.writeDB()
@@ -20,13 +83,120 @@ func (db *Database) CreateChirp(chirpText string, userID int) (Chirp, error) {
 		var zeroVal Chirp
 		return zeroVal, err
 	}
-	return db.Chirps[id], err
+	chirp := db.Chirps[id]
+	db.notifyChirpSaved(chirp)
+	return chirp, err
 }
 
+// UpdateChirp replaces the body, media and entities of a chirp with those of
+// edit, keeping the previous version in its history.
+func (db *Database) UpdateChirp(chirpID int, edit Chirp) (Chirp, error) {
+	db.mu.Lock()
+	chirp, ok := db.Chirps[chirpID]
+	if !ok {
+		db.mu.Unlock()
+		return Chirp{}, ErrInvalidChirpID
+	}
+	db.ChirpHistory[chirpID] = append(db.ChirpHistory[chirpID], ChirpRevision{
+		Body:      chirp.Body,
+		Media:     chirp.Media,
+		CreatedAt: chirp.UpdatedAt,
+	})
+	chirp.Body = edit.Body
+	chirp.Media = edit.Media
+	chirp.Entities = edit.Entities
+	chirp.UpdatedAt = time.Now().UTC()
+	db.Chirps[chirpID] = chirp
+	db.mu.Unlock()
+	err := db.writeDB()
+	if err != nil {
+		return Chirp{}, err
+	}
+	if !chirp.Hidden {
+		db.notifyChirpSaved(chirp)
+	}
+	return chirp, nil
+}
+
+func (db *Database) GetChirp(chirpID int) (Chirp, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	chirp, ok := db.Chirps[chirpID]
+	if !ok {
+		return Chirp{}, ErrInvalidChirpID
+	}
+	return chirp, nil
+}
+
+// GetChirpHistory returns the previous versions of a chirp, oldest first.
+func (db *Database) GetChirpHistory(chirpID int) ([]ChirpRevision, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if _, ok := db.Chirps[chirpID]; !ok {
+		return nil, ErrInvalidChirpID
+	}
+	history := make([]ChirpRevision, len(db.ChirpHistory[chirpID]))
+	copy(history, db.ChirpHistory[chirpID])
+	return history, nil
+}
+
+// DeleteChirp removes a chirp. A chirp with replies leaves a DeletedChirp in
+// its place so the conversation stays connected; it is removed once its last
+// reply is.
 func (db *Database) DeleteChirp(chirpID int) error {
 	db.mu.Lock()
+	chirp, ok := db.Chirps[chirpID]
+	if !ok {
+		db.mu.Unlock()
+		return ErrInvalidChirpID
+	}
 	delete(db.Chirps, chirpID)
+	delete(db.ChirpHistory, chirpID)
+	delete(db.Likes, chirpID)
+	delete(db.Reposts, chirpID)
+	db.deleteChirpNotifications(chirpID)
+	if quoted, ok := db.Chirps[chirp.QuoteOf]; ok {
+		quoted.QuoteCount--
+		db.Chirps[quoted.ID] = quoted
+	}
+	if chirp.ReplyCount > 0 {
+		db.DeletedChirps[chirpID] = DeletedChirp{
+			ID:         chirpID,
+			ReplyTo:    chirp.ReplyTo,
+			ReplyCount: chirp.ReplyCount,
+			DeletedAt:  time.Now().UTC(),
+		}
+	} else {
+		db.removeReply(chirp.ReplyTo)
+	}
 	db.mu.Unlock()
 	err := db.writeDB()
-	return err
+	if err != nil {
+		return err
+	}
+	db.notifyChirpDeleted(chirpID)
+	return nil
+}
+
+// removeReply decrements the reply count of a parent, clearing away deleted
+// parents that no longer have replies. Callers must hold the write lock.
+func (db *Database) removeReply(parentID int) {
+	for parentID != 0 {
+		if parent, ok := db.Chirps[parentID]; ok {
+			parent.ReplyCount--
+			db.Chirps[parentID] = parent
+			return
+		}
+		deleted, ok := db.DeletedChirps[parentID]
+		if !ok {
+			return
+		}
+		deleted.ReplyCount--
+		if deleted.ReplyCount > 0 {
+			db.DeletedChirps[parentID] = deleted
+			return
+		}
+		delete(db.DeletedChirps, parentID)
+		parentID = deleted.ReplyTo
+	}
 }
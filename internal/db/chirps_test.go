@@ -0,0 +1,115 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+// Deleting the newest chirp must not free its ID: remote servers, feed readers
+// and cursors may still hold it.
+func TestChirpIDsAreNeverReused(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	first, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DeleteChirp(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "third"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.ID == second.ID || third.ID == first.ID {
+		t.Errorf("new chirp reused ID %d", third.ID)
+	}
+
+	err = db.DeleteChirp(third.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = reload(t, db)
+	fourth, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "fourth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fourth.ID <= third.ID {
+		t.Errorf("after restart got ID %d, want more than %d", fourth.ID, third.ID)
+	}
+}
+
+func TestPendingChirpIDsAreNeverReused(t *testing.T) {
+	db := newTestDatabase(t)
+	draft, err := db.SavePendingChirp(PendingChirp{UserID: 1, Status: PendingDraft, Body: "draft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DeletePendingChirp(draft.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := db.SavePendingChirp(PendingChirp{UserID: 1, Status: PendingDraft, Body: "draft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID == draft.ID {
+		t.Errorf("new draft reused ID %d", next.ID)
+	}
+}
+
+func TestUpdateChirpKeepsHistory(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	chirp, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "first", Media: []string{"https://example.com/a.png"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !chirp.UpdatedAt.Equal(chirp.CreatedAt) {
+		t.Errorf("new chirp updated_at %s differs from created_at %s", chirp.UpdatedAt, chirp.CreatedAt)
+	}
+	history, err := db.GetChirpHistory(chirp.ID)
+	if err != nil || len(history) != 0 {
+		t.Fatalf("history of a new chirp = %v, %v, want none", history, err)
+	}
+
+	second, err := db.UpdateChirp(chirp.ID, Chirp{Body: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := db.UpdateChirp(chirp.ID, Chirp{Body: "third"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Body != "third" || len(third.Media) != 0 || !third.CreatedAt.Equal(chirp.CreatedAt) || !third.UpdatedAt.After(chirp.CreatedAt) {
+		t.Errorf("edited chirp = %+v", third)
+	}
+
+	db = reload(t, db)
+	history, err = db.GetChirpHistory(chirp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Body != "first" || len(history[0].Media) != 1 || history[1].Body != "second" {
+		t.Fatalf("history = %+v, want first then second", history)
+	}
+	if !history[0].CreatedAt.Equal(chirp.CreatedAt) || !history[1].CreatedAt.Equal(second.UpdatedAt) {
+		t.Errorf("revisions should be dated from when they were written: %+v", history)
+	}
+
+	err = db.DeleteChirp(chirp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetChirpHistory(chirp.ID); !errors.Is(err, ErrInvalidChirpID) {
+		t.Errorf("history of a deleted chirp: err = %v, want ErrInvalidChirpID", err)
+	}
+	if _, err := db.UpdateChirp(chirp.ID, Chirp{Body: "gone"}); !errors.Is(err, ErrInvalidChirpID) {
+		t.Errorf("editing a deleted chirp: err = %v, want ErrInvalidChirpID", err)
+	}
+}
@@ -6,23 +6,82 @@ import (
 	"log"
 	"os"
 	"sync"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
 )
 
 type Database struct {
-	dbPath string           `json:"-"`
-	Chirps map[int]Chirp    `json:"chirps"`
-	Users  map[int]User     `json:"users"`
-	Tokens map[string]Token `json:"tokens"`
-	mu     *sync.RWMutex    `json:"-"`
+	dbPath                  string                          `json:"-"`
+	Chirps                  map[int]Chirp                   `json:"chirps"`
+	ChirpHistory            map[int][]ChirpRevision         `json:"chirp_history"`
+	DeletedChirps           map[int]DeletedChirp            `json:"deleted_chirps"`
+	Users                   map[int]User                    `json:"users"`
+	Tokens                  map[string]Token                `json:"tokens"`
+	Identities              map[string]Identity             `json:"identities"`
+	OAuthClients            map[string]OAuthClient          `json:"oauth_clients"`
+	AuthCodes               map[string]AuthCode             `json:"auth_codes"`
+	RevokedAccessTokens     map[string]time.Time            `json:"revoked_access_tokens"`
+	Webhooks                map[string]Webhook              `json:"webhooks"`
+	Subscriptions           map[int]Subscription            `json:"subscriptions"`
+	Follows                 map[int]map[int]time.Time       `json:"follows"`
+	Likes                   map[int]map[int]time.Time       `json:"likes"`
+	Reposts                 map[int]map[int]time.Time       `json:"reposts"`
+	Notifications           map[int]Notification            `json:"notifications"`
+	NotificationPreferences map[int]map[string]bool         `json:"notification_preferences"`
+	Reports                 map[int]Report                  `json:"reports"`
+	ModerationActions       map[int]ModerationAction        `json:"moderation_actions"`
+	Suspensions             map[int]Suspension              `json:"suspensions"`
+	Appeals                 map[int]Appeal                  `json:"appeals"`
+	Blocks                  map[int]map[int]time.Time       `json:"blocks"`
+	Mutes                   map[int]map[int]time.Time       `json:"mutes"`
+	PendingChirps           map[int]PendingChirp            `json:"pending_chirps"`
+	ActorKeys               map[int]string                  `json:"actor_keys"`
+	RemoteActors            map[string]RemoteActor          `json:"remote_actors"`
+	RemoteFollowers         map[int]map[string]time.Time    `json:"remote_followers"`
+	RemoteFollowing         map[int]map[string]RemoteFollow `json:"remote_following"`
+	RemoteNotes             map[string]RemoteNote           `json:"remote_notes"`
+	Deliveries              map[int]Delivery                `json:"deliveries"`
+	NextIDs                 map[string]int                  `json:"next_ids"`
+	observers               *[]ChirpObserver                `json:"-"`
+	mu                      *sync.RWMutex                   `json:"-"`
 }
 
 func InitialiseDatabase(dbPath string) Database {
 	db := Database{
-		dbPath: dbPath,
-		Chirps: make(map[int]Chirp),
-		Users:  make(map[int]User),
-		Tokens: make(map[string]Token),
-		mu:     &sync.RWMutex{},
+		dbPath:                  dbPath,
+		Chirps:                  make(map[int]Chirp),
+		ChirpHistory:            make(map[int][]ChirpRevision),
+		DeletedChirps:           make(map[int]DeletedChirp),
+		Users:                   make(map[int]User),
+		Tokens:                  make(map[string]Token),
+		Identities:              make(map[string]Identity),
+		OAuthClients:            make(map[string]OAuthClient),
+		AuthCodes:               make(map[string]AuthCode),
+		RevokedAccessTokens:     make(map[string]time.Time),
+		Webhooks:                make(map[string]Webhook),
+		Subscriptions:           make(map[int]Subscription),
+		Follows:                 make(map[int]map[int]time.Time),
+		Likes:                   make(map[int]map[int]time.Time),
+		Reposts:                 make(map[int]map[int]time.Time),
+		Notifications:           make(map[int]Notification),
+		NotificationPreferences: make(map[int]map[string]bool),
+		Reports:                 make(map[int]Report),
+		ModerationActions:       make(map[int]ModerationAction),
+		Suspensions:             make(map[int]Suspension),
+		Appeals:                 make(map[int]Appeal),
+		Blocks:                  make(map[int]map[int]time.Time),
+		Mutes:                   make(map[int]map[int]time.Time),
+		PendingChirps:           make(map[int]PendingChirp),
+		ActorKeys:               make(map[int]string),
+		RemoteActors:            make(map[string]RemoteActor),
+		RemoteFollowers:         make(map[int]map[string]time.Time),
+		RemoteFollowing:         make(map[int]map[string]RemoteFollow),
+		RemoteNotes:             make(map[string]RemoteNote),
+		Deliveries:              make(map[int]Delivery),
+		NextIDs:                 make(map[string]int),
+		observers:               &[]ChirpObserver{},
+		mu:                      &sync.RWMutex{},
 	}
 	err := db.ensureDB()
 	if err != nil {
@@ -55,8 +114,24 @@ func (db *Database) loadDB() error {
 	}
 	db.mu.Lock()
 	err = json.Unmarshal(data, &db)
+	if err != nil {
+		db.mu.Unlock()
+		return err
+	}
+	// Chirps stored before entities were extracted have none recorded.
+	for id, chirp := range db.Chirps {
+		if chirp.Entities.Hashtags == nil {
+			chirp.Entities = entities.Parse(chirp.Body)
+			db.Chirps[id] = chirp
+		}
+	}
+	backfilled := db.backfillSubscriptions(time.Now())
+	backfilled = db.backfillJoinDates() || backfilled
 	db.mu.Unlock()
-	return err
+	if backfilled {
+		return db.writeDB()
+	}
+	return nil
 }
 
 func (db *Database) writeDB() error {
@@ -69,3 +144,23 @@ func (db *Database) writeDB() error {
 	err = os.WriteFile(db.dbPath, data, 0777)
 	return err
 }
+
+// allocateID hands out the next ID for a table. IDs are never reused, even
+// once the record holding the highest is deleted, because they appear in URLs,
+// feeds and cursors outside the server. floor covers tables created before
+// counters were kept. Callers must hold the write lock.
+func (db *Database) allocateID(table string, floor int) int {
+	id := max(db.NextIDs[table], floor)
+	db.NextIDs[table] = id + 1
+	return id
+}
+
+// nextID returns an ID above every key in use. Deleted records leave gaps, so
+// the map length cannot be used.
+func nextID[T any](records map[int]T) int {
+	id := 0
+	for key := range records {
+		id = max(id, key)
+	}
+	return id + 1
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestDatabase(t *testing.T) Database {
+	t.Helper()
+	return InitialiseDatabase(filepath.Join(t.TempDir(), "database.json"))
+}
+
+// reload reads the database back from disk, as on a restart.
+func reload(t *testing.T, db Database) Database {
+	t.Helper()
+	return InitialiseDatabase(db.dbPath)
+}
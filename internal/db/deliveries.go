@@ -0,0 +1,95 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// Delivery is an activity waiting to be posted to a remote inbox, signed as
+// UserID. Failed deliveries stay queued until NextAttempt.
+type Delivery struct {
+	ID          int             `json:"id"`
+	UserID      int             `json:"user_id"`
+	Inbox       string          `json:"inbox"`
+	Activity    json.RawMessage `json:"activity"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// AddDeliveries queues an activity for each inbox, due immediately.
+func (db *Database) AddDeliveries(userID int, inboxes []string, activity json.RawMessage) ([]Delivery, error) {
+	now := time.Now().UTC()
+	db.mu.Lock()
+	deliveries := make([]Delivery, 0, len(inboxes))
+	for _, inbox := range inboxes {
+		delivery := Delivery{
+			ID:          db.allocateID("deliveries", nextID(db.Deliveries)),
+			UserID:      userID,
+			Inbox:       inbox,
+			Activity:    activity,
+			NextAttempt: now,
+			CreatedAt:   now,
+		}
+		db.Deliveries[delivery.ID] = delivery
+		deliveries = append(deliveries, delivery)
+	}
+	db.mu.Unlock()
+	err := db.writeDB()
+	return deliveries, err
+}
+
+func (db *Database) GetDelivery(id int) (Delivery, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	delivery, ok := db.Deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// ListDeliveries returns every queued delivery, oldest first.
+func (db *Database) ListDeliveries() []Delivery {
+	db.mu.RLock()
+	deliveries := make([]Delivery, 0, len(db.Deliveries))
+	for _, delivery := range db.Deliveries {
+		deliveries = append(deliveries, delivery)
+	}
+	db.mu.RUnlock()
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries
+}
+
+// RetryDelivery records a failed attempt and when to try again.
+func (db *Database) RetryDelivery(id int, next time.Time) (Delivery, error) {
+	db.mu.Lock()
+	delivery, ok := db.Deliveries[id]
+	if !ok {
+		db.mu.Unlock()
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	delivery.Attempts++
+	delivery.NextAttempt = next.UTC()
+	db.Deliveries[id] = delivery
+	db.mu.Unlock()
+	err := db.writeDB()
+	return delivery, err
+}
+
+func (db *Database) DeleteDelivery(id int) error {
+	db.mu.Lock()
+	if _, ok := db.Deliveries[id]; !ok {
+		db.mu.Unlock()
+		return ErrDeliveryNotFound
+	}
+	delete(db.Deliveries, id)
+	db.mu.Unlock()
+	return db.writeDB()
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Queued deliveries are stored so a restart does not lose them.
+func TestDeliveriesSurviveReload(t *testing.T) {
+	db := newTestDatabase(t)
+	activity := json.RawMessage(`{"type":"Create"}`)
+	added, err := db.AddDeliveries(1, []string{"https://a.example/inbox", "https://b.example/inbox"}, activity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := time.Now().Add(time.Minute)
+	_, err = db.RetryDelivery(added[1].ID, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = reload(t, db)
+	deliveries := db.ListDeliveries()
+	if len(deliveries) != 2 {
+		t.Fatalf("got %d deliveries after reload, want 2", len(deliveries))
+	}
+	if string(deliveries[0].Activity) != string(activity) || deliveries[0].Inbox != "https://a.example/inbox" {
+		t.Errorf("first delivery = %+v", deliveries[0])
+	}
+	if deliveries[1].Attempts != 1 || !deliveries[1].NextAttempt.Equal(next.UTC()) {
+		t.Errorf("retried delivery = %+v, want 1 attempt due at %s", deliveries[1], next)
+	}
+
+	err = db.DeleteDelivery(added[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.GetDelivery(added[0].ID)
+	if !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("deleted delivery: err = %v, want ErrDeliveryNotFound", err)
+	}
+}
@@ -0,0 +1,66 @@
+package db
+
+import "time"
+
+func (db *Database) LikeChirp(chirpID, userID int) (Chirp, error) {
+	return db.setEngagement(db.Likes, chirpID, userID, true, func(c *Chirp) *int { return &c.LikeCount })
+}
+
+func (db *Database) UnlikeChirp(chirpID, userID int) (Chirp, error) {
+	return db.setEngagement(db.Likes, chirpID, userID, false, func(c *Chirp) *int { return &c.LikeCount })
+}
+
+func (db *Database) RepostChirp(chirpID, userID int) (Chirp, error) {
+	return db.setEngagement(db.Reposts, chirpID, userID, true, func(c *Chirp) *int { return &c.RepostCount })
+}
+
+func (db *Database) UndoRepost(chirpID, userID int) (Chirp, error) {
+	return db.setEngagement(db.Reposts, chirpID, userID, false, func(c *Chirp) *int { return &c.RepostCount })
+}
+
+func (db *Database) HasLiked(chirpID, userID int) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, ok := db.Likes[chirpID][userID]
+	return ok
+}
+
+func (db *Database) HasReposted(chirpID, userID int) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, ok := db.Reposts[chirpID][userID]
+	return ok
+}
+
+// setEngagement adds or removes a user's like or repost of a chirp and keeps
+// the chirp's counter in step. Repeating an action is a no-op.
+func (db *Database) setEngagement(records map[int]map[int]time.Time, chirpID, userID int, on bool, counter func(*Chirp) *int) (Chirp, error) {
+	db.mu.Lock()
+	chirp, ok := db.Chirps[chirpID]
+	if !ok {
+		db.mu.Unlock()
+		return Chirp{}, ErrInvalidChirpID
+	}
+	_, exists := records[chirpID][userID]
+	if exists == on {
+		db.mu.Unlock()
+		return chirp, nil
+	}
+	if on {
+		if records[chirpID] == nil {
+			records[chirpID] = make(map[int]time.Time)
+		}
+		records[chirpID][userID] = time.Now().UTC()
+		*counter(&chirp)++
+	} else {
+		delete(records[chirpID], userID)
+		if len(records[chirpID]) == 0 {
+			delete(records, chirpID)
+		}
+		*counter(&chirp)--
+	}
+	db.Chirps[chirpID] = chirp
+	db.mu.Unlock()
+	err := db.writeDB()
+	return chirp, err
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+// Likes and reposts are idempotent, so counters only move when the state
+// does.
+func TestEngagementCountersAreIdempotent(t *testing.T) {
+	db := newTestDatabase(t)
+	author, _ := db.AddUser("author@example.com", nil)
+	fan, _ := db.AddUser("fan@example.com", nil)
+	other, _ := db.AddUser("other@example.com", nil)
+	chirp, err := db.CreateChirp(Chirp{UserID: author.ID, Body: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		run     func() (Chirp, error)
+		likes   int
+		reposts int
+	}{
+		{"like", func() (Chirp, error) { return db.LikeChirp(chirp.ID, fan.ID) }, 1, 0},
+		{"like again", func() (Chirp, error) { return db.LikeChirp(chirp.ID, fan.ID) }, 1, 0},
+		{"another like", func() (Chirp, error) { return db.LikeChirp(chirp.ID, other.ID) }, 2, 0},
+		{"repost", func() (Chirp, error) { return db.RepostChirp(chirp.ID, fan.ID) }, 2, 1},
+		{"repost again", func() (Chirp, error) { return db.RepostChirp(chirp.ID, fan.ID) }, 2, 1},
+		{"unlike", func() (Chirp, error) { return db.UnlikeChirp(chirp.ID, fan.ID) }, 1, 1},
+		{"unlike again", func() (Chirp, error) { return db.UnlikeChirp(chirp.ID, fan.ID) }, 1, 1},
+		{"undo repost", func() (Chirp, error) { return db.UndoRepost(chirp.ID, fan.ID) }, 1, 0},
+		{"undo repost again", func() (Chirp, error) { return db.UndoRepost(chirp.ID, fan.ID) }, 1, 0},
+	}
+	for _, step := range steps {
+		updated, err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+		if updated.LikeCount != step.likes || updated.RepostCount != step.reposts {
+			t.Errorf("%s: likes %d reposts %d, want %d %d", step.name, updated.LikeCount, updated.RepostCount, step.likes, step.reposts)
+		}
+	}
+	if db.HasLiked(chirp.ID, fan.ID) || !db.HasLiked(chirp.ID, other.ID) || db.HasReposted(chirp.ID, fan.ID) {
+		t.Error("HasLiked and HasReposted disagree with the actions taken")
+	}
+	if _, err := db.LikeChirp(999, fan.ID); !errors.Is(err, ErrInvalidChirpID) {
+		t.Errorf("liking a missing chirp: err = %v, want ErrInvalidChirpID", err)
+	}
+
+	db = reload(t, db)
+	if stored := db.Chirps[chirp.ID]; stored.LikeCount != 1 || !db.HasLiked(chirp.ID, other.ID) {
+		t.Errorf("after restart: like count %d", stored.LikeCount)
+	}
+}
+
+func TestQuotesAndDeletedChirps(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	original, _ := db.CreateChirp(Chirp{UserID: user.ID, Body: "original"})
+	quote, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "quoting", QuoteOf: original.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Chirps[original.ID].QuoteCount != 1 {
+		t.Errorf("quote count = %d, want 1", db.Chirps[original.ID].QuoteCount)
+	}
+	err = db.DeleteChirp(quote.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Chirps[original.ID].QuoteCount != 0 {
+		t.Errorf("quote count after deleting the quote = %d, want 0", db.Chirps[original.ID].QuoteCount)
+	}
+	if _, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "quoting", QuoteOf: 999}); !errors.Is(err, ErrInvalidChirpID) {
+		t.Errorf("quoting a missing chirp: err = %v, want ErrInvalidChirpID", err)
+	}
+
+	liked, _ := db.CreateChirp(Chirp{UserID: user.ID, Body: "liked"})
+	_, err = db.LikeChirp(liked.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DeleteChirp(liked.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.HasLiked(liked.ID, user.ID) {
+		t.Error("likes of a deleted chirp were kept")
+	}
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrNotFollowingRemote = errors.New("not following remote actor")
+var ErrNoteOwner = errors.New("note belongs to another actor")
+
+// RemoteActor caches what is needed to talk to an actor on another server.
+type RemoteActor struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	Inbox       string    `json:"inbox"`
+	SharedInbox string    `json:"shared_inbox,omitempty"`
+	KeyID       string    `json:"key_id"`
+	PublicKey   string    `json:"public_key"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// RemoteFollow is a local user's follow of a remote actor, which counts once
+// the remote server accepts it.
+type RemoteFollow struct {
+	ActorID  string    `json:"actor_id"`
+	FollowID string    `json:"follow_id"`
+	Accepted bool      `json:"accepted"`
+	Since    time.Time `json:"since"`
+}
+
+// RemoteNote is a note received from another server: a reply to a local
+// chirp, or a post by a remote actor that local users follow.
+type RemoteNote struct {
+	ID         string    `json:"id"`
+	ActorID    string    `json:"actor_id"`
+	URL        string    `json:"url,omitempty"`
+	Content    string    `json:"content"`
+	InReplyTo  string    `json:"in_reply_to,omitempty"`
+	ReplyTo    int       `json:"reply_to,omitempty"`
+	Published  time.Time `json:"published"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// GetActorKey returns a user's signing key, creating it with generate on
+// first use.
+func (db *Database) GetActorKey(userID int, generate func() (string, error)) (string, error) {
+	db.mu.RLock()
+	key, ok := db.ActorKeys[userID]
+	db.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	key, err := generate()
+	if err != nil {
+		return "", err
+	}
+	db.mu.Lock()
+	if existing, ok := db.ActorKeys[userID]; ok {
+		db.mu.Unlock()
+		return existing, nil
+	}
+	db.ActorKeys[userID] = key
+	db.mu.Unlock()
+	return key, db.writeDB()
+}
+
+func (db *Database) SaveRemoteActor(actor RemoteActor) error {
+	db.mu.Lock()
+	actor.FetchedAt = time.Now().UTC()
+	db.RemoteActors[actor.ID] = actor
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+func (db *Database) GetRemoteActor(actorID string) (RemoteActor, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	actor, ok := db.RemoteActors[actorID]
+	return actor, ok
+}
+
+// GetRemoteActorByKey finds the cached actor owning a signing key.
+func (db *Database) GetRemoteActorByKey(keyID string) (RemoteActor, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, actor := range db.RemoteActors {
+		if actor.KeyID == keyID {
+			return actor, true
+		}
+	}
+	return RemoteActor{}, false
+}
+
+func (db *Database) AddRemoteFollower(userID int, actorID string) error {
+	db.mu.Lock()
+	if _, ok := db.Users[userID]; !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	if db.RemoteFollowers[userID] == nil {
+		db.RemoteFollowers[userID] = make(map[string]time.Time)
+	}
+	if _, ok := db.RemoteFollowers[userID][actorID]; !ok {
+		db.RemoteFollowers[userID][actorID] = time.Now().UTC()
+	}
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+func (db *Database) RemoveRemoteFollower(userID int, actorID string) error {
+	db.mu.Lock()
+	delete(db.RemoteFollowers[userID], actorID)
+	if len(db.RemoteFollowers[userID]) == 0 {
+		delete(db.RemoteFollowers, userID)
+	}
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// GetRemoteFollowers returns the remote actors following a user.
+func (db *Database) GetRemoteFollowers(userID int) []RemoteActor {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	followers := []RemoteActor{}
+	for actorID := range db.RemoteFollowers[userID] {
+		if actor, ok := db.RemoteActors[actorID]; ok {
+			followers = append(followers, actor)
+		}
+	}
+	sort.Slice(followers, func(i, j int) bool {
+		return followers[i].ID < followers[j].ID
+	})
+	return followers
+}
+
+func (db *Database) AddRemoteFollowing(userID int, follow RemoteFollow) (RemoteFollow, error) {
+	db.mu.Lock()
+	if db.RemoteFollowing[userID] == nil {
+		db.RemoteFollowing[userID] = make(map[string]RemoteFollow)
+	}
+	follow.Since = time.Now().UTC()
+	db.RemoteFollowing[userID][follow.ActorID] = follow
+	db.mu.Unlock()
+	return follow, db.writeDB()
+}
+
+// AcceptRemoteFollowing marks a follow as accepted by the remote actor,
+// matched by the ID of the Follow activity.
+func (db *Database) AcceptRemoteFollowing(actorID, followID string) error {
+	db.mu.Lock()
+	for userID, following := range db.RemoteFollowing {
+		follow, ok := following[actorID]
+		if ok && follow.FollowID == followID {
+			follow.Accepted = true
+			db.RemoteFollowing[userID][actorID] = follow
+			db.mu.Unlock()
+			return db.writeDB()
+		}
+	}
+	db.mu.Unlock()
+	return ErrNotFollowingRemote
+}
+
+func (db *Database) RemoveRemoteFollowing(userID int, actorID string) (RemoteFollow, error) {
+	db.mu.Lock()
+	follow, ok := db.RemoteFollowing[userID][actorID]
+	if !ok {
+		db.mu.Unlock()
+		return RemoteFollow{}, ErrNotFollowingRemote
+	}
+	delete(db.RemoteFollowing[userID], actorID)
+	if len(db.RemoteFollowing[userID]) == 0 {
+		delete(db.RemoteFollowing, userID)
+	}
+	db.mu.Unlock()
+	return follow, db.writeDB()
+}
+
+func (db *Database) GetRemoteFollowing(userID int) []RemoteFollow {
+	db.mu.RLock()
+	following := []RemoteFollow{}
+	for _, follow := range db.RemoteFollowing[userID] {
+		following = append(following, follow)
+	}
+	db.mu.RUnlock()
+	sort.Slice(following, func(i, j int) bool {
+		return following[i].Since.After(following[j].Since)
+	})
+	return following
+}
+
+// IsRemoteFollowed reports whether any local user follows a remote actor.
+func (db *Database) IsRemoteFollowed(actorID string) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, following := range db.RemoteFollowing {
+		if follow, ok := following[actorID]; ok && follow.Accepted {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *Database) SaveRemoteNote(note RemoteNote) error {
+	db.mu.Lock()
+	if existing, ok := db.RemoteNotes[note.ID]; ok && existing.ActorID != note.ActorID {
+		db.mu.Unlock()
+		return ErrNoteOwner
+	}
+	note.ReceivedAt = time.Now().UTC()
+	db.RemoteNotes[note.ID] = note
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// DeleteRemoteNote removes a note if it belongs to the actor deleting it.
+func (db *Database) DeleteRemoteNote(noteID, actorID string) error {
+	db.mu.Lock()
+	note, ok := db.RemoteNotes[noteID]
+	if !ok || note.ActorID != actorID {
+		db.mu.Unlock()
+		return nil
+	}
+	delete(db.RemoteNotes, noteID)
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// ListRemoteNotes returns remote notes newest first: replies to a chirp if
+// replyTo is set, otherwise notes by the remote actors a user follows.
+func (db *Database) ListRemoteNotes(userID, replyTo int) []RemoteNote {
+	db.mu.RLock()
+	notes := []RemoteNote{}
+	for _, note := range db.RemoteNotes {
+		if replyTo != 0 && note.ReplyTo == replyTo {
+			notes = append(notes, note)
+		} else if follow, ok := db.RemoteFollowing[userID][note.ActorID]; replyTo == 0 && ok && follow.Accepted {
+			notes = append(notes, note)
+		}
+	}
+	db.mu.RUnlock()
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].Published.After(notes[j].Published)
+	})
+	return notes
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrSelfFollow = errors.New("users cannot follow themselves")
+var ErrNotFollowing = errors.New("not following user")
+
+type Follow struct {
+	UserID     int       `json:"id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (db *Database) FollowUser(followerID, followedID int) error {
+	if followerID == followedID {
+		return ErrSelfFollow
+	}
+	db.mu.Lock()
+	if _, ok := db.Users[followedID]; !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	if _, ok := db.Follows[followerID][followedID]; ok {
+		db.mu.Unlock()
+		return nil
+	}
+	if db.Follows[followerID] == nil {
+		db.Follows[followerID] = make(map[int]time.Time)
+	}
+	db.Follows[followerID][followedID] = time.Now().UTC()
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+func (db *Database) UnfollowUser(followerID, followedID int) error {
+	db.mu.Lock()
+	if _, ok := db.Follows[followerID][followedID]; !ok {
+		db.mu.Unlock()
+		return ErrNotFollowing
+	}
+	delete(db.Follows[followerID], followedID)
+	if len(db.Follows[followerID]) == 0 {
+		delete(db.Follows, followerID)
+	}
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// GetFollowing returns the users someone follows, most recently followed first.
+func (db *Database) GetFollowing(userID int) []Follow {
+	db.mu.RLock()
+	following := []Follow{}
+	for followedID, since := range db.Follows[userID] {
+		following = append(following, Follow{UserID: followedID, FollowedAt: since})
+	}
+	db.mu.RUnlock()
+	sortFollows(following)
+	return following
+}
+
+// GetFollowers returns the users following someone, most recent first.
+func (db *Database) GetFollowers(userID int) []Follow {
+	db.mu.RLock()
+	followers := []Follow{}
+	for followerID, following := range db.Follows {
+		if since, ok := following[userID]; ok {
+			followers = append(followers, Follow{UserID: followerID, FollowedAt: since})
+		}
+	}
+	db.mu.RUnlock()
+	sortFollows(followers)
+	return followers
+}
+
+func sortFollows(follows []Follow) {
+	sort.Slice(follows, func(i, j int) bool {
+		if !follows[i].FollowedAt.Equal(follows[j].FollowedAt) {
+			return follows[i].FollowedAt.After(follows[j].FollowedAt)
+		}
+		return follows[i].UserID < follows[j].UserID
+	})
+}
+
+func (db *Database) IsFollowing(followerID, followedID int) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, ok := db.Follows[followerID][followedID]
+	return ok
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrIdentityNotFound = errors.New("identity not linked")
+
+type Identity struct {
+	Issuer   string    `json:"issuer"`
+	Subject  string    `json:"subject"`
+	UserID   int       `json:"user_id"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+func identityKey(issuer, subject string) string {
+	return issuer + "|" + subject
+}
+
+func (db *Database) GetIdentityUser(issuer, subject string) (User, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	identity, ok := db.Identities[identityKey(issuer, subject)]
+	if !ok {
+		return User{}, ErrIdentityNotFound
+	}
+	user, ok := db.Users[identity.UserID]
+	if !ok {
+		return User{}, ErrInvalidUserID
+	}
+	return user, nil
+}
+
+func (db *Database) LinkIdentity(issuer, subject, email string, userID int) error {
+	db.mu.Lock()
+	if _, ok := db.Users[userID]; !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	db.Identities[identityKey(issuer, subject)] = Identity{
+		Issuer:   issuer,
+		Subject:  subject,
+		UserID:   userID,
+		Email:    email,
+		LinkedAt: time.Now(),
+	}
+	db.mu.Unlock()
+	err := db.writeDB()
+	return err
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrActionNotFound = errors.New("moderation action not found")
+var ErrNotSuspended = errors.New("user is not suspended")
+var ErrAppealNotFound = errors.New("appeal not found")
+var ErrAppealExists = errors.New("action already appealed")
+var ErrAppealResolved = errors.New("appeal already resolved")
+var ErrNotAppealable = errors.New("action was not taken against this user")
+
+const (
+	ActionHideChirp     = "hide_chirp"
+	ActionUnhideChirp   = "unhide_chirp"
+	ActionDeleteChirp   = "delete_chirp"
+	ActionSuspendUser   = "suspend_user"
+	ActionUnsuspendUser = "unsuspend_user"
+
+	AppealOpen       = "open"
+	AppealUpheld     = "upheld"
+	AppealOverturned = "overturned"
+)
+
+// ModerationAction records what a moderator did, to whom and why.
+type ModerationAction struct {
+	ID          int       `json:"id"`
+	ModeratorID int       `json:"moderator_id"`
+	Type        string    `json:"type"`
+	UserID      int       `json:"user_id"`
+	ChirpID     int       `json:"chirp_id,omitempty"`
+	ReportID    int       `json:"report_id,omitempty"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Suspension stops a user from chirping. A zero Until never expires.
+type Suspension struct {
+	UserID      int       `json:"user_id"`
+	ModeratorID int       `json:"moderator_id"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+	Until       time.Time `json:"until"`
+}
+
+type Appeal struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	ActionID   int       `json:"action_id"`
+	Message    string    `json:"message"`
+	Status     string    `json:"status"`
+	ReviewerID int       `json:"reviewer_id,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+func (s Suspension) Active(now time.Time) bool {
+	return s.Until.IsZero() || now.Before(s.Until)
+}
+
+func (db *Database) SetModerator(userID int, moderator bool) error {
+	db.mu.Lock()
+	user, ok := db.Users[userID]
+	if !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	user.Moderator = moderator
+	db.Users[userID] = user
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+func (db *Database) SetChirpHidden(chirpID int, hidden bool) (Chirp, error) {
+	db.mu.Lock()
+	chirp, ok := db.Chirps[chirpID]
+	if !ok {
+		db.mu.Unlock()
+		return Chirp{}, ErrInvalidChirpID
+	}
+	changed := chirp.Hidden != hidden
+	chirp.Hidden = hidden
+	db.Chirps[chirpID] = chirp
+	db.mu.Unlock()
+	err := db.writeDB()
+	if err != nil || !changed {
+		return chirp, err
+	}
+	// Observers only index visible chirps, so hiding one is a deletion as
+	// far as they are concerned.
+	if hidden {
+		db.notifyChirpDeleted(chirpID)
+	} else {
+		db.notifyChirpSaved(chirp)
+	}
+	return chirp, nil
+}
+
+// VisibleChirps returns the chirps that are not hidden, for rebuilding
+// observers.
+func (db *Database) VisibleChirps() map[int]Chirp {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	chirps := make(map[int]Chirp, len(db.Chirps))
+	for id, chirp := range db.Chirps {
+		if !chirp.Hidden {
+			chirps[id] = chirp
+		}
+	}
+	return chirps
+}
+
+func (db *Database) RecordModerationAction(action ModerationAction) (ModerationAction, error) {
+	db.mu.Lock()
+	action.ID = db.allocateID("moderation_actions", nextID(db.ModerationActions))
+	action.CreatedAt = time.Now().UTC()
+	db.ModerationActions[action.ID] = action
+	db.mu.Unlock()
+	err := db.writeDB()
+	return action, err
+}
+
+func (db *Database) GetModerationAction(actionID int) (ModerationAction, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	action, ok := db.ModerationActions[actionID]
+	if !ok {
+		return ModerationAction{}, ErrActionNotFound
+	}
+	return action, nil
+}
+
+// ListModerationActions returns the actions taken against a user, or all
+// actions when userID is 0, newest first.
+func (db *Database) ListModerationActions(userID int) []ModerationAction {
+	db.mu.RLock()
+	actions := []ModerationAction{}
+	for _, action := range db.ModerationActions {
+		if userID == 0 || action.UserID == userID {
+			actions = append(actions, action)
+		}
+	}
+	db.mu.RUnlock()
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].ID > actions[j].ID
+	})
+	return actions
+}
+
+func (db *Database) SuspendUser(suspension Suspension) error {
+	db.mu.Lock()
+	if _, ok := db.Users[suspension.UserID]; !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	suspension.CreatedAt = time.Now().UTC()
+	db.Suspensions[suspension.UserID] = suspension
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+func (db *Database) LiftSuspension(userID int) error {
+	db.mu.Lock()
+	if _, ok := db.Suspensions[userID]; !ok {
+		db.mu.Unlock()
+		return ErrNotSuspended
+	}
+	delete(db.Suspensions, userID)
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// GetSuspension returns a user's suspension if it is in force at now.
+func (db *Database) GetSuspension(userID int, now time.Time) (Suspension, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	suspension, ok := db.Suspensions[userID]
+	if !ok || !suspension.Active(now) {
+		return Suspension{}, false
+	}
+	return suspension, true
+}
+
+// AddAppeal lets the target of a moderation action appeal it, once.
+func (db *Database) AddAppeal(userID, actionID int, message string) (Appeal, error) {
+	db.mu.Lock()
+	action, ok := db.ModerationActions[actionID]
+	if !ok {
+		db.mu.Unlock()
+		return Appeal{}, ErrActionNotFound
+	}
+	if action.UserID != userID {
+		db.mu.Unlock()
+		return Appeal{}, ErrNotAppealable
+	}
+	for _, appeal := range db.Appeals {
+		if appeal.ActionID == actionID {
+			db.mu.Unlock()
+			return Appeal{}, ErrAppealExists
+		}
+	}
+	appeal := Appeal{
+		ID:        db.allocateID("appeals", nextID(db.Appeals)),
+		UserID:    userID,
+		ActionID:  actionID,
+		Message:   message,
+		Status:    AppealOpen,
+		CreatedAt: time.Now().UTC(),
+	}
+	db.Appeals[appeal.ID] = appeal
+	db.mu.Unlock()
+	err := db.writeDB()
+	return appeal, err
+}
+
+// ListAppeals returns appeals filtered by status and appellant when those are
+// set, oldest first.
+func (db *Database) ListAppeals(status string, userID int) []Appeal {
+	db.mu.RLock()
+	appeals := []Appeal{}
+	for _, appeal := range db.Appeals {
+		if (status == "" || appeal.Status == status) && (userID == 0 || appeal.UserID == userID) {
+			appeals = append(appeals, appeal)
+		}
+	}
+	db.mu.RUnlock()
+	sort.Slice(appeals, func(i, j int) bool {
+		return appeals[i].ID < appeals[j].ID
+	})
+	return appeals
+}
+
+func (db *Database) GetAppeal(appealID int) (Appeal, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	appeal, ok := db.Appeals[appealID]
+	if !ok {
+		return Appeal{}, ErrAppealNotFound
+	}
+	return appeal, nil
+}
+
+func (db *Database) ResolveAppeal(appealID, reviewerID int, status, note string) (Appeal, error) {
+	db.mu.Lock()
+	appeal, ok := db.Appeals[appealID]
+	if !ok {
+		db.mu.Unlock()
+		return Appeal{}, ErrAppealNotFound
+	}
+	if appeal.Status != AppealOpen {
+		db.mu.Unlock()
+		return Appeal{}, ErrAppealResolved
+	}
+	appeal.Status = status
+	appeal.ReviewerID = reviewerID
+	appeal.Note = note
+	appeal.ResolvedAt = time.Now().UTC()
+	db.Appeals[appealID] = appeal
+	db.mu.Unlock()
+	err := db.writeDB()
+	return appeal, err
+}
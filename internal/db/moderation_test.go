@@ -0,0 +1,68 @@
+package db
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// recorder is a ChirpObserver that logs what it is told.
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) ChirpSaved(chirp Chirp) {
+	r.events = append(r.events, "saved "+strconv.Itoa(chirp.ID))
+}
+
+func (r *recorder) ChirpDeleted(chirpID int) {
+	r.events = append(r.events, "deleted "+strconv.Itoa(chirpID))
+}
+
+// Hidden chirps must leave search, timelines, trending and the stream, and
+// come back when they are unhidden.
+func TestSetChirpHiddenNotifiesObservers(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	chirp, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	observer := &recorder{}
+	db.ObserveChirps(observer)
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"hide", func() error { _, err := db.SetChirpHidden(chirp.ID, true); return err }},
+		{"hide again", func() error { _, err := db.SetChirpHidden(chirp.ID, true); return err }},
+		{"edit while hidden", func() error { _, err := db.UpdateChirp(chirp.ID, Chirp{Body: "edited"}); return err }},
+		{"unhide", func() error { _, err := db.SetChirpHidden(chirp.ID, false); return err }},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+	}
+	id := strconv.Itoa(chirp.ID)
+	want := []string{"deleted " + id, "saved " + id}
+	if !slices.Equal(observer.events, want) {
+		t.Errorf("observer saw %v, want %v", observer.events, want)
+	}
+}
+
+func TestVisibleChirps(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	shown, _ := db.CreateChirp(Chirp{UserID: user.ID, Body: "shown"})
+	hidden, _ := db.CreateChirp(Chirp{UserID: user.ID, Body: "hidden"})
+	_, err := db.SetChirpHidden(hidden.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	visible := db.VisibleChirps()
+	if _, ok := visible[shown.ID]; !ok || len(visible) != 1 {
+		t.Errorf("VisibleChirps = %v, want only chirp %d", visible, shown.ID)
+	}
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+const (
+	NotifyMention = "mention"
+	NotifyReply   = "reply"
+	NotifyQuote   = "quote"
+	NotifyFollow  = "follow"
+	NotifyLike    = "like"
+	NotifyRepost  = "repost"
+)
+
+var NotificationTypes = []string{NotifyMention, NotifyReply, NotifyQuote, NotifyFollow, NotifyLike, NotifyRepost}
+
+type Notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Type      string    `json:"type"`
+	ActorID   int       `json:"actor_id"`
+	ChirpID   int       `json:"chirp_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Read      bool      `json:"read"`
+}
+
+// AddNotification stores a notification unless the recipient already has one
+// for the same actor, type and chirp, so that repeated likes or follows do not
+// notify twice. It reports whether a notification was added.
+func (db *Database) AddNotification(n Notification) (bool, error) {
+	db.mu.Lock()
+	for _, existing := range db.Notifications {
+		if existing.UserID == n.UserID && existing.Type == n.Type && existing.ActorID == n.ActorID && existing.ChirpID == n.ChirpID {
+			db.mu.Unlock()
+			return false, nil
+		}
+	}
+	n.ID = db.allocateID("notifications", nextID(db.Notifications))
+	n.CreatedAt = time.Now().UTC()
+	n.Read = false
+	db.Notifications[n.ID] = n
+	db.mu.Unlock()
+	return true, db.writeDB()
+}
+
+// GetNotifications returns a user's notifications newest first, starting
+// below the before ID when it is set, along with their unread count.
+func (db *Database) GetNotifications(userID int, before int, limit int, unreadOnly bool) ([]Notification, int) {
+	db.mu.RLock()
+	notifications := []Notification{}
+	unread := 0
+	for _, n := range db.Notifications {
+		if n.UserID != userID {
+			continue
+		}
+		if !n.Read {
+			unread++
+		}
+		if (before != 0 && n.ID >= before) || (unreadOnly && n.Read) {
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+	db.mu.RUnlock()
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].ID > notifications[j].ID
+	})
+	return notifications[:min(limit, len(notifications))], unread
+}
+
+// MarkNotificationsRead marks the given notifications read, or all of a user's
+// notifications when ids is empty.
+func (db *Database) MarkNotificationsRead(userID int, ids []int) error {
+	db.mu.Lock()
+	for _, id := range ids {
+		if n, ok := db.Notifications[id]; !ok || n.UserID != userID {
+			db.mu.Unlock()
+			return ErrNotificationNotFound
+		}
+	}
+	for id, n := range db.Notifications {
+		if n.UserID != userID || n.Read {
+			continue
+		}
+		if len(ids) == 0 || slices.Contains(ids, id) {
+			n.Read = true
+			db.Notifications[id] = n
+		}
+	}
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// GetNotificationPreferences returns whether each notification type is
+// enabled for a user. Types are enabled unless turned off.
+func (db *Database) GetNotificationPreferences(userID int) map[string]bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	preferences := map[string]bool{}
+	for _, notificationType := range NotificationTypes {
+		enabled, ok := db.NotificationPreferences[userID][notificationType]
+		preferences[notificationType] = !ok || enabled
+	}
+	return preferences
+}
+
+func (db *Database) UpdateNotificationPreferences(userID int, preferences map[string]bool) (map[string]bool, error) {
+	db.mu.Lock()
+	if db.NotificationPreferences[userID] == nil {
+		db.NotificationPreferences[userID] = make(map[string]bool)
+	}
+	for notificationType, enabled := range preferences {
+		db.NotificationPreferences[userID][notificationType] = enabled
+	}
+	db.mu.Unlock()
+	err := db.writeDB()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetNotificationPreferences(userID), nil
+}
+
+// deleteChirpNotifications removes notifications about a deleted chirp.
+// Callers must hold the write lock.
+func (db *Database) deleteChirpNotifications(chirpID int) {
+	for id, n := range db.Notifications {
+		if n.ChirpID == chirpID {
+			delete(db.Notifications, id)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func notificationIDs(notifications []Notification) []int {
+	ids := make([]int, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+// Repeated likes, follows and mentions by the same actor only notify once.
+func TestAddNotificationDeduplicates(t *testing.T) {
+	db := newTestDatabase(t)
+	tests := []struct {
+		name string
+		n    Notification
+		want bool
+	}{
+		{"first like", Notification{UserID: 1, Type: NotifyLike, ActorID: 2, ChirpID: 10}, true},
+		{"same like", Notification{UserID: 1, Type: NotifyLike, ActorID: 2, ChirpID: 10}, false},
+		{"repost of the same chirp", Notification{UserID: 1, Type: NotifyRepost, ActorID: 2, ChirpID: 10}, true},
+		{"like of another chirp", Notification{UserID: 1, Type: NotifyLike, ActorID: 2, ChirpID: 11}, true},
+		{"like by another user", Notification{UserID: 1, Type: NotifyLike, ActorID: 3, ChirpID: 10}, true},
+		{"same like for another recipient", Notification{UserID: 4, Type: NotifyLike, ActorID: 2, ChirpID: 10}, true},
+		{"follow", Notification{UserID: 1, Type: NotifyFollow, ActorID: 2}, true},
+		{"follow again", Notification{UserID: 1, Type: NotifyFollow, ActorID: 2}, false},
+	}
+	for _, tt := range tests {
+		added, err := db.AddNotification(tt.n)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if added != tt.want {
+			t.Errorf("%s: added = %v, want %v", tt.name, added, tt.want)
+		}
+	}
+	if len(db.Notifications) != 6 {
+		t.Errorf("stored %d notifications, want 6", len(db.Notifications))
+	}
+}
+
+func TestGetNotificationsAndMarkRead(t *testing.T) {
+	db := newTestDatabase(t)
+	for chirpID := 1; chirpID <= 4; chirpID++ {
+		_, err := db.AddNotification(Notification{UserID: 1, Type: NotifyReply, ActorID: 2, ChirpID: chirpID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := db.AddNotification(Notification{UserID: 2, Type: NotifyFollow, ActorID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.MarkNotificationsRead(1, []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		before     int
+		limit      int
+		unreadOnly bool
+		want       []int
+	}{
+		{"all", 0, 10, false, []int{4, 3, 2, 1}},
+		{"limit", 0, 2, false, []int{4, 3}},
+		{"before", 3, 10, false, []int{2, 1}},
+		{"unread only", 0, 10, true, []int{4, 3, 1}},
+		{"before and unread only", 4, 1, true, []int{3}},
+	}
+	for _, tt := range tests {
+		got, unread := db.GetNotifications(1, tt.before, tt.limit, tt.unreadOnly)
+		if !slices.Equal(notificationIDs(got), tt.want) || unread != 3 {
+			t.Errorf("%s: got %v with %d unread, want %v with 3 unread", tt.name, notificationIDs(got), unread, tt.want)
+		}
+	}
+
+	if err := db.MarkNotificationsRead(1, []int{5}); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("marking another user's notification: err = %v, want %v", err, ErrNotificationNotFound)
+	}
+	if err := db.MarkNotificationsRead(1, []int{99}); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("marking an unknown notification: err = %v, want %v", err, ErrNotificationNotFound)
+	}
+	err = db.MarkNotificationsRead(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, unread := db.GetNotifications(1, 0, 10, false); unread != 0 {
+		t.Errorf("%d unread after marking all read, want 0", unread)
+	}
+	if _, unread := db.GetNotifications(2, 0, 10, false); unread != 1 {
+		t.Errorf("marking all read touched another user: %d unread, want 1", unread)
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	db := newTestDatabase(t)
+	for notificationType, enabled := range db.GetNotificationPreferences(1) {
+		if !enabled {
+			t.Errorf("%s is off by default", notificationType)
+		}
+	}
+	preferences, err := db.UpdateNotificationPreferences(1, map[string]bool{NotifyLike: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preferences[NotifyLike] || !preferences[NotifyFollow] {
+		t.Errorf("preferences = %v, want only likes turned off", preferences)
+	}
+	preferences, err = db.UpdateNotificationPreferences(1, map[string]bool{NotifyLike: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preferences[NotifyLike] {
+		t.Error("likes could not be turned back on")
+	}
+	if len(preferences) != len(NotificationTypes) {
+		t.Errorf("got %d preferences, want one per type", len(preferences))
+	}
+}
+
+func TestDeletingChirpRemovesItsNotifications(t *testing.T) {
+	db := newTestDatabase(t)
+	author, _ := db.AddUser("author@example.com", nil)
+	fan, _ := db.AddUser("fan@example.com", nil)
+	deleted, _ := db.CreateChirp(Chirp{UserID: author.ID, Body: "going away"})
+	kept, _ := db.CreateChirp(Chirp{UserID: author.ID, Body: "staying"})
+	for _, n := range []Notification{
+		{UserID: author.ID, Type: NotifyLike, ActorID: fan.ID, ChirpID: deleted.ID},
+		{UserID: author.ID, Type: NotifyLike, ActorID: fan.ID, ChirpID: kept.ID},
+		{UserID: author.ID, Type: NotifyFollow, ActorID: fan.ID},
+	} {
+		_, err := db.AddNotification(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.DeleteChirp(deleted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifications, unread := db.GetNotifications(author.ID, 0, 10, false)
+	if len(notifications) != 2 || unread != 2 {
+		t.Fatalf("got %d notifications with %d unread, want 2 and 2", len(notifications), unread)
+	}
+	for _, n := range notifications {
+		if n.ChirpID == deleted.ID {
+			t.Errorf("notification %d about the deleted chirp was kept", n.ID)
+		}
+	}
+}
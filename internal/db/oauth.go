@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidClient = errors.New("invalid client")
+var ErrInvalidCode = errors.New("invalid authorization code")
+
+type OAuthClient struct {
+	ID           string    `json:"client_id"`
+	SecretHash   []byte    `json:"secret_hash"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	OwnerID      int       `json:"owner_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AuthCode struct {
+	Code          string    `json:"code"`
+	ClientID      string    `json:"client_id"`
+	UserID        int       `json:"user_id"`
+	RedirectURI   string    `json:"redirect_uri"`
+	Scope         string    `json:"scope"`
+	CodeChallenge string    `json:"code_challenge"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (db *Database) AddOAuthClient(client OAuthClient) (OAuthClient, error) {
+	db.mu.Lock()
+	client.CreatedAt = time.Now()
+	db.OAuthClients[client.ID] = client
+	db.mu.Unlock()
+	err := db.writeDB()
+	if err != nil {
+		return OAuthClient{}, err
+	}
+	return client, nil
+}
+
+func (db *Database) GetOAuthClient(clientID string) (OAuthClient, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	client, ok := db.OAuthClients[clientID]
+	if !ok {
+		return OAuthClient{}, ErrInvalidClient
+	}
+	return client, nil
+}
+
+func (db *Database) AddAuthCode(code AuthCode) error {
+	db.mu.Lock()
+	now := time.Now()
+	for key, existing := range db.AuthCodes {
+		if now.After(existing.ExpiresAt) {
+			delete(db.AuthCodes, key)
+		}
+	}
+	db.AuthCodes[code.Code] = code
+	db.mu.Unlock()
+	err := db.writeDB()
+	return err
+}
+
+// ConsumeAuthCode returns the code and deletes it, so each code is single use.
+func (db *Database) ConsumeAuthCode(code string) (AuthCode, error) {
+	db.mu.Lock()
+	authCode, ok := db.AuthCodes[code]
+	delete(db.AuthCodes, code)
+	db.mu.Unlock()
+	if !ok || time.Now().After(authCode.ExpiresAt) {
+		return AuthCode{}, ErrInvalidCode
+	}
+	err := db.writeDB()
+	return authCode, err
+}
+
+func (db *Database) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	db.mu.Lock()
+	now := time.Now()
+	for key, expiry := range db.RevokedAccessTokens {
+		if now.After(expiry) {
+			delete(db.RevokedAccessTokens, key)
+		}
+	}
+	db.RevokedAccessTokens[jti] = expiresAt
+	db.mu.Unlock()
+	err := db.writeDB()
+	return err
+}
+
+func (db *Database) IsAccessTokenRevoked(jti string) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, ok := db.RevokedAccessTokens[jti]
+	return ok
+}
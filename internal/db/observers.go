@@ -0,0 +1,34 @@
+package db
+
+import "slices"
+
+// ChirpObserver is told about chirps after they are written to disk, so that
+// derived state such as the search index can stay in step with the database.
+type ChirpObserver interface {
+	ChirpSaved(chirp Chirp)
+	ChirpDeleted(chirpID int)
+}
+
+func (db *Database) ObserveChirps(observer ChirpObserver) {
+	db.mu.Lock()
+	*db.observers = append(*db.observers, observer)
+	db.mu.Unlock()
+}
+
+func (db *Database) chirpObservers() []ChirpObserver {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return slices.Clone(*db.observers)
+}
+
+func (db *Database) notifyChirpSaved(chirp Chirp) {
+	for _, observer := range db.chirpObservers() {
+		observer.ChirpSaved(chirp)
+	}
+}
+
+func (db *Database) notifyChirpDeleted(chirpID int) {
+	for _, observer := range db.chirpObservers() {
+		observer.ChirpDeleted(chirpID)
+	}
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrPendingNotFound = errors.New("pending chirp not found")
+
+const (
+	PendingDraft     = "draft"
+	PendingScheduled = "scheduled"
+	PendingFailed    = "failed"
+)
+
+// PendingChirp is a chirp that has not been published: a private draft, or a
+// chirp scheduled to publish at PublishAt. Scheduled chirps that could not be
+// published are kept as failed, with the reason in Error.
+type PendingChirp struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"author_id"`
+	Status    string    `json:"status"`
+	Body      string    `json:"body"`
+	Media     []string  `json:"media,omitempty"`
+	ReplyTo   int       `json:"reply_to,omitempty"`
+	QuoteOf   int       `json:"quote_of,omitempty"`
+	PublishAt time.Time `json:"publish_at"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SavePendingChirp stores a new pending chirp, or replaces the author's
+// existing one when pending.ID is set.
+func (db *Database) SavePendingChirp(pending PendingChirp) (PendingChirp, error) {
+	db.mu.Lock()
+	now := time.Now().UTC()
+	if pending.ID == 0 {
+		pending.ID = db.allocateID("pending_chirps", nextID(db.PendingChirps))
+		pending.CreatedAt = now
+	} else {
+		existing, ok := db.PendingChirps[pending.ID]
+		if !ok || existing.UserID != pending.UserID {
+			db.mu.Unlock()
+			return PendingChirp{}, ErrPendingNotFound
+		}
+		pending.CreatedAt = existing.CreatedAt
+	}
+	pending.UpdatedAt = now
+	db.PendingChirps[pending.ID] = pending
+	db.mu.Unlock()
+	err := db.writeDB()
+	return pending, err
+}
+
+// GetPendingChirp returns one of a user's pending chirps, or any user's when
+// userID is 0.
+func (db *Database) GetPendingChirp(id, userID int) (PendingChirp, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	pending, ok := db.PendingChirps[id]
+	if !ok || (userID != 0 && pending.UserID != userID) {
+		return PendingChirp{}, ErrPendingNotFound
+	}
+	return pending, nil
+}
+
+// ListPendingChirps returns a user's pending chirps with any of the given
+// statuses. Drafts come newest first and scheduled chirps soonest first.
+func (db *Database) ListPendingChirps(userID int, statuses ...string) []PendingChirp {
+	db.mu.RLock()
+	list := []PendingChirp{}
+	for _, pending := range db.PendingChirps {
+		if pending.UserID != userID {
+			continue
+		}
+		for _, status := range statuses {
+			if pending.Status == status {
+				list = append(list, pending)
+				break
+			}
+		}
+	}
+	db.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].PublishAt.Equal(list[j].PublishAt) {
+			return list[i].PublishAt.Before(list[j].PublishAt)
+		}
+		return list[i].ID > list[j].ID
+	})
+	return list
+}
+
+// CountScheduledChirps returns how many chirps a user has waiting to publish.
+func (db *Database) CountScheduledChirps(userID int) int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	count := 0
+	for _, pending := range db.PendingChirps {
+		if pending.UserID == userID && pending.Status == PendingScheduled {
+			count++
+		}
+	}
+	return count
+}
+
+// ScheduledChirps returns the publish time of every scheduled chirp, to load
+// into a scheduler.
+func (db *Database) ScheduledChirps() map[int]time.Time {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	jobs := map[int]time.Time{}
+	for id, pending := range db.PendingChirps {
+		if pending.Status == PendingScheduled {
+			jobs[id] = pending.PublishAt
+		}
+	}
+	return jobs
+}
+
+// DeletePendingChirp removes a pending chirp. A userID of 0 skips the
+// ownership check, for use once a chirp has been published.
+func (db *Database) DeletePendingChirp(id, userID int) error {
+	db.mu.Lock()
+	pending, ok := db.PendingChirps[id]
+	if !ok || (userID != 0 && pending.UserID != userID) {
+		db.mu.Unlock()
+		return ErrPendingNotFound
+	}
+	delete(db.PendingChirps, id)
+	db.mu.Unlock()
+	return db.writeDB()
+}
+
+// FailPendingChirp marks a scheduled chirp as failed to publish.
+func (db *Database) FailPendingChirp(id int, reason string) error {
+	db.mu.Lock()
+	pending, ok := db.PendingChirps[id]
+	if !ok {
+		db.mu.Unlock()
+		return ErrPendingNotFound
+	}
+	pending.Status = PendingFailed
+	pending.Error = reason
+	pending.UpdatedAt = time.Now().UTC()
+	db.PendingChirps[id] = pending
+	db.mu.Unlock()
+	return db.writeDB()
+}
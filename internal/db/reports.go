@@ -0,0 +1,123 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrReportNotFound = errors.New("report not found")
+var ErrReportClaimed = errors.New("report claimed by another moderator")
+var ErrReportResolved = errors.New("report already resolved")
+
+const (
+	ReportOpen     = "open"
+	ReportClaimed  = "claimed"
+	ReportResolved = "resolved"
+)
+
+// Report asks moderators to review a chirp or user. Reports raised by the
+// moderation pipeline have no reporter.
+type Report struct {
+	ID         int       `json:"id"`
+	ReporterID int       `json:"reporter_id,omitempty"`
+	ChirpID    int       `json:"chirp_id,omitempty"`
+	UserID     int       `json:"user_id"`
+	Reason     string    `json:"reason"`
+	Status     string    `json:"status"`
+	ClaimedBy  int       `json:"claimed_by,omitempty"`
+	Resolution string    `json:"resolution,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+func (db *Database) AddReport(report Report) (Report, error) {
+	db.mu.Lock()
+	if _, ok := db.Users[report.UserID]; !ok {
+		db.mu.Unlock()
+		return Report{}, ErrInvalidUserID
+	}
+	report.ID = db.allocateID("reports", nextID(db.Reports))
+	report.Status = ReportOpen
+	report.CreatedAt = time.Now().UTC()
+	db.Reports[report.ID] = report
+	db.mu.Unlock()
+	err := db.writeDB()
+	return report, err
+}
+
+// ListReports returns reports with the given status, or all reports when it
+// is empty, oldest first.
+func (db *Database) ListReports(status string) []Report {
+	db.mu.RLock()
+	reports := []Report{}
+	for _, report := range db.Reports {
+		if status == "" || report.Status == status {
+			reports = append(reports, report)
+		}
+	}
+	db.mu.RUnlock()
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].ID < reports[j].ID
+	})
+	return reports
+}
+
+func (db *Database) GetReport(reportID int) (Report, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	report, ok := db.Reports[reportID]
+	if !ok {
+		return Report{}, ErrReportNotFound
+	}
+	return report, nil
+}
+
+func (db *Database) ClaimReport(reportID, moderatorID int) (Report, error) {
+	db.mu.Lock()
+	report, ok := db.Reports[reportID]
+	switch {
+	case !ok:
+		db.mu.Unlock()
+		return Report{}, ErrReportNotFound
+	case report.Status == ReportResolved:
+		db.mu.Unlock()
+		return Report{}, ErrReportResolved
+	case report.Status == ReportClaimed && report.ClaimedBy != moderatorID:
+		db.mu.Unlock()
+		return Report{}, ErrReportClaimed
+	}
+	report.Status = ReportClaimed
+	report.ClaimedBy = moderatorID
+	db.Reports[reportID] = report
+	db.mu.Unlock()
+	err := db.writeDB()
+	return report, err
+}
+
+// ResolveReport closes a report that is open or claimed by the moderator.
+func (db *Database) ResolveReport(reportID, moderatorID int, resolution, note string) (Report, error) {
+	db.mu.Lock()
+	report, ok := db.Reports[reportID]
+	switch {
+	case !ok:
+		db.mu.Unlock()
+		return Report{}, ErrReportNotFound
+	case report.Status == ReportResolved:
+		db.mu.Unlock()
+		return Report{}, ErrReportResolved
+	case report.Status == ReportClaimed && report.ClaimedBy != moderatorID:
+		db.mu.Unlock()
+		return Report{}, ErrReportClaimed
+	}
+	report.Status = ReportResolved
+	report.ClaimedBy = moderatorID
+	report.Resolution = resolution
+	report.Note = note
+	report.ResolvedAt = time.Now().UTC()
+	db.Reports[reportID] = report
+	db.mu.Unlock()
+	err := db.writeDB()
+	return report, err
+}
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrUnknownSubscriptionEvent = errors.New("unknown subscription event")
+var ErrNoSubscription = errors.New("user has no subscription")
+
+const (
+	SubscriptionActive    = "active"
+	SubscriptionPastDue   = "past_due"
+	SubscriptionCancelled = "cancelled"
+	SubscriptionExpired   = "expired"
+	SubscriptionRefunded  = "refunded"
+
+	PlanFree      = "free"
+	PlanChirpyRed = "chirpy_red"
+
+	DefaultSubscriptionPeriod = 30 * 24 * time.Hour
+)
+
+type SubscriptionPolicy struct {
+	Period      time.Duration
+	GracePeriod time.Duration
+}
+
+type Subscription struct {
+	UserID           int       `json:"user_id"`
+	Plan             string    `json:"plan"`
+	PendingPlan      string    `json:"pending_plan,omitempty"`
+	Status           string    `json:"status"`
+	StartedAt        time.Time `json:"started_at"`
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+	GraceUntil       time.Time `json:"grace_until"`
+	EndedAt          time.Time `json:"ended_at"`
+	LastEventAt      time.Time `json:"last_event_at"`
+}
+
+type SubscriptionEvent struct {
+	Type      string
+	UserID    int
+	Plan      string
+	PeriodEnd time.Time
+	CreatedAt time.Time
+}
+
+// Entitled reports whether the subscription currently grants its plan.
+// Cancelled subscriptions run to the end of the paid period and past due
+// subscriptions keep access until the grace period ends.
+func (s Subscription) Entitled(now time.Time) bool {
+	switch s.Status {
+	case SubscriptionActive:
+		return true
+	case SubscriptionPastDue:
+		return now.Before(s.GraceUntil)
+	case SubscriptionCancelled:
+		return now.Before(s.CurrentPeriodEnd)
+	}
+	return false
+}
+
+// ActivePlan is the plan the user is entitled to right now.
+func (s Subscription) ActivePlan(now time.Time) string {
+	if s.Plan == "" || !s.Entitled(now) {
+		return PlanFree
+	}
+	return s.Plan
+}
+
+func (db *Database) GetSubscription(userID int) (Subscription, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	subscription, ok := db.Subscriptions[userID]
+	if !ok {
+		return Subscription{}, ErrNoSubscription
+	}
+	return subscription, nil
+}
+
+// ApplySubscriptionEvent advances a user's subscription. Events created before
+// the last applied event are stale deliveries and are skipped, reporting false.
+func (db *Database) ApplySubscriptionEvent(event SubscriptionEvent, policy SubscriptionPolicy, now time.Time) (Subscription, bool, error) {
+	db.mu.Lock()
+	if _, ok := db.Users[event.UserID]; !ok {
+		db.mu.Unlock()
+		return Subscription{}, false, ErrInvalidUserID
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now
+	}
+	s, ok := db.Subscriptions[event.UserID]
+	if !ok {
+		s = Subscription{UserID: event.UserID, Status: SubscriptionExpired}
+	}
+	if event.CreatedAt.Before(s.LastEventAt) {
+		db.mu.Unlock()
+		return s, false, nil
+	}
+	periodEnd := event.PeriodEnd
+	if periodEnd.IsZero() {
+		periodEnd = now.Add(policy.Period)
+	}
+
+	switch event.Type {
+	case "user.upgraded":
+		if !s.Entitled(now) {
+			s.StartedAt = now
+		}
+		s.Plan = PlanChirpyRed
+		if event.Plan != "" {
+			s.Plan = event.Plan
+		}
+		s.PendingPlan = ""
+		s.Status = SubscriptionActive
+		s.CurrentPeriodEnd = periodEnd
+		s.GraceUntil = time.Time{}
+		s.EndedAt = time.Time{}
+	case "subscription.renewed":
+		if s.Plan == "" {
+			s.Plan = PlanChirpyRed
+		}
+		if s.PendingPlan != "" {
+			s.Plan = s.PendingPlan
+			s.PendingPlan = ""
+		}
+		if !s.Entitled(now) {
+			s.StartedAt = now
+		}
+		s.Status = SubscriptionActive
+		s.CurrentPeriodEnd = periodEnd
+		s.GraceUntil = time.Time{}
+		s.EndedAt = time.Time{}
+	case "user.downgraded":
+		// Downgrades take effect at the end of the paid period; downgrading to
+		// the free plan is a cancellation.
+		if event.Plan == "" || event.Plan == PlanFree {
+			if s.Status == SubscriptionActive || s.Status == SubscriptionPastDue {
+				s.Status = SubscriptionCancelled
+			}
+		} else {
+			s.PendingPlan = event.Plan
+		}
+	case "subscription.cancelled":
+		if s.Status == SubscriptionActive || s.Status == SubscriptionPastDue {
+			s.Status = SubscriptionCancelled
+		}
+	case "payment.failed":
+		if s.Status == SubscriptionActive {
+			s.Status = SubscriptionPastDue
+			s.GraceUntil = s.CurrentPeriodEnd.Add(policy.GracePeriod)
+		}
+	case "payment.refunded":
+		s.Status = SubscriptionRefunded
+		s.EndedAt = now
+	default:
+		db.mu.Unlock()
+		return s, false, ErrUnknownSubscriptionEvent
+	}
+	s.LastEventAt = event.CreatedAt
+	db.Subscriptions[event.UserID] = s
+	db.syncChirpyRed(event.UserID, now)
+	db.mu.Unlock()
+	err := db.writeDB()
+	return s, true, err
+}
+
+// ExpireSubscriptions moves subscriptions whose renewal has lapsed into the
+// grace period and ends those whose grace or paid period is over.
+func (db *Database) ExpireSubscriptions(policy SubscriptionPolicy, now time.Time) (int, error) {
+	db.mu.Lock()
+	changed := 0
+	for userID, s := range db.Subscriptions {
+		previous := s.Status
+		if s.Status == SubscriptionActive && now.After(s.CurrentPeriodEnd) {
+			s.Status = SubscriptionPastDue
+			s.GraceUntil = s.CurrentPeriodEnd.Add(policy.GracePeriod)
+		}
+		if (s.Status == SubscriptionPastDue || s.Status == SubscriptionCancelled) && !s.Entitled(now) {
+			s.Status = SubscriptionExpired
+			s.EndedAt = now
+		}
+		if s.Status != previous {
+			db.Subscriptions[userID] = s
+			db.syncChirpyRed(userID, now)
+			changed++
+		}
+	}
+	db.mu.Unlock()
+	if changed == 0 {
+		return 0, nil
+	}
+	err := db.writeDB()
+	return changed, err
+}
+
+// backfillSubscriptions gives users upgraded before subscriptions existed, who
+// have only the ChirpyRed flag, an active subscription starting a fresh period,
+// then derives every user's flag from their subscription. It reports whether
+// any subscription was added. Callers must hold the write lock.
+func (db *Database) backfillSubscriptions(now time.Time) bool {
+	backfilled := false
+	for userID, user := range db.Users {
+		if _, ok := db.Subscriptions[userID]; user.ChirpyRed && !ok {
+			db.Subscriptions[userID] = Subscription{
+				UserID:           userID,
+				Plan:             PlanChirpyRed,
+				Status:           SubscriptionActive,
+				StartedAt:        now,
+				CurrentPeriodEnd: now.Add(DefaultSubscriptionPeriod),
+			}
+			backfilled = true
+		}
+		db.syncChirpyRed(userID, now)
+	}
+	return backfilled
+}
+
+// syncChirpyRed keeps the denormalised flag on the user record in step with the
+// subscription. Callers must hold the write lock.
+func (db *Database) syncChirpyRed(userID int, now time.Time) {
+	user, ok := db.Users[userID]
+	if !ok {
+		return
+	}
+	user.ChirpyRed = db.Subscriptions[userID].ActivePlan(now) != PlanFree
+	db.Users[userID] = user
+}
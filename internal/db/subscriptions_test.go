@@ -0,0 +1,183 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+var testPolicy = SubscriptionPolicy{Period: 30 * 24 * time.Hour, GracePeriod: 3 * 24 * time.Hour}
+
+func TestApplySubscriptionEventOrdering(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	tests := []struct {
+		name       string
+		events     []SubscriptionEvent
+		wantStatus string
+		wantRed    bool
+		wantSkips  int
+	}{
+		{
+			name:       "upgrade",
+			events:     []SubscriptionEvent{{Type: "user.upgraded", CreatedAt: at(0)}},
+			wantStatus: SubscriptionActive,
+			wantRed:    true,
+		},
+		{
+			name: "cancel then late upgrade delivery",
+			events: []SubscriptionEvent{
+				{Type: "subscription.cancelled", CreatedAt: at(2)},
+				{Type: "user.upgraded", CreatedAt: at(1)},
+			},
+			wantStatus: SubscriptionExpired,
+			wantSkips:  1,
+		},
+		{
+			name: "upgrade then cancel",
+			events: []SubscriptionEvent{
+				{Type: "user.upgraded", CreatedAt: at(1)},
+				{Type: "subscription.cancelled", CreatedAt: at(2)},
+			},
+			wantStatus: SubscriptionCancelled,
+			wantRed:    true,
+		},
+		{
+			name: "refund beats late renewal",
+			events: []SubscriptionEvent{
+				{Type: "user.upgraded", CreatedAt: at(1)},
+				{Type: "payment.refunded", CreatedAt: at(3)},
+				{Type: "subscription.renewed", CreatedAt: at(2)},
+			},
+			wantStatus: SubscriptionRefunded,
+			wantSkips:  1,
+		},
+		{
+			name: "payment failure keeps grace access",
+			events: []SubscriptionEvent{
+				{Type: "user.upgraded", CreatedAt: at(1)},
+				{Type: "payment.failed", CreatedAt: at(2)},
+			},
+			wantStatus: SubscriptionPastDue,
+			wantRed:    true,
+		},
+		{
+			name: "same timestamp is not stale",
+			events: []SubscriptionEvent{
+				{Type: "user.upgraded", CreatedAt: at(1)},
+				{Type: "payment.failed", CreatedAt: at(1)},
+			},
+			wantStatus: SubscriptionPastDue,
+			wantRed:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDatabase(t)
+			user, err := db.AddUser("a@example.com", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			skips := 0
+			for _, event := range tt.events {
+				event.UserID = user.ID
+				_, applied, err := db.ApplySubscriptionEvent(event, testPolicy, at(5))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !applied {
+					skips++
+				}
+			}
+			subscription, _ := db.GetSubscription(user.ID)
+			if subscription.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", subscription.Status, tt.wantStatus)
+			}
+			if db.Users[user.ID].ChirpyRed != tt.wantRed {
+				t.Errorf("ChirpyRed = %v, want %v", db.Users[user.ID].ChirpyRed, tt.wantRed)
+			}
+			if skips != tt.wantSkips {
+				t.Errorf("skipped %d stale events, want %d", skips, tt.wantSkips)
+			}
+		})
+	}
+}
+
+func TestApplySubscriptionEventRejectsUnknown(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	_, _, err := db.ApplySubscriptionEvent(SubscriptionEvent{Type: "user.teleported", UserID: user.ID}, testPolicy, time.Now())
+	if err != ErrUnknownSubscriptionEvent {
+		t.Errorf("err = %v, want ErrUnknownSubscriptionEvent", err)
+	}
+	_, _, err = db.ApplySubscriptionEvent(SubscriptionEvent{Type: "user.upgraded", UserID: 99}, testPolicy, time.Now())
+	if err != ErrInvalidUserID {
+		t.Errorf("err = %v, want ErrInvalidUserID", err)
+	}
+}
+
+func TestExpireSubscriptions(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	start := time.Now()
+	_, _, err := db.ApplySubscriptionEvent(SubscriptionEvent{Type: "user.upgraded", UserID: user.ID, CreatedAt: start}, testPolicy, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		after  time.Duration
+		status string
+		red    bool
+	}{
+		{testPolicy.Period - time.Hour, SubscriptionActive, true},
+		{testPolicy.Period + time.Hour, SubscriptionPastDue, true},
+		{testPolicy.Period + testPolicy.GracePeriod + time.Hour, SubscriptionExpired, false},
+	}
+	for _, check := range checks {
+		_, err := db.ExpireSubscriptions(testPolicy, start.Add(check.after))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subscription, _ := db.GetSubscription(user.ID)
+		if subscription.Status != check.status || db.Users[user.ID].ChirpyRed != check.red {
+			t.Errorf("after %s: status %q red %v, want %q %v", check.after, subscription.Status, db.Users[user.ID].ChirpyRed, check.status, check.red)
+		}
+	}
+}
+
+// Users upgraded before subscriptions existed only have the flag set.
+func TestLoadBackfillsLegacyChirpyRed(t *testing.T) {
+	db := newTestDatabase(t)
+	legacy, _ := db.AddUser("legacy@example.com", nil)
+	lapsed, _ := db.AddUser("lapsed@example.com", nil)
+	db.mu.Lock()
+	for _, id := range []int{legacy.ID, lapsed.ID} {
+		user := db.Users[id]
+		user.ChirpyRed = true
+		db.Users[id] = user
+	}
+	db.Subscriptions[lapsed.ID] = Subscription{UserID: lapsed.ID, Plan: PlanChirpyRed, Status: SubscriptionExpired}
+	db.mu.Unlock()
+	err := db.writeDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = reload(t, db)
+	subscription, err := db.GetSubscription(legacy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscription.ActivePlan(time.Now()) != PlanChirpyRed || !db.Users[legacy.ID].ChirpyRed {
+		t.Errorf("legacy user: plan %q red %v, want chirpy_red true", subscription.ActivePlan(time.Now()), db.Users[legacy.ID].ChirpyRed)
+	}
+	if db.Users[lapsed.ID].ChirpyRed {
+		t.Error("lapsed user kept the ChirpyRed flag")
+	}
+
+	db = reload(t, db)
+	if again, _ := db.GetSubscription(legacy.ID); !again.CurrentPeriodEnd.Equal(subscription.CurrentPeriodEnd) {
+		t.Error("backfill was not persisted and ran again on restart")
+	}
+}
@@ -0,0 +1,70 @@
+package db
+
+import "sort"
+
+// ThreadNode is a chirp in a conversation tree. Deleted chirps that still
+// have replies appear with Deleted set and no Chirp; callers may do the same
+// with Hidden for chirps the reader may not see.
+type ThreadNode struct {
+	ID      int          `json:"id"`
+	Deleted bool         `json:"deleted,omitempty"`
+	Hidden  bool         `json:"hidden,omitempty"`
+	Chirp   *Chirp       `json:"chirp,omitempty"`
+	Replies []ThreadNode `json:"replies"`
+}
+
+// GetThread returns the whole conversation containing a chirp, from its root.
+func (db *Database) GetThread(chirpID int) (ThreadNode, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rootID := chirpID
+	for {
+		parentID, ok := db.parentOf(rootID)
+		if !ok {
+			return ThreadNode{}, ErrInvalidChirpID
+		}
+		if parentID == 0 {
+			break
+		}
+		rootID = parentID
+	}
+
+	replies := map[int][]int{}
+	for _, chirp := range db.Chirps {
+		if chirp.ReplyTo != 0 {
+			replies[chirp.ReplyTo] = append(replies[chirp.ReplyTo], chirp.ID)
+		}
+	}
+	for _, deleted := range db.DeletedChirps {
+		if deleted.ReplyTo != 0 {
+			replies[deleted.ReplyTo] = append(replies[deleted.ReplyTo], deleted.ID)
+		}
+	}
+	return db.threadNode(rootID, replies), nil
+}
+
+func (db *Database) parentOf(chirpID int) (int, bool) {
+	if chirp, ok := db.Chirps[chirpID]; ok {
+		return chirp.ReplyTo, true
+	}
+	if deleted, ok := db.DeletedChirps[chirpID]; ok {
+		return deleted.ReplyTo, true
+	}
+	return 0, false
+}
+
+func (db *Database) threadNode(chirpID int, replies map[int][]int) ThreadNode {
+	node := ThreadNode{ID: chirpID, Replies: []ThreadNode{}}
+	if chirp, ok := db.Chirps[chirpID]; ok {
+		node.Chirp = &chirp
+	} else {
+		node.Deleted = true
+	}
+	children := replies[chirpID]
+	sort.Ints(children)
+	for _, childID := range children {
+		node.Replies = append(node.Replies, db.threadNode(childID, replies))
+	}
+	return node
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// threadShape lists the IDs of a thread depth first, marking deleted chirps
+// with a minus sign.
+func threadShape(node ThreadNode) []int {
+	id := node.ID
+	if node.Deleted {
+		id = -id
+	}
+	shape := []int{id}
+	for _, reply := range node.Replies {
+		shape = append(shape, threadShape(reply)...)
+	}
+	return shape
+}
+
+func TestThreadsKeepDeletedChirpsWithReplies(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	post := func(replyTo int) Chirp {
+		t.Helper()
+		chirp, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "chirp", ReplyTo: replyTo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chirp
+	}
+	root := post(0)
+	middle := post(root.ID)
+	sibling := post(root.ID)
+	leaf := post(middle.ID)
+	if db.Chirps[root.ID].ReplyCount != 2 || db.Chirps[middle.ID].ReplyCount != 1 {
+		t.Fatalf("reply counts = %d, %d, want 2, 1", db.Chirps[root.ID].ReplyCount, db.Chirps[middle.ID].ReplyCount)
+	}
+
+	steps := []struct {
+		name   string
+		delete int
+		from   int
+		want   []int
+	}{
+		{"whole thread from a leaf", 0, leaf.ID, []int{root.ID, middle.ID, leaf.ID, sibling.ID}},
+		{"delete a chirp with a reply", middle.ID, leaf.ID, []int{root.ID, -middle.ID, leaf.ID, sibling.ID}},
+		{"delete its last reply", leaf.ID, sibling.ID, []int{root.ID, sibling.ID}},
+	}
+	for _, step := range steps {
+		if step.delete != 0 {
+			err := db.DeleteChirp(step.delete)
+			if err != nil {
+				t.Fatalf("%s: %s", step.name, err)
+			}
+		}
+		thread, err := db.GetThread(step.from)
+		if err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+		if got := threadShape(thread); !slices.Equal(got, step.want) {
+			t.Errorf("%s: thread = %v, want %v", step.name, got, step.want)
+		}
+	}
+	if len(db.DeletedChirps) != 0 {
+		t.Errorf("placeholders left after their replies went: %v", db.DeletedChirps)
+	}
+	if db.Chirps[root.ID].ReplyCount != 1 {
+		t.Errorf("root reply count = %d, want 1", db.Chirps[root.ID].ReplyCount)
+	}
+}
+
+// Deleting the root of a chain keeps a placeholder for each deleted ancestor
+// until the replies below it are gone.
+func TestDeletedAncestorsAreClearedTogether(t *testing.T) {
+	db := newTestDatabase(t)
+	user, _ := db.AddUser("a@example.com", nil)
+	root, _ := db.CreateChirp(Chirp{UserID: user.ID, Body: "root"})
+	reply, _ := db.CreateChirp(Chirp{UserID: user.ID, Body: "reply", ReplyTo: root.ID})
+	leaf, _ := db.CreateChirp(Chirp{UserID: user.ID, Body: "leaf", ReplyTo: reply.ID})
+	for _, id := range []int{root.ID, reply.ID} {
+		err := db.DeleteChirp(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	thread, err := db.GetThread(leaf.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := threadShape(thread); !slices.Equal(got, []int{-root.ID, -reply.ID, leaf.ID}) {
+		t.Errorf("thread = %v", got)
+	}
+	if _, err := db.CreateChirp(Chirp{UserID: user.ID, Body: "late", ReplyTo: reply.ID}); !errors.Is(err, ErrInvalidChirpID) {
+		t.Errorf("replying to a deleted chirp: err = %v, want ErrInvalidChirpID", err)
+	}
+
+	err = db.DeleteChirp(leaf.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.DeletedChirps) != 0 {
+		t.Errorf("placeholders left: %v", db.DeletedChirps)
+	}
+	if _, err := db.GetThread(root.ID); !errors.Is(err, ErrInvalidChirpID) {
+		t.Errorf("thread of a fully deleted conversation: err = %v, want ErrInvalidChirpID", err)
+	}
+}
@@ -26,9 +26,11 @@ func (db *Database) RevokeToken(token string) error {
 	db.mu.Lock()
 	_, ok := db.Tokens[token]
 	if !ok {
+		db.mu.Unlock()
 		return errors.New("token does not exist")
 	}
 	if !db.Tokens[token].Valid {
+		db.mu.Unlock()
 		return errors.New("token already revoked")
 	}
 	db.Tokens[token] = Token{
@@ -40,3 +42,9 @@ func (db *Database) RevokeToken(token string) error {
 	err := db.writeDB()
 	return err
 }
+
+func (db *Database) IsTokenValid(token string) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.Tokens[token].Valid
+}
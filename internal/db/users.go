@@ -2,6 +2,10 @@ package db
 
 import (
 	"errors"
+	"strings"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -10,28 +14,37 @@ var ErrTakenEmail = errors.New("email already taken")
 var ErrInvalidEmail = errors.New("invalid email address")
 var ErrIncorrectPassword = errors.New("inocrrect password")
 var ErrInvalidUserID = errors.New("invalid user ID")
+var ErrTakenHandle = errors.New("handle already taken")
 
 type User struct {
-	ID           int    `json:"id"`
-	Email        string `json:"email"`
-	PasswordHash []byte `json:"hash"`
-	ChirpyRed    bool   `json:"is_chirpy_red"`
+	ID           int                  `json:"id"`
+	Email        string               `json:"email"`
+	PasswordHash []byte               `json:"hash"`
+	ChirpyRed    bool                 `json:"is_chirpy_red"`
+	Moderator    bool                 `json:"is_moderator"`
+	Credentials  []WebAuthnCredential `json:"webauthn_credentials,omitempty"`
+	Handle       string               `json:"handle,omitempty"`
+	DisplayName  string               `json:"display_name,omitempty"`
+	Bio          string               `json:"bio,omitempty"`
+	AvatarURL    string               `json:"avatar_url,omitempty"`
+	CreatedAt    time.Time            `json:"created_at"`
 }
 
 func (db *Database) AddUser(email string, hash []byte) (User, error) {
 	db.mu.Lock()
 	for _, user := range db.Users {
-		if user.Email == email {
+		if email != "" && user.Email == email {
 			db.mu.Unlock()
 			return User{}, ErrTakenEmail
 		}
 	}
-	id := len(db.Users) + 1
+	id := db.allocateID("users", nextID(db.Users))
 	db.Users[id] = User{
 		ID:           id,
 		Email:        email,
 		PasswordHash: hash,
 		ChirpyRed:    false,
+		CreatedAt:    time.Now().UTC(),
 	}
 	db.mu.Unlock()
 	err := db.writeDB()
@@ -43,12 +56,11 @@ func (db *Database) AddUser(email string, hash []byte) (User, error) {
 
 func (db *Database) UpdateUser(id int, email string, hash []byte) (User, error) {
 	db.mu.Lock()
-	db.Users[id] = User{
-		ID:           id,
-		Email:        email,
-		PasswordHash: hash,
-		ChirpyRed:    db.Users[id].ChirpyRed,
-	}
+	user := db.Users[id]
+	user.ID = id
+	user.Email = email
+	user.PasswordHash = hash
+	db.Users[id] = user
 	db.mu.Unlock()
 	err := db.writeDB()
 	if err != nil {
@@ -62,7 +74,7 @@ func (db *Database) AuthenticateUser(email string, password []byte) (User, error
 	defer db.mu.Unlock()
 	id := 0
 	for i, user := range db.Users {
-		if user.Email == email {
+		if email != "" && user.Email == email {
 			id = i
 		}
 	}
@@ -77,19 +89,97 @@ func (db *Database) AuthenticateUser(email string, password []byte) (User, error
 	return db.Users[id], nil
 }
 
-func (db *Database) AddChirpyRed(userID int) error {
+func (db *Database) GetUserByEmail(email string) (User, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, user := range db.Users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return User{}, ErrInvalidEmail
+}
+
+// Profile is the public part of a user that they can edit.
+type Profile struct {
+	Handle      string
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+}
+
+// UpdateProfile replaces a user's profile. Handles are unique regardless of
+// case.
+func (db *Database) UpdateProfile(id int, profile Profile) (User, error) {
 	db.mu.Lock()
-	_, ok := db.Users[userID]
+	user, ok := db.Users[id]
 	if !ok {
-		return ErrInvalidUserID
+		db.mu.Unlock()
+		return User{}, ErrInvalidUserID
 	}
-	db.Users[userID] = User{
-		ID:           userID,
-		Email:        db.Users[userID].Email,
-		PasswordHash: db.Users[userID].PasswordHash,
-		ChirpyRed:    true,
+	for _, other := range db.Users {
+		if profile.Handle != "" && other.ID != id && strings.EqualFold(other.Handle, profile.Handle) {
+			db.mu.Unlock()
+			return User{}, ErrTakenHandle
+		}
 	}
+	user.Handle = profile.Handle
+	user.DisplayName = profile.DisplayName
+	user.Bio = profile.Bio
+	user.AvatarURL = profile.AvatarURL
+	db.Users[id] = user
 	db.mu.Unlock()
-	err := db.writeDB()
-	return err
+	return user, db.writeDB()
+}
+
+func (db *Database) GetUserByHandle(handle string) (User, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, user := range db.Users {
+		if handle != "" && strings.EqualFold(user.Handle, handle) {
+			return user, nil
+		}
+	}
+	return User{}, ErrInvalidUserID
+}
+
+// ResolveMentions sets the UserID of mentions whose handle belongs to a user.
+func (db *Database) ResolveMentions(parsed entities.Entities) entities.Entities {
+	for i, mention := range parsed.Mentions {
+		user, err := db.GetUserByHandle(mention.Handle)
+		if err == nil {
+			parsed.Mentions[i].UserID = user.ID
+		}
+	}
+	return parsed
+}
+
+// backfillJoinDates gives users stored before join dates were recorded the
+// time of their earliest chirp or follow. Users with neither are left without
+// one. It reports whether any user changed. Callers must hold the write lock.
+func (db *Database) backfillJoinDates() bool {
+	earliest := map[int]time.Time{}
+	seen := func(userID int, at time.Time) {
+		if first, ok := earliest[userID]; !at.IsZero() && (!ok || at.Before(first)) {
+			earliest[userID] = at
+		}
+	}
+	for _, chirp := range db.Chirps {
+		seen(chirp.UserID, chirp.CreatedAt)
+	}
+	for followerID, following := range db.Follows {
+		for _, since := range following {
+			seen(followerID, since)
+		}
+	}
+
+	backfilled := false
+	for id, user := range db.Users {
+		if first, ok := earliest[id]; ok && user.CreatedAt.IsZero() {
+			user.CreatedAt = first
+			db.Users[id] = user
+			backfilled = true
+		}
+	}
+	return backfilled
 }
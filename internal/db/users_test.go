@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadBackfillsJoinDates(t *testing.T) {
+	db := newTestDatabase(t)
+	chirper, _ := db.AddUser("chirper@example.com", nil)
+	follower, _ := db.AddUser("follower@example.com", nil)
+	idle, _ := db.AddUser("idle@example.com", nil)
+	firstChirp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	firstFollow := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db.mu.Lock()
+	for _, id := range []int{chirper.ID, follower.ID, idle.ID} {
+		user := db.Users[id]
+		user.CreatedAt = time.Time{}
+		db.Users[id] = user
+	}
+	db.Chirps[1] = Chirp{ID: 1, UserID: chirper.ID, CreatedAt: firstChirp.Add(time.Hour)}
+	db.Chirps[2] = Chirp{ID: 2, UserID: chirper.ID, CreatedAt: firstChirp}
+	db.Follows[follower.ID] = map[int]time.Time{chirper.ID: firstFollow}
+	db.mu.Unlock()
+	err := db.writeDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = reload(t, db)
+	tests := []struct {
+		name string
+		id   int
+		want time.Time
+	}{
+		{"chirper", chirper.ID, firstChirp},
+		{"follower", follower.ID, firstFollow},
+		{"idle", idle.ID, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := db.Users[tt.id].CreatedAt; !got.Equal(tt.want) {
+			t.Errorf("%s joined %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
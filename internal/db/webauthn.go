@@ -0,0 +1,82 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+var ErrCredentialNotFound = errors.New("credential not found")
+var ErrCredentialExists = errors.New("credential already registered")
+
+type WebAuthnCredential struct {
+	ID        []byte    `json:"id"`
+	PublicKey []byte    `json:"public_key"`
+	SignCount uint32    `json:"sign_count"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+func (db *Database) AddCredential(userID int, credential WebAuthnCredential) error {
+	db.mu.Lock()
+	user, ok := db.Users[userID]
+	if !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	for _, u := range db.Users {
+		for _, c := range u.Credentials {
+			if bytes.Equal(c.ID, credential.ID) {
+				db.mu.Unlock()
+				return ErrCredentialExists
+			}
+		}
+	}
+	credential.CreatedAt = time.Now()
+	user.Credentials = append(user.Credentials, credential)
+	db.Users[userID] = user
+	db.mu.Unlock()
+	err := db.writeDB()
+	return err
+}
+
+func (db *Database) GetUserByCredential(credentialID []byte) (User, WebAuthnCredential, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, user := range db.Users {
+		for _, c := range user.Credentials {
+			if bytes.Equal(c.ID, credentialID) {
+				return user, c, nil
+			}
+		}
+	}
+	return User{}, WebAuthnCredential{}, ErrCredentialNotFound
+}
+
+func (db *Database) UpdateCredentialCounter(userID int, credentialID []byte, signCount uint32) error {
+	db.mu.Lock()
+	user, ok := db.Users[userID]
+	if !ok {
+		db.mu.Unlock()
+		return ErrInvalidUserID
+	}
+	credentials := make([]WebAuthnCredential, len(user.Credentials))
+	copy(credentials, user.Credentials)
+	found := false
+	for i, c := range credentials {
+		if bytes.Equal(c.ID, credentialID) {
+			credentials[i].SignCount = signCount
+			credentials[i].LastUsed = time.Now()
+			found = true
+		}
+	}
+	if !found {
+		db.mu.Unlock()
+		return ErrCredentialNotFound
+	}
+	user.Credentials = credentials
+	db.Users[userID] = user
+	db.mu.Unlock()
+	err := db.writeDB()
+	return err
+}
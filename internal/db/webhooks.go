@@ -0,0 +1,83 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	WebhookReceived  = "received"
+	WebhookProcessed = "processed"
+	WebhookIgnored   = "ignored"
+	WebhookFailed    = "failed"
+
+	// webhookClaimTimeout is how long a claimed webhook may stay received
+	// before a redelivery may claim it, in case processing never finished.
+	webhookClaimTimeout = 5 * time.Minute
+)
+
+type Webhook struct {
+	ID          string          `json:"id"`
+	Event       string          `json:"event"`
+	UserID      int             `json:"user_id"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Deliveries  int             `json:"deliveries"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ClaimedAt   time.Time       `json:"claimed_at"`
+	ProcessedAt time.Time       `json:"processed_at"`
+}
+
+// ClaimWebhook records a delivery of the webhook and reports whether it should
+// be processed. Deliveries of an event that is already being handled or has
+// completed are duplicates. Failed events may be retried, as may events whose
+// processing was claimed long enough ago that it must have been abandoned.
+func (db *Database) ClaimWebhook(webhook Webhook, now time.Time) (bool, error) {
+	db.mu.Lock()
+	existing, ok := db.Webhooks[webhook.ID]
+	abandoned := existing.Status == WebhookReceived && now.Sub(existing.ClaimedAt) > webhookClaimTimeout
+	claim := !ok || existing.Status == WebhookFailed || abandoned
+	if ok {
+		existing.Deliveries++
+		if claim {
+			existing.Status = WebhookReceived
+			existing.Payload = webhook.Payload
+			existing.ClaimedAt = now
+		}
+		webhook = existing
+	} else {
+		webhook.Status = WebhookReceived
+		webhook.Deliveries = 1
+		webhook.ReceivedAt = now
+		webhook.ClaimedAt = now
+	}
+	db.Webhooks[webhook.ID] = webhook
+	db.mu.Unlock()
+	err := db.writeDB()
+	return claim, err
+}
+
+func (db *Database) CompleteWebhook(id, status, errorText string) error {
+	db.mu.Lock()
+	webhook := db.Webhooks[id]
+	webhook.Status = status
+	webhook.Error = errorText
+	webhook.ProcessedAt = time.Now()
+	db.Webhooks[id] = webhook
+	db.mu.Unlock()
+	err := db.writeDB()
+	return err
+}
+
+func (db *Database) ListWebhooks(status string) []Webhook {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	webhooks := []Webhook{}
+	for _, webhook := range db.Webhooks {
+		if status == "" || webhook.Status == status {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks
+}
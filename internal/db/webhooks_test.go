@@ -0,0 +1,47 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimWebhook(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		status    string
+		claimedAt time.Time
+		want      bool
+	}{
+		{"in progress", WebhookReceived, now.Add(-time.Minute), false},
+		{"abandoned", WebhookReceived, now.Add(-webhookClaimTimeout - time.Second), true},
+		{"processed", WebhookProcessed, now.Add(-time.Hour), false},
+		{"ignored", WebhookIgnored, now.Add(-time.Hour), false},
+		{"failed", WebhookFailed, now.Add(-time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDatabase(t)
+			claimed, err := db.ClaimWebhook(Webhook{ID: "evt"}, tt.claimedAt)
+			if err != nil || !claimed {
+				t.Fatalf("first delivery: claimed %v, err %v", claimed, err)
+			}
+			if tt.status != WebhookReceived {
+				err = db.CompleteWebhook("evt", tt.status, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			claimed, err = db.ClaimWebhook(Webhook{ID: "evt"}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claimed != tt.want {
+				t.Errorf("claimed = %v, want %v", claimed, tt.want)
+			}
+			if deliveries := db.Webhooks["evt"].Deliveries; deliveries != 2 {
+				t.Errorf("deliveries = %d, want 2", deliveries)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const maxHandleLength = 30
+
+// Entities are the structured parts of a chirp body. Indices are rune offsets
+// into the body, start inclusive and end exclusive, covering the # or @.
+type Entities struct {
+	Hashtags []Hashtag `json:"hashtags"`
+	Mentions []Mention `json:"mentions"`
+}
+
+type Hashtag struct {
+	Tag     string `json:"tag"`
+	Indices [2]int `json:"indices"`
+}
+
+// Mention is an @handle. UserID is set when the handle belongs to a user.
+type Mention struct {
+	Handle  string `json:"handle"`
+	UserID  int    `json:"user_id,omitempty"`
+	Indices [2]int `json:"indices"`
+}
+
+// Parse extracts hashtags and mentions from a chirp body. Both must start a
+// word, so email addresses and URL fragments are not matched. Tags and
+// handles are lower-cased.
+func Parse(body string) Entities {
+	parsed := Entities{Hashtags: []Hashtag{}, Mentions: []Mention{}}
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' && runes[i] != '@' {
+			continue
+		}
+		if i > 0 && (IsTagRune(runes[i-1]) || runes[i-1] == '#' || runes[i-1] == '@') {
+			continue
+		}
+		end := i + 1
+		if runes[i] == '#' {
+			for end < len(runes) && IsTagRune(runes[end]) {
+				end++
+			}
+			tag := strings.ToLower(string(runes[i+1 : end]))
+			if ValidTag(tag) {
+				parsed.Hashtags = append(parsed.Hashtags, Hashtag{Tag: tag, Indices: [2]int{i, end}})
+			}
+		} else {
+			for end < len(runes) && IsHandleRune(runes[end]) {
+				end++
+			}
+			handle := strings.ToLower(string(runes[i+1 : end]))
+			if handle != "" && len(handle) <= maxHandleLength {
+				parsed.Mentions = append(parsed.Mentions, Mention{Handle: handle, Indices: [2]int{i, end}})
+			}
+		}
+		i = end - 1
+	}
+	return parsed
+}
+
+// Tags returns the distinct hashtags, in order of first use.
+func (e Entities) Tags() []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, hashtag := range e.Hashtags {
+		if !seen[hashtag.Tag] {
+			seen[hashtag.Tag] = true
+			tags = append(tags, hashtag.Tag)
+		}
+	}
+	return tags
+}
+
+// ValidTag reports whether tag, without its #, is a hashtag. Tags made only of
+// digits, like #1, are not.
+func ValidTag(tag string) bool {
+	hasLetter := false
+	for _, r := range tag {
+		if !IsTagRune(r) {
+			return false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	return hasLetter
+}
+
+// ValidHandle reports whether handle, without its @, can belong to a user.
+// Like tags, handles made only of digits are not allowed, so they cannot be
+// confused with user IDs.
+func ValidHandle(handle string) bool {
+	if len(handle) > maxHandleLength {
+		return false
+	}
+	hasLetter := false
+	for _, r := range handle {
+		if !IsHandleRune(r) {
+			return false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	return hasLetter
+}
+
+func IsTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func IsHandleRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
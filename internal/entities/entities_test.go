@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		body     string
+		tags     []string
+		mentions []string
+	}{
+		{"no entities here", nil, nil},
+		{"#Go and #golang", []string{"go", "golang"}, nil},
+		{"#go #GO #go", []string{"go"}, nil},
+		{"hello @Alice and @bob_2", nil, []string{"alice", "bob_2"}},
+		{"mail me at a@example.com", nil, nil},
+		{"see example.com/page#section", nil, nil},
+		{"#1 is not a tag but #1st is", []string{"1st"}, nil},
+		{"##double and @@double", nil, nil},
+		{"(#wrapped) @end.", []string{"wrapped"}, []string{"end"}},
+		{"#café au lait", []string{"café"}, nil},
+		{"@" + strings.Repeat("a", 31), nil, nil},
+	}
+	for _, tt := range tests {
+		parsed := Parse(tt.body)
+		mentions := []string{}
+		for _, mention := range parsed.Mentions {
+			mentions = append(mentions, mention.Handle)
+		}
+		if got := parsed.Tags(); !slices.Equal(got, tt.tags) {
+			t.Errorf("Parse(%q) tags = %v, want %v", tt.body, got, tt.tags)
+		}
+		if !slices.Equal(mentions, tt.mentions) {
+			t.Errorf("Parse(%q) mentions = %v, want %v", tt.body, mentions, tt.mentions)
+		}
+	}
+}
+
+// Indices are rune offsets covering the # or @, so clients can link them in
+// bodies with multi-byte characters.
+func TestParseIndices(t *testing.T) {
+	parsed := Parse("héllo #wörld @bob")
+	if len(parsed.Hashtags) != 1 || parsed.Hashtags[0].Indices != [2]int{6, 12} {
+		t.Errorf("hashtags = %+v, want #wörld at [6 12]", parsed.Hashtags)
+	}
+	if len(parsed.Mentions) != 1 || parsed.Mentions[0].Indices != [2]int{13, 17} {
+		t.Errorf("mentions = %+v, want @bob at [13 17]", parsed.Mentions)
+	}
+}
@@ -0,0 +1,53 @@
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+type Limits struct {
+	MaxChirpLength     int  `json:"max_chirp_length"`
+	CanEditChirps      bool `json:"can_edit_chirps"`
+	MaxScheduledChirps int  `json:"max_scheduled_chirps"`
+}
+
+// Plans maps plan names, as stored on subscriptions, to what they allow.
+type Plans map[string]Limits
+
+func Load(path string) (Plans, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plans := Plans{}
+	err = json.Unmarshal(data, &plans)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := plans[db.PlanFree]; !ok {
+		return nil, errors.New("plans config must define the free plan")
+	}
+	for name, limits := range plans {
+		if limits.MaxChirpLength <= 0 {
+			return nil, fmt.Errorf("plan %q must allow chirps of at least one character", name)
+		}
+		if limits.MaxScheduledChirps < 0 {
+			return nil, fmt.Errorf("plan %q has negative limits", name)
+		}
+	}
+	return plans, nil
+}
+
+// For returns the limits of a plan. Unknown plans get the free limits so a
+// stale plan name never grants more than intended.
+func (p Plans) For(plan string) Limits {
+	limits, ok := p[plan]
+	if !ok {
+		return p[db.PlanFree]
+	}
+	return limits
+}
@@ -0,0 +1,704 @@
+package hdl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/activitypub"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+const (
+	maxInboxBody   = 1 << 20
+	outboxPageSize = 20
+)
+
+func (cfg *ApiConfig) actorURL(userID int) string {
+	return cfg.BaseURL + "/users/" + strconv.Itoa(userID)
+}
+
+func (cfg *ApiConfig) noteURL(chirpID int) string {
+	return cfg.BaseURL + "/notes/" + strconv.Itoa(chirpID)
+}
+
+// localNoteID returns the chirp a note URL on this server refers to.
+func (cfg *ApiConfig) localNoteID(noteURL string) (int, bool) {
+	idPart, ok := strings.CutPrefix(noteURL, cfg.BaseURL+"/notes/")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(idPart)
+	return id, err == nil
+}
+
+// localActorID returns the user an actor URL on this server refers to.
+func (cfg *ApiConfig) localActorID(actorURL string) (int, bool) {
+	idPart, ok := strings.CutPrefix(actorURL, cfg.BaseURL+"/users/")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		return 0, false
+	}
+	_, exists := cfg.DB.Users[id]
+	return id, exists
+}
+
+// actorUsername is the name a user is known by to other servers: their handle,
+// or their ID until they choose one.
+func actorUsername(user db.User) string {
+	if user.Handle != "" {
+		return user.Handle
+	}
+	return strconv.Itoa(user.ID)
+}
+
+func (cfg *ApiConfig) signer(userID int) (activitypub.Signer, error) {
+	pemKey, err := cfg.DB.GetActorKey(userID, activitypub.GenerateKey)
+	if err != nil {
+		return activitypub.Signer{}, err
+	}
+	key, err := activitypub.ParsePrivateKey(pemKey)
+	if err != nil {
+		return activitypub.Signer{}, err
+	}
+	return activitypub.Signer{KeyID: cfg.actorURL(userID) + "#main-key", Key: key}, nil
+}
+
+func (cfg *ApiConfig) note(chirp db.Chirp) activitypub.Note {
+	note := activitypub.Note{
+		ID:           cfg.noteURL(chirp.ID),
+		Type:         "Note",
+		AttributedTo: cfg.actorURL(chirp.UserID),
+		Content:      activitypub.HTMLContent(chirp.Body),
+		Published:    chirp.CreatedAt.UTC().Format(time.RFC3339),
+		URL:          cfg.BaseURL + "/api/chirps/" + strconv.Itoa(chirp.ID),
+		InReplyTo:    chirp.RemoteReply,
+		To:           []string{activitypub.PublicAudience},
+		CC:           []string{cfg.actorURL(chirp.UserID) + "/followers"},
+	}
+	if chirp.ReplyTo != 0 {
+		note.InReplyTo = cfg.noteURL(chirp.ReplyTo)
+	}
+	if !chirp.UpdatedAt.Equal(chirp.CreatedAt) {
+		note.Updated = chirp.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return note
+}
+
+func (cfg *ApiConfig) activity(activityType string, userID int, object any) activitypub.Activity {
+	data, _ := json.Marshal(object)
+	return activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      cfg.actorURL(userID) + "/activities/" + randomID(),
+		Type:    activityType,
+		Actor:   cfg.actorURL(userID),
+		To:      []string{activitypub.PublicAudience},
+		CC:      []string{cfg.actorURL(userID) + "/followers"},
+		Object:  data,
+	}
+}
+
+func randomID() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+func writeActivityJSON(w http.ResponseWriter, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+// FEDERATING LOCAL CHIRPS
+
+// federateChirp sends a new or edited chirp to the author's remote followers
+// and, for replies to remote notes, to the author of the note.
+func (cfg *ApiConfig) federateChirp(chirp db.Chirp, activityType string) {
+	if chirp.Hidden {
+		return
+	}
+	inboxes := cfg.remoteInboxes(chirp.UserID)
+	if actor, ok := cfg.remoteNoteAuthor(chirp.RemoteReply); ok && !slices.Contains(inboxes, actor.Inbox) {
+		inboxes = append(inboxes, actor.Inbox)
+	}
+	cfg.deliver(chirp.UserID, inboxes, cfg.activity(activityType, chirp.UserID, cfg.note(chirp)))
+}
+
+// federateDelete tells remote servers a chirp is gone.
+func (cfg *ApiConfig) federateDelete(chirp db.Chirp) {
+	tombstone := map[string]string{"id": cfg.noteURL(chirp.ID), "type": "Tombstone"}
+	inboxes := cfg.remoteInboxes(chirp.UserID)
+	if actor, ok := cfg.remoteNoteAuthor(chirp.RemoteReply); ok && !slices.Contains(inboxes, actor.Inbox) {
+		inboxes = append(inboxes, actor.Inbox)
+	}
+	cfg.deliver(chirp.UserID, inboxes, cfg.activity("Delete", chirp.UserID, tombstone))
+}
+
+// remoteInboxes lists the inboxes of a user's remote followers, using shared
+// inboxes to send once per server where possible.
+func (cfg *ApiConfig) remoteInboxes(userID int) []string {
+	inboxes := []string{}
+	for _, actor := range cfg.DB.GetRemoteFollowers(userID) {
+		inbox := actor.Inbox
+		if actor.SharedInbox != "" {
+			inbox = actor.SharedInbox
+		}
+		if !slices.Contains(inboxes, inbox) {
+			inboxes = append(inboxes, inbox)
+		}
+	}
+	return inboxes
+}
+
+// checkRemoteReply validates the remote note a chirp replies to, which must be
+// one this server has received.
+func (cfg *ApiConfig) checkRemoteReply(noteID string, replyTo int) error {
+	if noteID == "" {
+		return nil
+	}
+	if replyTo != 0 {
+		return chirpError{400, "A chirp cannot reply to both reply_to and remote_reply_to"}
+	}
+	if _, ok := cfg.DB.RemoteNotes[noteID]; !ok {
+		return chirpError{400, "remote_reply_to must be a known remote note"}
+	}
+	return nil
+}
+
+func (cfg *ApiConfig) remoteNoteAuthor(noteID string) (db.RemoteActor, bool) {
+	note, ok := cfg.DB.RemoteNotes[noteID]
+	if noteID == "" || !ok {
+		return db.RemoteActor{}, false
+	}
+	return cfg.DB.GetRemoteActor(note.ActorID)
+}
+
+// deliver stores an activity for each inbox and queues it to be sent. Stored
+// deliveries survive restarts, when QueueDeliveries picks them up again.
+func (cfg *ApiConfig) deliver(userID int, inboxes []string, activity any) {
+	if len(inboxes) == 0 {
+		return
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		log.Printf("Error Marshalling Activity: %s", err)
+		return
+	}
+	deliveries, err := cfg.DB.AddDeliveries(userID, inboxes, body)
+	if err != nil {
+		log.Printf("Error Queueing Deliveries: %s", err)
+	}
+	for _, delivery := range deliveries {
+		cfg.Deliveries.Add(delivery.ID, delivery.Inbox, delivery.NextAttempt)
+	}
+}
+
+// QueueDeliveries queues every stored delivery. Run it once on startup.
+func (cfg *ApiConfig) QueueDeliveries() {
+	for _, delivery := range cfg.DB.ListDeliveries() {
+		cfg.Deliveries.Add(delivery.ID, delivery.Inbox, delivery.NextAttempt)
+	}
+}
+
+// SendDelivery posts a queued delivery. It is run by the delivery queue;
+// failures are retried after each of DeliveryRetries before being dropped.
+func (cfg *ApiConfig) SendDelivery(id int) {
+	delivery, err := cfg.DB.GetDelivery(id)
+	if err != nil {
+		return
+	}
+	signer, err := cfg.signer(delivery.UserID)
+	if err == nil {
+		err = cfg.Federation.Post(delivery.Inbox, delivery.Activity, signer)
+	}
+	permanent := errors.Is(err, activitypub.ErrRejected) || errors.Is(err, activitypub.ErrForbiddenURL)
+	if err != nil && !permanent && delivery.Attempts < len(cfg.DeliveryRetries) {
+		delivery, err = cfg.DB.RetryDelivery(id, time.Now().Add(cfg.DeliveryRetries[delivery.Attempts]))
+		if err != nil {
+			log.Printf("Error Rescheduling Delivery: %s", err)
+			return
+		}
+		cfg.Deliveries.Add(delivery.ID, delivery.Inbox, delivery.NextAttempt)
+		return
+	}
+	if err != nil {
+		log.Printf("Giving up delivery to %s: %s", delivery.Inbox, err)
+	}
+	err = cfg.DB.DeleteDelivery(id)
+	if err != nil {
+		log.Printf("Error Deleting Delivery: %s", err)
+	}
+}
+
+// DISCOVERY
+
+func (cfg *ApiConfig) GetWebFingerHandler(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	account, ok := strings.CutPrefix(resource, "acct:")
+	if !ok {
+		writeError(w, 400, "resource must be an acct: URI")
+		return
+	}
+	base, _ := url.Parse(cfg.BaseURL)
+	username, host, _ := strings.Cut(account, "@")
+	var user db.User
+	found := false
+	for _, candidate := range cfg.DB.Users {
+		if strings.EqualFold(actorUsername(candidate), username) {
+			user, found = candidate, true
+			break
+		}
+	}
+	if !found || host != base.Host {
+		writeError(w, 404, "No User by that account")
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	writeResponse(w, 200, activitypub.WebFinger{
+		Subject: resource,
+		Aliases: []string{cfg.actorURL(user.ID)},
+		Links: []activitypub.WebFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: cfg.actorURL(user.ID)},
+		},
+	})
+}
+
+func (cfg *ApiConfig) GetActorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	user, ok := cfg.DB.Users[id]
+	if !ok {
+		writeError(w, 404, "No User by that ID")
+		return
+	}
+	signer, err := cfg.signer(id)
+	if err != nil {
+		log.Printf("Error Loading Actor Key: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	publicKey, err := activitypub.PublicKeyPEM(signer.Key)
+	if err != nil {
+		log.Printf("Error Encoding Public Key: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	actorURL := cfg.actorURL(id)
+	actor := activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                actorURL,
+		Type:              "Person",
+		PreferredUsername: actorUsername(user),
+		Name:              displayName(user),
+		URL:               cfg.BaseURL + "/api/users/" + strconv.Itoa(id),
+		Inbox:             actorURL + "/inbox",
+		Outbox:            actorURL + "/outbox",
+		Followers:         actorURL + "/followers",
+		Endpoints:         &activitypub.Endpoints{SharedInbox: cfg.BaseURL + "/inbox"},
+		PublicKey: activitypub.PublicKey{
+			ID:           signer.KeyID,
+			Owner:        actorURL,
+			PublicKeyPem: publicKey,
+		},
+	}
+	if user.Bio != "" {
+		actor.Summary = activitypub.HTMLContent(user.Bio)
+	}
+	if user.AvatarURL != "" {
+		actor.Icon = &activitypub.Image{Type: "Image", URL: user.AvatarURL}
+	}
+	writeActivityJSON(w, actor)
+}
+
+func (cfg *ApiConfig) GetOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	if _, ok := cfg.DB.Users[id]; !ok {
+		writeError(w, 404, "No User by that ID")
+		return
+	}
+	chirps := cfg.filterChirps(chirpFilter{authors: []int{id}}, 0)
+	order := chirpOrder{name: "-created_at", key: chirpCreatedKey, desc: true}
+	order.sort(chirps)
+	items := []any{}
+	for _, chirp := range chirps[:min(len(chirps), outboxPageSize)] {
+		create := cfg.activity("Create", id, cfg.note(chirp))
+		create.Context = nil
+		create.ID = cfg.noteURL(chirp.ID) + "/activity"
+		items = append(items, create)
+	}
+	writeActivityJSON(w, activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           cfg.actorURL(id) + "/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   len(chirps),
+		OrderedItems: items,
+	})
+}
+
+func (cfg *ApiConfig) GetActorFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	if _, ok := cfg.DB.Users[id]; !ok {
+		writeError(w, 404, "No User by that ID")
+		return
+	}
+	writeActivityJSON(w, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURL(id) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: len(cfg.DB.GetFollowers(id)) + len(cfg.DB.GetRemoteFollowers(id)),
+	})
+}
+
+func (cfg *ApiConfig) GetNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	chirp, ok := cfg.DB.Chirps[id]
+	if !ok || chirp.Hidden {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	}
+	note := cfg.note(chirp)
+	note.Context = activitypub.Context
+	writeActivityJSON(w, note)
+}
+
+// INBOX
+
+var errLocalActor = errors.New("actor is local")
+
+// remoteActor returns a remote actor, fetching it if it is not cached or
+// refresh is set. URLs on this server are refused, so remote servers cannot
+// claim to be local users.
+func (cfg *ApiConfig) remoteActor(actorURL string, refresh bool) (db.RemoteActor, error) {
+	if strings.HasPrefix(actorURL, cfg.BaseURL+"/") {
+		return db.RemoteActor{}, errLocalActor
+	}
+	if actor, ok := cfg.DB.GetRemoteActor(actorURL); ok && !refresh {
+		return actor, nil
+	}
+	fetched, err := cfg.Federation.FetchActor(actorURL)
+	if err != nil {
+		return db.RemoteActor{}, err
+	}
+	actor := db.RemoteActor{
+		ID:        fetched.ID,
+		Username:  fetched.PreferredUsername,
+		Inbox:     fetched.Inbox,
+		KeyID:     fetched.PublicKey.ID,
+		PublicKey: fetched.PublicKey.PublicKeyPem,
+	}
+	if fetched.Endpoints != nil {
+		actor.SharedInbox = fetched.Endpoints.SharedInbox
+	}
+	return actor, cfg.DB.SaveRemoteActor(actor)
+}
+
+// verifyInbox checks the HTTP signature on an inbox delivery and returns the
+// signing actor. Cached keys that fail are refetched once, in case the actor
+// rotated its key.
+func (cfg *ApiConfig) verifyInbox(r *http.Request, body []byte) (db.RemoteActor, error) {
+	keyID, err := activitypub.SignatureKeyID(r)
+	if err != nil {
+		return db.RemoteActor{}, err
+	}
+	ownerURL := activitypub.KeyOwner(keyID)
+	actor, cached := cfg.DB.GetRemoteActorByKey(keyID)
+	if cached && actor.ID != ownerURL {
+		return db.RemoteActor{}, activitypub.ErrInvalidSignature
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		if !cached || attempt > 0 {
+			actor, err = cfg.remoteActor(ownerURL, true)
+			if err != nil {
+				return db.RemoteActor{}, err
+			}
+			if actor.KeyID != keyID {
+				return db.RemoteActor{}, activitypub.ErrInvalidSignature
+			}
+		}
+		key, err := activitypub.ParsePublicKey(actor.PublicKey)
+		if err == nil && activitypub.Verify(r, body, key) == nil {
+			return actor, nil
+		}
+		if !cached {
+			break
+		}
+	}
+	return db.RemoteActor{}, activitypub.ErrInvalidSignature
+}
+
+func (cfg *ApiConfig) PostInboxHandler(w http.ResponseWriter, r *http.Request) {
+	// REQUEST
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInboxBody))
+	if err != nil {
+		writeError(w, 413, "Activity too large")
+		return
+	}
+	activity := activitypub.Activity{}
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		writeError(w, 400, "Invalid activity")
+		return
+	}
+
+	// CHECKING SIGNATURE
+	actor, err := cfg.verifyInbox(r, body)
+	if err != nil || actor.ID != activity.Actor {
+		writeError(w, 401, "Invalid signature")
+		return
+	}
+
+	// HANDLE ACTIVITY
+	switch activity.Type {
+	case "Follow":
+		err = cfg.handleRemoteFollow(actor, activity)
+	case "Undo":
+		err = cfg.handleRemoteUndo(actor, activity)
+	case "Accept":
+		err = cfg.DB.AcceptRemoteFollowing(actor.ID, activity.ObjectID())
+		if errors.Is(err, db.ErrNotFollowingRemote) {
+			err = nil
+		}
+	case "Create", "Update":
+		err = cfg.handleRemoteNote(actor, activity)
+	case "Delete":
+		err = cfg.DB.DeleteRemoteNote(activity.ObjectID(), actor.ID)
+	}
+	if errors.Is(err, errUnknownObject) {
+		writeError(w, 404, "Unknown object")
+		return
+	} else if err != nil {
+		log.Printf("Error Handling %s Activity: %s", activity.Type, err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	w.WriteHeader(202)
+}
+
+var errUnknownObject = errors.New("activity refers to an unknown object")
+
+func (cfg *ApiConfig) handleRemoteFollow(actor db.RemoteActor, activity activitypub.Activity) error {
+	userID, ok := cfg.localActorID(activity.ObjectID())
+	if !ok {
+		return errUnknownObject
+	}
+	err := cfg.DB.AddRemoteFollower(userID, actor.ID)
+	if err != nil {
+		return err
+	}
+	accept := cfg.activity("Accept", userID, activity)
+	accept.To, accept.CC = []string{actor.ID}, nil
+	cfg.deliver(userID, []string{actor.Inbox}, accept)
+	return nil
+}
+
+func (cfg *ApiConfig) handleRemoteUndo(actor db.RemoteActor, activity activitypub.Activity) error {
+	if activity.ObjectType() != "Follow" {
+		return nil
+	}
+	follow := activitypub.Activity{}
+	err := json.Unmarshal(activity.Object, &follow)
+	if err != nil || follow.Actor != actor.ID {
+		return nil
+	}
+	userID, ok := cfg.localActorID(follow.ObjectID())
+	if !ok {
+		return errUnknownObject
+	}
+	return cfg.DB.RemoveRemoteFollower(userID, actor.ID)
+}
+
+// handleRemoteNote stores notes that reply to local chirps or come from
+// actors local users follow, after moderating them like local chirps.
+func (cfg *ApiConfig) handleRemoteNote(actor db.RemoteActor, activity activitypub.Activity) error {
+	if activity.ObjectType() != "Note" {
+		return nil
+	}
+	note := activitypub.Note{}
+	err := json.Unmarshal(activity.Object, &note)
+	if err != nil || note.AttributedTo != actor.ID || note.ID == "" {
+		return nil
+	}
+	remote := db.RemoteNote{
+		ID:        note.ID,
+		ActorID:   actor.ID,
+		URL:       note.URL,
+		InReplyTo: note.InReplyTo,
+	}
+	remote.Published, _ = time.Parse(time.RFC3339, note.Published)
+	if chirpID, ok := cfg.localNoteID(note.InReplyTo); ok {
+		chirp, exists := cfg.DB.Chirps[chirpID]
+		if !exists || chirp.Hidden {
+			return nil
+		}
+		remote.ReplyTo = chirpID
+	} else if !cfg.DB.IsRemoteFollowed(actor.ID) {
+		return nil
+	}
+	decision := cfg.Moderation.Moderate(activitypub.PlainText(note.Content), nil)
+	if decision.Rejected {
+		return nil
+	}
+	remote.Content = decision.Text
+	err = cfg.DB.SaveRemoteNote(remote)
+	if errors.Is(err, db.ErrNoteOwner) {
+		return nil
+	}
+	return err
+}
+
+// FOLLOWING REMOTE ACTORS
+
+func (cfg *ApiConfig) PostRemoteFollowHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	userID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		Account string `json:"account"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	actorURL, err := cfg.Federation.ResolveAccount(request.Account)
+	if err != nil {
+		writeError(w, 400, "Could not find that account")
+		return
+	}
+	if _, local := cfg.localActorID(actorURL); local {
+		writeError(w, 400, "Follow local users with POST /api/users/{id}/follow")
+		return
+	}
+	actor, err := cfg.remoteActor(actorURL, true)
+	if err != nil {
+		writeError(w, 502, "Could not fetch that account")
+		return
+	}
+
+	// FOLLOW ACTOR
+	follow := cfg.activity("Follow", userID, actor.ID)
+	follow.To, follow.CC = []string{actor.ID}, nil
+	remoteFollow, err := cfg.DB.AddRemoteFollowing(userID, db.RemoteFollow{ActorID: actor.ID, FollowID: follow.ID})
+	if err != nil {
+		log.Printf("Error Following Remote Actor: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.deliver(userID, []string{actor.Inbox}, follow)
+
+	// RESPONSE
+	writeResponse(w, 202, remoteFollow)
+}
+
+func (cfg *ApiConfig) DeleteRemoteFollowHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	userID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// UNFOLLOW ACTOR
+	actorURL := r.URL.Query().Get("actor")
+	follow, err := cfg.DB.RemoveRemoteFollowing(userID, actorURL)
+	if errors.Is(err, db.ErrNotFollowingRemote) {
+		writeError(w, 404, "You do not follow this account")
+		return
+	} else if err != nil {
+		log.Printf("Error Unfollowing Remote Actor: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	if actor, ok := cfg.DB.GetRemoteActor(actorURL); ok {
+		original := activitypub.Activity{
+			ID:     follow.FollowID,
+			Type:   "Follow",
+			Actor:  cfg.actorURL(userID),
+			Object: json.RawMessage(strconv.Quote(actorURL)),
+		}
+		undo := cfg.activity("Undo", userID, original)
+		undo.To, undo.CC = []string{actor.ID}, nil
+		cfg.deliver(userID, []string{actor.Inbox}, undo)
+	}
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+func (cfg *ApiConfig) GetRemoteFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	writeResponse(w, 200, cfg.DB.GetRemoteFollowing(userID))
+}
+
+// GetRemoteNotesHandler lists notes from the remote actors the user follows.
+func (cfg *ApiConfig) GetRemoteNotesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	writeResponse(w, 200, cfg.DB.ListRemoteNotes(userID, 0))
+}
+
+func (cfg *ApiConfig) GetRemoteRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	chirp, ok := cfg.DB.Chirps[id]
+	if !ok || !cfg.canView(chirp, viewerID) {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	}
+	writeResponse(w, 200, cfg.DB.ListRemoteNotes(0, id))
+}
@@ -0,0 +1,206 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func (cfg *ApiConfig) PostAppealHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		ActionID int    `json:"action_id"`
+		Message  string `json:"message"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	if request.Message == "" || len(request.Message) > maxReasonLength {
+		writeError(w, 400, "message must be between 1 and "+strconv.Itoa(maxReasonLength)+" characters")
+		return
+	}
+
+	// ADD APPEAL
+	appeal, err := cfg.DB.AddAppeal(id, request.ActionID, request.Message)
+	switch {
+	case errors.Is(err, db.ErrActionNotFound), errors.Is(err, db.ErrNotAppealable):
+		writeError(w, 404, "No moderation action against you by that ID")
+		return
+	case errors.Is(err, db.ErrAppealExists):
+		writeError(w, 409, "This action has already been appealed")
+		return
+	case err != nil:
+		log.Printf("Error Adding Appeal: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventAppeal, id, audit.Success, map[string]string{
+		"appeal_id": strconv.Itoa(appeal.ID),
+		"action_id": strconv.Itoa(appeal.ActionID),
+		"status":    appeal.Status,
+	})
+
+	// RESPONSE
+	writeResponse(w, 201, appeal)
+}
+
+func (cfg *ApiConfig) GetMyAppealsHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.DB.ListAppeals("", id))
+}
+
+func (cfg *ApiConfig) GetAppealsHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	_, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// QUERY PARAMETERS
+	status := r.URL.Query().Get("status")
+	if status != "" && status != db.AppealOpen && status != db.AppealUpheld && status != db.AppealOverturned {
+		writeError(w, 400, "status must be open, upheld or overturned")
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.DB.ListAppeals(status, 0))
+}
+
+// PostResolveAppealHandler decides an appeal. Appeals are reviewed by someone
+// other than the moderator who took the action, unless an admin steps in.
+// Overturning a hide or suspension reverses it; deleted chirps stay deleted.
+func (cfg *ApiConfig) PostResolveAppealHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	reviewerID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// REQUEST
+	appealID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	type requestStruct struct {
+		Decision string `json:"decision"`
+		Note     string `json:"note"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	if request.Decision != db.AppealUpheld && request.Decision != db.AppealOverturned {
+		writeError(w, 400, "decision must be upheld or overturned")
+		return
+	}
+	appeal, err := cfg.DB.GetAppeal(appealID)
+	if errors.Is(err, db.ErrAppealNotFound) {
+		writeError(w, 404, "No Appeal by that ID")
+		return
+	} else if err != nil {
+		log.Printf("Error Getting Appeal: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	action, err := cfg.DB.GetModerationAction(appeal.ActionID)
+	if err != nil {
+		log.Printf("Error Getting Moderation Action: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !cfg.isAdmin(reviewerID) {
+		if appeal.UserID == reviewerID {
+			writeError(w, 403, "You cannot review your own appeal")
+			return
+		}
+		if action.ModeratorID == reviewerID {
+			writeError(w, 403, "You cannot review an appeal of your own action")
+			return
+		}
+	}
+
+	// RESOLVE APPEAL
+	appeal, err = cfg.DB.ResolveAppeal(appealID, reviewerID, request.Decision, request.Note)
+	if errors.Is(err, db.ErrAppealResolved) {
+		writeError(w, 409, "Appeal is already resolved")
+		return
+	} else if err != nil {
+		log.Printf("Error Resolving Appeal: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventAppeal, reviewerID, audit.Success, map[string]string{
+		"appeal_id": strconv.Itoa(appeal.ID),
+		"action_id": strconv.Itoa(appeal.ActionID),
+		"status":    appeal.Status,
+	})
+
+	// REVERSE ACTION
+	if appeal.Status == db.AppealOverturned {
+		err = cfg.reverseAction(r, action, reviewerID, appeal.ID)
+		if err != nil {
+			log.Printf("Error Reversing Moderation Action: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, appeal)
+}
+
+func (cfg *ApiConfig) reverseAction(r *http.Request, action db.ModerationAction, reviewerID, appealID int) error {
+	reversal := db.ModerationAction{
+		ModeratorID: reviewerID,
+		UserID:      action.UserID,
+		ChirpID:     action.ChirpID,
+		ReportID:    action.ReportID,
+		Reason:      "Appeal " + strconv.Itoa(appealID) + " overturned action " + strconv.Itoa(action.ID),
+	}
+	var err error
+	switch action.Type {
+	case db.ActionHideChirp:
+		reversal.Type = db.ActionUnhideChirp
+		var chirp db.Chirp
+		chirp, err = cfg.DB.SetChirpHidden(action.ChirpID, false)
+		if errors.Is(err, db.ErrInvalidChirpID) {
+			return nil
+		} else if err == nil {
+			cfg.federateChirp(chirp, "Create")
+		}
+	case db.ActionSuspendUser:
+		reversal.Type = db.ActionUnsuspendUser
+		err = cfg.DB.LiftSuspension(action.UserID)
+		if errors.Is(err, db.ErrNotSuspended) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = cfg.recordAction(r, reversal)
+	return err
+}
@@ -0,0 +1,135 @@
+package hdl
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
+)
+
+const (
+	eventLogin          = "login"
+	eventTokenRefresh   = "token_refresh"
+	eventTokenRevoke    = "token_revoke"
+	eventAccountUpdate  = "account_update"
+	eventSubscription   = "subscription_change"
+	eventPasskeyAdded   = "passkey_registered"
+	eventOAuthConsent   = "oauth_consent"
+	eventOAuthToken     = "oauth_token"
+	eventOAuthRevoke    = "oauth_revoke"
+	eventModeration     = "moderation_action"
+	eventReport         = "report_change"
+	eventAppeal         = "appeal_change"
+	defaultAuditResults = 100
+)
+
+// recordEvent writes an audit event. Failing to audit is logged but never
+// fails the request that triggered it.
+func (cfg *ApiConfig) recordEvent(r *http.Request, eventType string, actorID int, outcome string, details map[string]string) {
+	if cfg.Audit == nil {
+		return
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	err = cfg.Audit.Record(audit.Event{
+		Type:      eventType,
+		ActorID:   actorID,
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+		Outcome:   outcome,
+		Details:   details,
+	})
+	if err != nil {
+		log.Printf("Error Recording Audit Event: %s", err)
+	}
+}
+
+func (cfg *ApiConfig) isAdmin(userID int) bool {
+	return slices.Contains(cfg.Admins, userID)
+}
+
+func (cfg *ApiConfig) GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	if !cfg.isAdmin(id) {
+		writeError(w, 403, "Admin access required")
+		return
+	}
+
+	// QUERY PARAMETERS
+	filter, ok := parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		filter.ActorID, err = strconv.Atoi(userID)
+		if err != nil {
+			writeError(w, 400, "Invalid ID")
+			return
+		}
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.Audit.Query(filter))
+}
+
+func (cfg *ApiConfig) GetUserActivityHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// QUERY PARAMETERS
+	filter, ok := parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.ActorID = id
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.Audit.Query(filter))
+}
+
+func parseAuditFilter(w http.ResponseWriter, r *http.Request) (audit.Filter, bool) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Type:    query.Get("type"),
+		Outcome: query.Get("outcome"),
+		Limit:   defaultAuditResults,
+	}
+	var err error
+	if since := query.Get("since"); since != "" {
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeError(w, 400, "since must be an RFC 3339 timestamp")
+			return audit.Filter{}, false
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		filter.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			writeError(w, 400, "until must be an RFC 3339 timestamp")
+			return audit.Filter{}, false
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 {
+			writeError(w, 400, "limit must be a positive integer")
+			return audit.Filter{}, false
+		}
+	}
+	return filter, true
+}
@@ -4,8 +4,12 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
 	"github.com/LoreviQ/PrivateWebServer/internal/auth"
 	"github.com/LoreviQ/PrivateWebServer/internal/db"
 )
@@ -24,9 +28,12 @@ func (cfg *ApiConfig) PostLoginHandler(w http.ResponseWriter, r *http.Request) {
 	// AUTHENTICATE USER
 	user, err := cfg.DB.AuthenticateUser(request.Email, []byte(request.Password))
 	if errors.Is(err, db.ErrInvalidEmail) {
+		cfg.recordEvent(r, eventLogin, 0, audit.Failure, map[string]string{"method": "password", "email": request.Email, "reason": "unknown email"})
 		writeError(w, 404, "No user with this email")
 		return
 	} else if errors.Is(err, db.ErrIncorrectPassword) {
+		target, _ := cfg.DB.GetUserByEmail(request.Email)
+		cfg.recordEvent(r, eventLogin, target.ID, audit.Failure, map[string]string{"method": "password", "email": request.Email, "reason": "incorrect password"})
 		writeError(w, 401, "Incorrect Password")
 		return
 	} else if err != nil {
@@ -35,6 +42,11 @@ func (cfg *ApiConfig) PostLoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cfg.recordEvent(r, eventLogin, user.ID, audit.Success, map[string]string{"method": "password"})
+	cfg.writeLoginResponse(w, user)
+}
+
+func (cfg *ApiConfig) writeLoginResponse(w http.ResponseWriter, user db.User) {
 	// CREATE JWT TOKENS
 	accessToken, err := auth.IssueAccessToken(user.ID, cfg.JWT_Secret)
 	if err != nil {
@@ -70,6 +82,7 @@ func (cfg *ApiConfig) PostRefreshHandler(w http.ResponseWriter, r *http.Request)
 	// CHECKING AUTHENTICATION
 	id, err := auth.AuthenticateRefreshToken(r, cfg.JWT_Secret, cfg.DB)
 	if err != nil {
+		cfg.recordEvent(r, eventTokenRefresh, 0, audit.Failure, map[string]string{"reason": err.Error()})
 		writeError(w, 401, "Inavlid Token. Please log in again")
 		return
 	}
@@ -83,6 +96,7 @@ func (cfg *ApiConfig) PostRefreshHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// RESPONSE
+	cfg.recordEvent(r, eventTokenRefresh, id, audit.Success, nil)
 	type responseStruct struct {
 		Token string `json:"token"`
 	}
@@ -92,11 +106,73 @@ func (cfg *ApiConfig) PostRefreshHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (cfg *ApiConfig) PostRevokeHandler(w http.ResponseWriter, r *http.Request) {
-	err := cfg.DB.RevokeToken(strings.Split(r.Header.Get("Authorization"), " ")[1])
+	token, err := auth.BearerToken(r)
+	if err != nil {
+		writeError(w, 401, "Inavlid Token. Please log in again")
+		return
+	}
+	actorID := 0
+	claims, err := auth.ParseToken(token, "chirpy-refresh", cfg.JWT_Secret)
+	if err == nil {
+		actorID, _ = strconv.Atoi(claims.Subject)
+	}
+	err = cfg.DB.RevokeToken(token)
 	if err != nil {
+		cfg.recordEvent(r, eventTokenRevoke, actorID, audit.Failure, map[string]string{"reason": err.Error()})
 		log.Printf("Error Revoking Token: %s", err)
 		w.WriteHeader(500)
 	} else {
+		cfg.recordEvent(r, eventTokenRevoke, actorID, audit.Success, nil)
 		w.WriteHeader(200)
 	}
 }
+
+const (
+	scopeChirpsWrite = "chirps:write"
+	// scopeAccount covers credential and account management. It is implied by
+	// first-party tokens and never granted to third-party clients.
+	scopeAccount = "account"
+)
+
+var grantableScopes = []string{scopeChirpsWrite}
+
+var errInsufficientScope = errors.New("token lacks required scope")
+var errSuspended = errors.New("account suspended")
+
+func (cfg *ApiConfig) authenticate(r *http.Request, scope string) (int, error) {
+	tokenString, err := auth.BearerToken(r)
+	if err != nil {
+		return 0, err
+	}
+	claims, err := auth.ParseToken(tokenString, "chirpy-access", cfg.JWT_Secret)
+	if err != nil {
+		return 0, err
+	}
+	if claims.ID != "" && cfg.DB.IsAccessTokenRevoked(claims.ID) {
+		return 0, errors.New("token has been revoked")
+	}
+	if scope != "" && claims.ClientID != "" && !slices.Contains(strings.Fields(claims.Scope), scope) {
+		return 0, errInsufficientScope
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, err
+	}
+	// Suspended users can still sign in, read and appeal, but not chirp.
+	if _, suspended := cfg.DB.GetSuspension(id, time.Now()); suspended && scope == scopeChirpsWrite {
+		return 0, errSuspended
+	}
+	return id, nil
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInsufficientScope) {
+		writeError(w, 403, "Token does not grant this permission")
+		return
+	}
+	if errors.Is(err, errSuspended) {
+		writeError(w, 403, "Your account is suspended")
+		return
+	}
+	writeError(w, 401, "Inavlid Token. Please log in again")
+}
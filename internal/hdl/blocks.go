@@ -0,0 +1,146 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// shows reports whether a chirp belongs in what a viewer reads: they may see
+// it, and its author has not blocked them and is not blocked or muted by them.
+func (cfg *ApiConfig) shows(chirp db.Chirp, viewerID int) bool {
+	if !cfg.canView(chirp, viewerID) {
+		return false
+	}
+	if viewerID == 0 || chirp.UserID == viewerID {
+		return true
+	}
+	return !cfg.DB.IsBlocked(viewerID, chirp.UserID) && !cfg.DB.IsMuted(viewerID, chirp.UserID)
+}
+
+func (cfg *ApiConfig) PostBlockHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	blockerID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// BLOCK USER
+	blockedID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	err = cfg.DB.BlockUser(blockerID, blockedID)
+	if !handleRestrictionError(w, err, "block") {
+		return
+	}
+	cfg.Timeline.Unfollow(blockerID, blockedID)
+	cfg.Timeline.Unfollow(blockedID, blockerID)
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+func (cfg *ApiConfig) DeleteBlockHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.removeRestriction(w, r, cfg.DB.UnblockUser, "block")
+}
+
+func (cfg *ApiConfig) PostMuteHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	muterID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// MUTE USER
+	mutedID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	err = cfg.DB.MuteUser(muterID, mutedID)
+	if !handleRestrictionError(w, err, "mute") {
+		return
+	}
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+func (cfg *ApiConfig) DeleteMuteHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.removeRestriction(w, r, cfg.DB.UnmuteUser, "mute")
+}
+
+func (cfg *ApiConfig) removeRestriction(w http.ResponseWriter, r *http.Request, remove func(fromID, toID int) error, kind string) {
+	// CHECKING AUTHENTICATION
+	userID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REMOVE RESTRICTION
+	otherID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	err = remove(userID, otherID)
+	if !handleRestrictionError(w, err, kind) {
+		return
+	}
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+func handleRestrictionError(w http.ResponseWriter, err error, kind string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, db.ErrSelfBlock):
+		writeError(w, 400, "You cannot "+kind+" yourself")
+	case errors.Is(err, db.ErrInvalidUserID):
+		writeError(w, 404, "No User by that ID")
+	case errors.Is(err, db.ErrNotBlocked), errors.Is(err, db.ErrNotMuted):
+		writeError(w, 404, "You have not "+kind+"d this user")
+	default:
+		log.Printf("Error updating %s list: %s", kind, err)
+		w.WriteHeader(500)
+	}
+	return false
+}
+
+func (cfg *ApiConfig) GetBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.writeRestrictionList(w, r, cfg.DB.GetBlocked)
+}
+
+func (cfg *ApiConfig) GetMutesHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.writeRestrictionList(w, r, cfg.DB.GetMuted)
+}
+
+func (cfg *ApiConfig) writeRestrictionList(w http.ResponseWriter, r *http.Request, list func(int) []db.Restriction) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// RESPONSE
+	type responseStruct struct {
+		Count int              `json:"count"`
+		Users []db.Restriction `json:"users"`
+	}
+	users := list(id)
+	writeResponse(w, 200, responseStruct{
+		Count: len(users),
+		Users: users,
+	})
+}
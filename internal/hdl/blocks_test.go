@@ -0,0 +1,206 @@
+package hdl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// responseIDs returns the IDs of the objects in a JSON array response.
+func responseIDs(t *testing.T, res *httptest.ResponseRecorder) []int {
+	t.Helper()
+	if res.Code != 200 {
+		t.Fatalf("status %d, want 200", res.Code)
+	}
+	items := []struct {
+		ID int `json:"id"`
+	}{}
+	err := json.Unmarshal(res.Body.Bytes(), &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+type blockTest struct {
+	cfg         *ApiConfig
+	viewerToken string
+	// The viewer's own chirp, and chirps by a followed user, a user the
+	// viewer blocked, a user who blocked the viewer and a muted user.
+	own, friend, blocked, blocker, muted   db.Chirp
+	blockedID, blockerID, mutedID          int
+	blockedToken, blockerToken, mutedToken string
+}
+
+func newBlockTest(t *testing.T) *blockTest {
+	t.Helper()
+	cfg := newTestConfig(t)
+	viewerID, viewerToken := newTestUser(t, cfg, "viewer@example.com")
+	friendID, friendToken := newTestUser(t, cfg, "friend@example.com")
+	blockedID, blockedToken := newTestUser(t, cfg, "blocked@example.com")
+	blockerID, blockerToken := newTestUser(t, cfg, "blocker@example.com")
+	mutedID, mutedToken := newTestUser(t, cfg, "muted@example.com")
+	relate := func(pattern string, handler func(http.ResponseWriter, *http.Request), token string, userID int) {
+		path := strings.Replace(pattern, "{id}", strconv.Itoa(userID), 1)
+		res := serve(t, pattern, handler, "POST", strings.TrimPrefix(path, "POST "), token, nil)
+		if res.Code != 200 {
+			t.Fatalf("%s: status %d, want 200", path, res.Code)
+		}
+	}
+	relate("POST /api/users/{id}/follow", cfg.PostFollowHandler, viewerToken, friendID)
+	relate("POST /api/users/{id}/follow", cfg.PostFollowHandler, viewerToken, mutedID)
+	relate("POST /api/users/{id}/mute", cfg.PostMuteHandler, viewerToken, mutedID)
+	relate("POST /api/users/{id}/block", cfg.PostBlockHandler, viewerToken, blockedID)
+	relate("POST /api/users/{id}/block", cfg.PostBlockHandler, blockerToken, viewerID)
+	return &blockTest{
+		cfg:          cfg,
+		viewerToken:  viewerToken,
+		own:          postChirp(t, cfg, viewerToken, map[string]any{"body": "news from the viewer"}),
+		friend:       postChirp(t, cfg, friendToken, map[string]any{"body": "news from a friend"}),
+		blocked:      postChirp(t, cfg, blockedToken, map[string]any{"body": "news from a blocked user"}),
+		blocker:      postChirp(t, cfg, blockerToken, map[string]any{"body": "news from a blocker"}),
+		muted:        postChirp(t, cfg, mutedToken, map[string]any{"body": "news from a muted user"}),
+		blockedID:    blockedID,
+		blockerID:    blockerID,
+		mutedID:      mutedID,
+		blockedToken: blockedToken,
+		blockerToken: blockerToken,
+		mutedToken:   mutedToken,
+	}
+}
+
+func TestBlocksAndMutesHideChirpsFromListings(t *testing.T) {
+	b := newBlockTest(t)
+	everyone := []int{b.own.ID, b.friend.ID, b.blocked.ID, b.blocker.ID, b.muted.ID}
+	visible := []int{b.own.ID, b.friend.ID}
+
+	if got, _ := listChirpIDs(t, b.cfg, b.viewerToken, ""); !slices.Equal(got, visible) {
+		t.Errorf("GET /api/chirps as the viewer = %v, want %v", got, visible)
+	}
+	if got, _ := listChirpIDs(t, b.cfg, "", ""); !slices.Equal(got, everyone) {
+		t.Errorf("GET /api/chirps signed out = %v, want %v", got, everyone)
+	}
+	authors := strconv.Itoa(b.blockedID) + "," + strconv.Itoa(b.mutedID)
+	if got, _ := listChirpIDs(t, b.cfg, b.viewerToken, "author_id="+authors); len(got) != 0 {
+		t.Errorf("filtering by hidden authors = %v, want none", got)
+	}
+
+	res := serve(t, "GET /api/search", b.cfg.GetSearchHandler, "GET", "/api/search?q=news", b.viewerToken, nil)
+	if got := responseIDs(t, res); !slices.Equal(got, visible) {
+		t.Errorf("search as the viewer = %v, want %v", got, visible)
+	}
+	res = serve(t, "GET /api/search", b.cfg.GetSearchHandler, "GET", "/api/search?q=news", "", nil)
+	if got := responseIDs(t, res); !slices.Equal(got, everyone) {
+		t.Errorf("search signed out = %v, want %v", got, everyone)
+	}
+
+	res = serve(t, "GET /api/timeline", b.cfg.GetTimelineHandler, "GET", "/api/timeline", b.viewerToken, nil)
+	if got := responseIDs(t, res); !slices.Equal(got, visible) {
+		t.Errorf("timeline = %v, want %v without the muted user it follows", got, visible)
+	}
+}
+
+// Blocks hide chirps and profiles even when asked for directly. Mutes only
+// keep users out of listings, so a muted user's chirp can still be opened.
+func TestBlocksHideChirpsAndProfiles(t *testing.T) {
+	b := newBlockTest(t)
+	tests := []struct {
+		name   string
+		chirp  db.Chirp
+		userID int
+		want   int
+	}{
+		{"blocked", b.blocked, b.blockedID, 404},
+		{"blocker", b.blocker, b.blockerID, 404},
+		{"muted", b.muted, b.mutedID, 200},
+	}
+	for _, tt := range tests {
+		chirpPath := "/api/chirps/" + strconv.Itoa(tt.chirp.ID)
+		requests := []struct {
+			pattern string
+			handler func(t *testing.T, token string) int
+		}{
+			{"GET /api/chirps/{id}", func(t *testing.T, token string) int {
+				return serve(t, "GET /api/chirps/{id}", b.cfg.GetChirpByIDHandler, "GET", chirpPath, token, nil).Code
+			}},
+			{"GET /api/chirps/{id}/history", func(t *testing.T, token string) int {
+				return serve(t, "GET /api/chirps/{id}/history", b.cfg.GetChirpHistoryHandler, "GET", chirpPath+"/history", token, nil).Code
+			}},
+			{"GET /api/chirps/{id}/thread", func(t *testing.T, token string) int {
+				return serve(t, "GET /api/chirps/{id}/thread", b.cfg.GetChirpThreadHandler, "GET", chirpPath+"/thread", token, nil).Code
+			}},
+			{"GET /api/users/{id}", func(t *testing.T, token string) int {
+				return serve(t, "GET /api/users/{id}", b.cfg.GetProfileHandler, "GET", "/api/users/"+strconv.Itoa(tt.userID), token, nil).Code
+			}},
+		}
+		for _, request := range requests {
+			if code := request.handler(t, b.viewerToken); code != tt.want {
+				t.Errorf("%s, %s user: status %d, want %d", request.pattern, tt.name, code, tt.want)
+			}
+			if code := request.handler(t, ""); code != 200 {
+				t.Errorf("%s, %s user, signed out: status %d, want 200", request.pattern, tt.name, code)
+			}
+		}
+	}
+}
+
+// Replies by hidden users stay in threads as placeholders, so the replies
+// below them are not lost.
+func TestThreadsRedactBlockedAndMutedReplies(t *testing.T) {
+	b := newBlockTest(t)
+	blockedReply := postChirp(t, b.cfg, b.blockedToken, map[string]any{"body": "reply", "reply_to": b.friend.ID})
+	answer := postChirp(t, b.cfg, b.mutedToken, map[string]any{"body": "answer", "reply_to": blockedReply.ID})
+
+	path := "/api/chirps/" + strconv.Itoa(answer.ID) + "/thread"
+	if res := serve(t, "GET /api/chirps/{id}/thread", b.cfg.GetChirpThreadHandler, "GET", path, b.viewerToken, nil); res.Code != 200 {
+		t.Errorf("a muted user's reply: status %d, want 200", res.Code)
+	}
+	path = "/api/chirps/" + strconv.Itoa(b.friend.ID) + "/thread"
+	res := serve(t, "GET /api/chirps/{id}/thread", b.cfg.GetChirpThreadHandler, "GET", path, b.viewerToken, nil)
+	thread := db.ThreadNode{}
+	err := json.Unmarshal(res.Body.Bytes(), &thread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thread.Chirp == nil || len(thread.Replies) != 1 {
+		t.Fatalf("thread = %+v, want the friend's chirp with one reply", thread)
+	}
+	reply := thread.Replies[0]
+	if reply.ID != blockedReply.ID || !reply.Hidden || reply.Chirp != nil || len(reply.Replies) != 1 {
+		t.Fatalf("blocked user's reply = %+v, want a hidden placeholder with one reply", reply)
+	}
+	if nested := reply.Replies[0]; nested.ID != answer.ID || !nested.Hidden || nested.Chirp != nil {
+		t.Errorf("muted user's reply = %+v, want a hidden placeholder", nested)
+	}
+}
+
+func TestBlocksAndMutesSuppressNotifications(t *testing.T) {
+	b := newBlockTest(t)
+	viewerID := b.own.UserID
+	likePath := "/api/chirps/" + strconv.Itoa(b.own.ID) + "/like"
+	res := serve(t, "POST /api/chirps/{chirpID}/like", b.cfg.PostLikeHandler, "POST", likePath, b.blockerToken, nil)
+	if res.Code != 403 {
+		t.Errorf("liking a blocked user's chirp: status %d, want 403", res.Code)
+	}
+	res = serve(t, "POST /api/chirps/{chirpID}/like", b.cfg.PostLikeHandler, "POST", likePath, b.mutedToken, nil)
+	if res.Code != 200 {
+		t.Errorf("liking as a muted user: status %d, want 200", res.Code)
+	}
+	postChirp(t, b.cfg, b.mutedToken, map[string]any{"body": "reply", "reply_to": b.own.ID})
+	b.cfg.notify(viewerID, db.NotifyFollow, b.blockedID, 0)
+
+	if notifications, _ := b.cfg.DB.GetNotifications(viewerID, 0, 10, false); len(notifications) != 0 {
+		t.Errorf("notifications = %+v, want none from blocked or muted users", notifications)
+	}
+}
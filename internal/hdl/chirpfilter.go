@@ -0,0 +1,142 @@
+package hdl
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
+)
+
+type chirpFilter struct {
+	authors       []int
+	createdAfter  time.Time
+	createdBefore time.Time
+	hashtags      []string
+	hasMedia      *bool
+	chirpyRed     *bool
+}
+
+// parseChirpFilter reads the filtering query parameters. Multi-valued
+// parameters may be repeated or comma separated.
+func parseChirpFilter(w http.ResponseWriter, r *http.Request) (chirpFilter, bool) {
+	query := r.URL.Query()
+	filter := chirpFilter{}
+
+	for _, value := range listParam(query["author_id"]) {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, 400, "author_id must be a list of user IDs")
+			return chirpFilter{}, false
+		}
+		filter.authors = append(filter.authors, id)
+	}
+	for _, value := range listParam(query["hashtag"]) {
+		tag := strings.ToLower(strings.TrimPrefix(value, "#"))
+		if !entities.ValidTag(tag) {
+			writeError(w, 400, "hashtag must contain a letter and only letters, digits and underscores")
+			return chirpFilter{}, false
+		}
+		filter.hashtags = append(filter.hashtags, tag)
+	}
+
+	var err error
+	for param, target := range map[string]*time.Time{
+		"created_after":  &filter.createdAfter,
+		"created_before": &filter.createdBefore,
+	} {
+		if value := query.Get(param); value != "" {
+			*target, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeError(w, 400, param+" must be an RFC 3339 timestamp")
+				return chirpFilter{}, false
+			}
+		}
+	}
+	for param, target := range map[string]**bool{
+		"has_media":  &filter.hasMedia,
+		"chirpy_red": &filter.chirpyRed,
+	} {
+		if value := query.Get(param); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				writeError(w, 400, param+" must be true or false")
+				return chirpFilter{}, false
+			}
+			*target = &parsed
+		}
+	}
+	return filter, true
+}
+
+func (f chirpFilter) matches(chirp db.Chirp, users map[int]db.User) bool {
+	if len(f.authors) > 0 && !slices.Contains(f.authors, chirp.UserID) {
+		return false
+	}
+	if !f.createdAfter.IsZero() && !chirp.CreatedAt.After(f.createdAfter) {
+		return false
+	}
+	if !f.createdBefore.IsZero() && !chirp.CreatedAt.Before(f.createdBefore) {
+		return false
+	}
+	if f.hasMedia != nil && (len(chirp.Media) > 0) != *f.hasMedia {
+		return false
+	}
+	if f.chirpyRed != nil && users[chirp.UserID].ChirpyRed != *f.chirpyRed {
+		return false
+	}
+	if len(f.hashtags) > 0 {
+		tags := chirp.Entities.Tags()
+		for _, tag := range f.hashtags {
+			if !slices.Contains(tags, tag) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func parseChirpOrder(w http.ResponseWriter, r *http.Request) (chirpOrder, bool) {
+	query := r.URL.Query()
+	order := chirpOrder{}
+	switch query.Get("sort_by") {
+	case "", "id":
+		order.name, order.key = "id", chirpIDKey
+	case "created_at":
+		order.name, order.key = "created_at", chirpCreatedKey
+	case "engagement":
+		order.name, order.key = "engagement", chirpEngagementKey
+	default:
+		writeError(w, 400, "sort_by must be one of id, created_at, engagement")
+		return chirpOrder{}, false
+	}
+	switch query.Get("sort") {
+	case "", "asc":
+	case "desc":
+		order.desc = true
+		order.name = "-" + order.name
+	default:
+		writeError(w, 400, "sort must be asc or desc")
+		return chirpOrder{}, false
+	}
+	return order, true
+}
+
+func chirpCreatedKey(chirp db.Chirp) int64 {
+	return chirp.CreatedAt.UnixNano()
+}
+
+func listParam(values []string) []string {
+	list := []string{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,91 @@
+package hdl
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listChirpIDs requests GET /api/chirps with query and returns the IDs of the
+// chirps listed, or the status code if the request failed.
+func listChirpIDs(t *testing.T, cfg *ApiConfig, token, query string) ([]int, int) {
+	t.Helper()
+	res := serve(t, "GET /api/chirps", cfg.GetChirpHandler, "GET", "/api/chirps?"+query, token, nil)
+	if res.Code != 200 {
+		return nil, res.Code
+	}
+	chirps := []chirpView{}
+	err := json.Unmarshal(res.Body.Bytes(), &chirps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	return ids, 200
+}
+
+func TestChirpFilters(t *testing.T) {
+	cfg := newTestConfig(t)
+	aliceID, aliceToken := newTestUser(t, cfg, "alice@example.com")
+	bobID, bobToken := newTestUser(t, cfg, "bob@example.com")
+	upgradeTestUser(t, cfg, aliceID)
+	first := postChirp(t, cfg, aliceToken, map[string]any{"body": "hello #golang", "media": []string{"https://example.com/a.png"}})
+	time.Sleep(time.Millisecond)
+	second := postChirp(t, cfg, bobToken, map[string]any{"body": "hi #golang #chirpy"})
+	time.Sleep(time.Millisecond)
+	third := postChirp(t, cfg, aliceToken, map[string]any{"body": "plain"})
+	_, err := cfg.DB.LikeChirp(second.ID, aliceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice, bob := strconv.Itoa(aliceID), strconv.Itoa(bobID)
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{1, 2, 3}},
+		{"author_id=" + alice, []int{1, 3}},
+		{"author_id=" + alice + "," + bob, []int{1, 2, 3}},
+		{"author_id=" + alice + "&author_id=" + bob, []int{1, 2, 3}},
+		{"hashtag=golang", []int{1, 2}},
+		{"hashtag=" + url.QueryEscape("#GoLang") + ",chirpy", []int{2}},
+		{"has_media=true", []int{1}},
+		{"has_media=false", []int{2, 3}},
+		{"chirpy_red=true", []int{1, 3}},
+		{"chirpy_red=false&hashtag=golang", []int{2}},
+		{"created_after=" + url.QueryEscape(first.CreatedAt.Format(time.RFC3339Nano)), []int{2, 3}},
+		{"created_before=" + url.QueryEscape(third.CreatedAt.Format(time.RFC3339Nano)), []int{1, 2}},
+		{"sort=desc", []int{3, 2, 1}},
+		{"sort_by=created_at&sort=desc", []int{3, 2, 1}},
+		{"sort_by=engagement&sort=desc", []int{2, 3, 1}},
+	}
+	for _, tt := range tests {
+		if got, code := listChirpIDs(t, cfg, "", tt.query); code != 200 || !slices.Equal(got, tt.want) {
+			t.Errorf("GET /api/chirps?%s = %v (status %d), want %v", tt.query, got, code, tt.want)
+		}
+	}
+}
+
+func TestChirpFiltersRejectBadParameters(t *testing.T) {
+	cfg := newTestConfig(t)
+	for _, query := range []string{
+		"author_id=alice",
+		"hashtag=" + url.QueryEscape("#!!"),
+		"created_after=yesterday",
+		"created_before=2026-01-01",
+		"has_media=maybe",
+		"chirpy_red=2",
+		"sort_by=likes",
+		"sort=up",
+	} {
+		if _, code := listChirpIDs(t, cfg, "", query); code != 400 {
+			t.Errorf("GET /api/chirps?%s: status %d, want 400", query, code)
+		}
+	}
+}
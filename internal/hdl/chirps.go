@@ -1,106 +1,151 @@
 package hdl
 
 import (
+	"errors"
 	"log"
 	"net/http"
-	"slices"
-	"sort"
 	"strconv"
-	"strings"
 
-	"github.com/LoreviQ/PrivateWebServer/internal/auth"
 	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
+	"github.com/LoreviQ/PrivateWebServer/internal/moderation"
 )
 
+const maxChirpMedia = 4
+
 func (cfg *ApiConfig) GetChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listChirps(w, r, "")
+}
+
+// listChirps responds with the chirps matching the request's filters, limited
+// to those using hashtag if it is set.
+func (cfg *ApiConfig) listChirps(w http.ResponseWriter, r *http.Request, hashtag string) {
+	// CHECKING AUTHENTICATION
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
 	// QUERY PARAMETERS
-	authorID := r.URL.Query().Get("author_id")
-	sortParam := r.URL.Query().Get("sort")
+	filter, ok := parseChirpFilter(w, r)
+	if !ok {
+		return
+	}
+	if hashtag != "" {
+		filter.hashtags = append(filter.hashtags, hashtag)
+	}
+	order, ok := parseChirpOrder(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, paginated, ok := parsePageParams(w, r, order)
+	if !ok {
+		return
+	}
 
 	// GET SLICE OF CHIRPS
-	chirps := make([]db.Chirp, 0, len(cfg.DB.Chirps))
-	if authorID == "" {
-		for _, chirp := range cfg.DB.Chirps {
-			chirps = append(chirps, chirp)
-		}
-	} else {
-		id, err := strconv.Atoi(authorID)
-		if err != nil {
-			writeError(w, 400, "Invalid ID")
-			return
-		}
-		for _, chirp := range cfg.DB.Chirps {
-			if chirp.UserID == id {
-				chirps = append(chirps, chirp)
-			}
-		}
-	}
+	chirps := cfg.filterChirps(filter, viewerID)
 
 	//SORT CHIRPS
-	sort.Slice(chirps, func(i, j int) bool {
-		return chirps[i].ID < chirps[j].ID
-	})
-	if sortParam == "desc" {
-		slices.Reverse(chirps)
-	}
+	order.sort(chirps)
 
 	//RESPONSE
-	writeResponse(w, 200, chirps)
+	if !paginated {
+		writeResponse(w, 200, cfg.chirpViews(chirps, viewerID))
+		return
+	}
+	cfg.writeChirpPage(w, r, order.paginate(chirps, cursor, limit), viewerID)
+}
+
+// filterChirps returns the chirps matching filter that the viewer should see,
+// in no particular order.
+func (cfg *ApiConfig) filterChirps(filter chirpFilter, viewerID int) []db.Chirp {
+	chirps := make([]db.Chirp, 0, len(cfg.DB.Chirps))
+	for _, chirp := range cfg.DB.Chirps {
+		if filter.matches(chirp, cfg.DB.Users) && cfg.shows(chirp, viewerID) {
+			chirps = append(chirps, chirp)
+		}
+	}
+	return chirps
 }
 
 func (cfg *ApiConfig) GetChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		writeError(w, 400, "Invalid ID")
 		return
 	}
 	chirp, ok := cfg.DB.Chirps[id]
-	if !ok {
+	if !ok || !cfg.canView(chirp, viewerID) || cfg.DB.IsBlocked(viewerID, chirp.UserID) {
 		writeError(w, 404, "No Chirp by that ID")
 		return
 	}
-	writeResponse(w, 200, chirp)
+	writeResponse(w, 200, cfg.chirpView(chirp, viewerID))
 }
 
 func (cfg *ApiConfig) PostChirpHandler(w http.ResponseWriter, r *http.Request) {
 	// CHECKING AUTHENTICATION
-	id, err := auth.AuthenticateAccessToken(r, cfg.JWT_Secret)
+	id, err := cfg.authenticate(r, scopeChirpsWrite)
 	if err != nil {
-		writeError(w, 401, "Inavlid Token. Please log in again")
+		writeAuthError(w, err)
 		return
 	}
 
 	// REQUEST
 	type requestStruct struct {
-		Body string `json:"body"`
+		Body        string   `json:"body"`
+		Media       []string `json:"media"`
+		ReplyTo     int      `json:"reply_to"`
+		QuoteOf     int      `json:"quote_of"`
+		RemoteReply string   `json:"remote_reply_to"`
 	}
 	request, err := decodeRequest(w, r, requestStruct{})
 	if err != nil {
 		return
 	}
+	err = cfg.checkChirp(id, request.Body, request.Media, request.ReplyTo, request.QuoteOf)
+	if err == nil {
+		err = cfg.checkRemoteReply(request.RemoteReply, request.ReplyTo)
+	}
+	if err != nil {
+		writeChirpError(w, err)
+		return
+	}
 
-	// POST CHIRP
-	w.Header().Set("Content-Type", "application/json")
-	if len(request.Body) <= 140 {
-		chirp, err := cfg.validateChirp(request.Body, id)
-		if err != nil {
-			log.Printf("Error validating chirp: %s", err)
-			w.WriteHeader(500)
-			return
-		}
+	// MODERATION
+	decision, ok := cfg.moderateChirp(w, request.Body, request.Media)
+	if !ok {
+		return
+	}
 
-		// RESPONSE
-		writeResponse(w, 201, chirp)
-	} else {
-		writeError(w, 400, "Chirp is too long")
+	// POST CHIRP
+	chirp, err := cfg.publishChirp(db.Chirp{
+		UserID:      id,
+		Media:       request.Media,
+		ReplyTo:     request.ReplyTo,
+		QuoteOf:     request.QuoteOf,
+		RemoteReply: request.RemoteReply,
+	}, decision)
+	if err != nil {
+		writeChirpError(w, err)
+		return
 	}
+
+	// RESPONSE
+	writeResponse(w, 201, cfg.chirpView(chirp, 0))
 }
 
 func (cfg *ApiConfig) DeleteChirpHandler(w http.ResponseWriter, r *http.Request) {
 	// AUTHENTICATION
-	userID, err := auth.AuthenticateAccessToken(r, cfg.JWT_Secret)
+	userID, err := cfg.authenticate(r, scopeChirpsWrite)
 	if err != nil {
-		writeError(w, 401, "Inavlid Token. Please log in again")
+		writeAuthError(w, err)
 		return
 	}
 
@@ -127,20 +172,206 @@ func (cfg *ApiConfig) DeleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(500)
 		return
 	}
+	cfg.federateDelete(chirp)
 
 	// RESPONSE
 	w.WriteHeader(200)
 }
 
-func (cfg *ApiConfig) validateChirp(body string, userID int) (db.Chirp, error) {
-	badWords := []string{"kerfuffle", "sharbert", "fornax"}
+func (cfg *ApiConfig) PatchChirpHandler(w http.ResponseWriter, r *http.Request) {
+	// AUTHENTICATION
+	userID, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// AUTHORIZATION
+	chirpID, err := strconv.Atoi(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	chirp, ok := cfg.DB.Chirps[chirpID]
+	if !ok {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	}
+	if chirp.UserID != userID {
+		writeError(w, 403, "Not Authorised to edit this chirp")
+		return
+	}
+	if !cfg.entitlements(userID).CanEditChirps {
+		writeError(w, 403, "Your plan does not include editing chirps")
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		Body  *string   `json:"body"`
+		Media *[]string `json:"media"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	body := chirp.Body
+	if request.Body != nil {
+		body = *request.Body
+	}
+	media := chirp.Media
+	if request.Media != nil {
+		media = *request.Media
+	}
+	err = cfg.checkChirp(userID, body, media, 0, 0)
+	if err != nil {
+		writeChirpError(w, err)
+		return
+	}
+
+	// MODERATION
+	decision, ok := cfg.moderateChirp(w, body, media)
+	if !ok {
+		return
+	}
+
+	// UPDATE CHIRP
+	chirp, err = cfg.DB.UpdateChirp(chirpID, db.Chirp{
+		Body:     decision.Text,
+		Media:    media,
+		Entities: cfg.DB.ResolveMentions(entities.Parse(decision.Text)),
+	})
+	if err != nil {
+		log.Printf("Error updating chirp: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.flagChirp(chirp, decision)
+	cfg.notifyChirp(chirp)
+	cfg.federateChirp(chirp, "Update")
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.chirpView(chirp, userID))
+}
+
+func (cfg *ApiConfig) GetChirpHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	history, err := cfg.DB.GetChirpHistory(id)
+	if err != nil || !cfg.canView(cfg.DB.Chirps[id], viewerID) || cfg.DB.IsBlocked(viewerID, cfg.DB.Chirps[id].UserID) {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	}
+	writeResponse(w, 200, history)
+}
 
-	words := strings.Split(body, " ")
-	for i, word := range words {
-		if slices.Contains(badWords, strings.ToLower(word)) {
-			words[i] = "****"
+func (cfg *ApiConfig) GetChirpThreadHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	if chirp, ok := cfg.DB.Chirps[id]; ok && (!cfg.canView(chirp, viewerID) || cfg.DB.IsBlocked(viewerID, chirp.UserID)) {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	}
+	thread, err := cfg.DB.GetThread(id)
+	if err != nil {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	}
+	cfg.redactThread(&thread, viewerID)
+	writeResponse(w, 200, thread)
+}
+
+// redactThread replaces the chirps a viewer should not see with placeholders,
+// so replies to them stay in place.
+func (cfg *ApiConfig) redactThread(node *db.ThreadNode, viewerID int) {
+	if node.Chirp != nil && !cfg.shows(*node.Chirp, viewerID) {
+		node.Hidden = true
+		node.Chirp = nil
+	}
+	for i := range node.Replies {
+		cfg.redactThread(&node.Replies[i], viewerID)
+	}
+}
+
+// chirpError is a reason a chirp cannot be posted and the status to report it
+// with.
+type chirpError struct {
+	status  int
+	message string
+}
+
+func (e chirpError) Error() string {
+	return e.message
+}
+
+var errChirpTargets = chirpError{400, "reply_to and quote_of must be existing chirps"}
+
+// checkChirp validates a chirp the user wants to post against their plan and
+// the chirps it replies to or quotes.
+func (cfg *ApiConfig) checkChirp(userID int, body string, media []string, replyTo, quoteOf int) error {
+	if len(body) > cfg.entitlements(userID).MaxChirpLength {
+		return chirpError{400, "Chirp is too long"}
+	}
+	if len(media) > maxChirpMedia {
+		return chirpError{400, "A chirp can have at most " + strconv.Itoa(maxChirpMedia) + " media attachments"}
+	}
+	for _, item := range media {
+		if !isWebURL(item) {
+			return chirpError{400, "Media must be http or https URLs"}
+		}
+	}
+	for _, target := range []int{replyTo, quoteOf} {
+		chirp, ok := cfg.DB.Chirps[target]
+		if target != 0 && (!ok || !cfg.canView(chirp, userID)) {
+			return errChirpTargets
+		}
+		if ok && cfg.DB.IsBlocked(userID, chirp.UserID) {
+			return chirpError{403, "You cannot interact with this user"}
 		}
 	}
-	chirp, err := cfg.DB.CreateChirp(strings.Join(words, " "), userID)
-	return chirp, err
+	return nil
+}
+
+// publishChirp stores a chirp with the moderated text of decision, then
+// flags it for review, notifies the users it involves and sends it to remote
+// followers.
+func (cfg *ApiConfig) publishChirp(draft db.Chirp, decision moderation.Decision) (db.Chirp, error) {
+	draft.Body = decision.Text
+	draft.Entities = cfg.DB.ResolveMentions(entities.Parse(decision.Text))
+	chirp, err := cfg.DB.CreateChirp(draft)
+	if errors.Is(err, db.ErrInvalidChirpID) {
+		return db.Chirp{}, errChirpTargets
+	} else if err != nil {
+		return db.Chirp{}, err
+	}
+	cfg.flagChirp(chirp, decision)
+	cfg.notifyChirp(chirp)
+	cfg.federateChirp(chirp, "Create")
+	return chirp, nil
+}
+
+func writeChirpError(w http.ResponseWriter, err error) {
+	var chirpErr chirpError
+	if errors.As(err, &chirpErr) {
+		writeError(w, chirpErr.status, chirpErr.message)
+		return
+	}
+	log.Printf("Error creating chirp: %s", err)
+	w.WriteHeader(500)
 }
@@ -0,0 +1,46 @@
+package hdl
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func TestEditingChirpsRequiresPlan(t *testing.T) {
+	cfg := newTestConfig(t)
+	authorID, authorToken := newTestUser(t, cfg, "author@example.com")
+	otherID, otherToken := newTestUser(t, cfg, "other@example.com")
+	upgradeTestUser(t, cfg, otherID)
+	chirp := postChirp(t, cfg, authorToken, map[string]any{"body": "first"})
+	target := "/api/chirps/" + strconv.Itoa(chirp.ID)
+	edit := func(token, body string) int {
+		res := serve(t, "PATCH /api/chirps/{chirpID}", cfg.PatchChirpHandler, "PATCH", target, token, map[string]string{"body": body})
+		return res.Code
+	}
+
+	if code := edit(authorToken, "second"); code != 403 {
+		t.Errorf("editing on the free plan: status %d, want 403", code)
+	}
+	if code := edit(otherToken, "second"); code != 403 {
+		t.Errorf("editing someone else's chirp: status %d, want 403", code)
+	}
+	if cfg.DB.Chirps[chirp.ID].Body != "first" {
+		t.Fatal("a refused edit changed the chirp")
+	}
+
+	upgradeTestUser(t, cfg, authorID)
+	if code := edit(authorToken, "second"); code != 200 {
+		t.Fatalf("editing on Chirpy Red: status %d, want 200", code)
+	}
+	res := serve(t, "GET /api/chirps/{id}/history", cfg.GetChirpHistoryHandler, "GET", target+"/history", "", nil)
+	history := []db.ChirpRevision{}
+	err := json.Unmarshal(res.Body.Bytes(), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != 200 || len(history) != 1 || history[0].Body != "first" {
+		t.Errorf("history: status %d, %+v, want the first version", res.Code, history)
+	}
+}
@@ -0,0 +1,113 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// chirpView is a chirp as returned by the API: quote chirps embed the chirp
+// they quote, and authenticated requests learn whether they liked or reposted
+// it.
+type chirpView struct {
+	db.Chirp
+	Quoted       *db.Chirp `json:"quoted,omitempty"`
+	LikedByMe    *bool     `json:"liked_by_me,omitempty"`
+	RepostedByMe *bool     `json:"reposted_by_me,omitempty"`
+}
+
+func (cfg *ApiConfig) chirpView(chirp db.Chirp, viewerID int) chirpView {
+	view := chirpView{Chirp: chirp}
+	// Stream handlers build views while chirps are being written, so the
+	// quoted chirp is read under the lock.
+	if quoted, err := cfg.DB.GetChirp(chirp.QuoteOf); err == nil && cfg.shows(quoted, viewerID) {
+		view.Quoted = &quoted
+	}
+	if viewerID != 0 {
+		liked := cfg.DB.HasLiked(chirp.ID, viewerID)
+		reposted := cfg.DB.HasReposted(chirp.ID, viewerID)
+		view.LikedByMe = &liked
+		view.RepostedByMe = &reposted
+	}
+	return view
+}
+
+func (cfg *ApiConfig) chirpViews(chirps []db.Chirp, viewerID int) []chirpView {
+	views := make([]chirpView, 0, len(chirps))
+	for _, chirp := range chirps {
+		views = append(views, cfg.chirpView(chirp, viewerID))
+	}
+	return views
+}
+
+// viewer returns the authenticated user for endpoints that also serve
+// anonymous requests, or 0 when no token was sent.
+func (cfg *ApiConfig) viewer(r *http.Request) (int, error) {
+	if r.Header.Get("Authorization") == "" {
+		return 0, nil
+	}
+	return cfg.authenticate(r, "")
+}
+
+func chirpEngagementKey(chirp db.Chirp) int64 {
+	return int64(chirp.LikeCount + chirp.RepostCount + chirp.QuoteCount + chirp.ReplyCount)
+}
+
+func (cfg *ApiConfig) PostLikeHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.handleEngagement(w, r, cfg.DB.LikeChirp, db.NotifyLike)
+}
+
+func (cfg *ApiConfig) DeleteLikeHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.handleEngagement(w, r, cfg.DB.UnlikeChirp, "")
+}
+
+func (cfg *ApiConfig) PostRepostHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.handleEngagement(w, r, cfg.DB.RepostChirp, db.NotifyRepost)
+}
+
+func (cfg *ApiConfig) DeleteRepostHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.handleEngagement(w, r, cfg.DB.UndoRepost, "")
+}
+
+// handleEngagement applies a like or repost action to a chirp, notifying its
+// author with notificationType if set.
+func (cfg *ApiConfig) handleEngagement(w http.ResponseWriter, r *http.Request, action func(chirpID, userID int) (db.Chirp, error), notificationType string) {
+	// CHECKING AUTHENTICATION
+	userID, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// UPDATE CHIRP
+	chirpID, err := strconv.Atoi(r.PathValue("chirpID"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	if chirp, ok := cfg.DB.Chirps[chirpID]; ok && !cfg.canView(chirp, userID) {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	} else if ok && cfg.DB.IsBlocked(userID, chirp.UserID) {
+		writeError(w, 403, "You cannot interact with this user")
+		return
+	}
+	chirp, err := action(chirpID, userID)
+	if errors.Is(err, db.ErrInvalidChirpID) {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	} else if err != nil {
+		log.Printf("Error updating engagement: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	if notificationType != "" {
+		cfg.notify(chirp.UserID, notificationType, userID, chirp.ID)
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.chirpView(chirp, userID))
+}
@@ -0,0 +1,41 @@
+package hdl
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entitlements"
+)
+
+func (cfg *ApiConfig) userPlan(userID int) string {
+	subscription, err := cfg.DB.GetSubscription(userID)
+	if err != nil {
+		return db.PlanFree
+	}
+	return subscription.ActivePlan(time.Now())
+}
+
+func (cfg *ApiConfig) entitlements(userID int) entitlements.Limits {
+	return cfg.Plans.For(cfg.userPlan(userID))
+}
+
+func (cfg *ApiConfig) GetEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// RESPONSE
+	type responseStruct struct {
+		Plan string `json:"plan"`
+		entitlements.Limits
+	}
+	plan := cfg.userPlan(id)
+	writeResponse(w, 200, responseStruct{
+		Plan:   plan,
+		Limits: cfg.Plans.For(plan),
+	})
+}
@@ -0,0 +1,122 @@
+package hdl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/activitypub"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// newFederatedServer runs a test config behind an HTTP server with the
+// federation routes, talking to other test servers over loopback HTTP.
+func newFederatedServer(t *testing.T) *ApiConfig {
+	t.Helper()
+	cfg := newTestConfig(t)
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	cfg.BaseURL = server.URL
+	cfg.Federation = activitypub.NewClient(true)
+	cfg.Deliveries = activitypub.NewQueue(4, cfg.SendDelivery)
+
+	mux.HandleFunc("GET /.well-known/webfinger", cfg.GetWebFingerHandler)
+	mux.HandleFunc("GET /users/{id}", cfg.GetActorHandler)
+	mux.HandleFunc("POST /users/{id}/inbox", cfg.PostInboxHandler)
+	mux.HandleFunc("POST /inbox", cfg.PostInboxHandler)
+	mux.HandleFunc("GET /notes/{id}", cfg.GetNoteHandler)
+	return cfg
+}
+
+// eventually polls until check passes, since deliveries are asynchronous.
+func eventually(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFederationBetweenServers(t *testing.T) {
+	home := newFederatedServer(t)
+	away := newFederatedServer(t)
+	aliceID, aliceToken := newTestUser(t, home, "alice@example.com")
+	bobID, bobToken := newTestUser(t, away, "bob@example.com")
+	_, err := home.DB.UpdateProfile(aliceID, db.Profile{Handle: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob follows Alice by her account name, and her server accepts.
+	account := "alice@" + strings.TrimPrefix(home.BaseURL, "http://")
+	res := serve(t, "POST /api/federation/follows", away.PostRemoteFollowHandler, "POST", "/api/federation/follows", bobToken, map[string]string{"account": account})
+	if res.Code != 202 {
+		t.Fatalf("following: status %d: %s", res.Code, res.Body)
+	}
+	aliceActor := home.actorURL(aliceID)
+	eventually(t, "the follow to be accepted", func() bool {
+		following := away.DB.GetRemoteFollowing(bobID)
+		return len(following) == 1 && following[0].ActorID == aliceActor && following[0].Accepted
+	})
+	if followers := home.DB.GetRemoteFollowers(aliceID); len(followers) != 1 || followers[0].ID != away.actorURL(bobID) {
+		t.Errorf("Alice's remote followers = %+v", followers)
+	}
+
+	// Alice's chirps reach Bob's server.
+	chirp := postChirp(t, home, aliceToken, map[string]any{"body": "Hello from home"})
+	noteID := home.noteURL(chirp.ID)
+	eventually(t, "the note to arrive", func() bool {
+		notes := away.DB.ListRemoteNotes(bobID, 0)
+		return len(notes) == 1 && notes[0].ID == noteID && strings.Contains(notes[0].Content, "Hello from home")
+	})
+
+	// Bob's reply reaches Alice's server and hangs off her chirp.
+	postChirp(t, away, bobToken, map[string]any{"body": "Hello back", "remote_reply_to": noteID})
+	eventually(t, "the reply to arrive", func() bool {
+		replies := home.DB.ListRemoteNotes(0, chirp.ID)
+		return len(replies) == 1 && replies[0].ActorID == away.actorURL(bobID)
+	})
+
+	// Deleting the chirp deletes the copy.
+	target := "/api/chirps/" + strconv.Itoa(chirp.ID)
+	res = serve(t, "DELETE /api/chirps/{chirpID}", home.DeleteChirpHandler, "DELETE", target, aliceToken, nil)
+	if res.Code != 200 {
+		t.Fatalf("deleting chirp: status %d: %s", res.Code, res.Body)
+	}
+	eventually(t, "the note to be deleted", func() bool {
+		return len(away.DB.ListRemoteNotes(bobID, 0)) == 0
+	})
+	eventually(t, "deliveries to drain", func() bool {
+		return len(home.DB.ListDeliveries()) == 0 && len(away.DB.ListDeliveries()) == 0
+	})
+}
+
+func TestInboxRejectsUnsignedActivities(t *testing.T) {
+	cfg := newFederatedServer(t)
+	activity := `{"id":"https://evil.example/1","type":"Follow","actor":"https://evil.example/users/mallory","object":"` + cfg.actorURL(1) + `"}`
+	resp, err := http.Post(cfg.BaseURL+"/inbox", activitypub.ContentType, strings.NewReader(activity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 401 {
+		t.Errorf("unsigned activity: status %d, want 401", resp.StatusCode)
+	}
+}
+
+// A server must not be able to pass off an actor URL on this server as one of
+// its own.
+func TestRemoteActorRefusesLocalURLs(t *testing.T) {
+	cfg := newFederatedServer(t)
+	_, err := cfg.remoteActor(cfg.actorURL(1), true)
+	if err != errLocalActor {
+		t.Errorf("err = %v, want errLocalActor", err)
+	}
+}
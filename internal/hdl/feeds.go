@@ -0,0 +1,125 @@
+package hdl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
+	"github.com/LoreviQ/PrivateWebServer/internal/feed"
+)
+
+const (
+	feedSize        = 50
+	feedTitleLength = 80
+)
+
+// feedFormats maps feed file names to their renderers and content types.
+var feedFormats = map[string]struct {
+	render      func(feed.Feed) ([]byte, error)
+	contentType string
+}{
+	"feed.atom": {feed.Atom, feed.AtomType},
+	"feed.rss":  {feed.RSS, feed.RSSType},
+	"feed.json": {feed.JSON, feed.JSONType},
+}
+
+func (cfg *ApiConfig) GetUserFeedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	user, ok := cfg.DB.Users[id]
+	if !ok {
+		writeError(w, 404, "No User by that ID")
+		return
+	}
+	name := displayName(user)
+	cfg.writeFeed(w, r, chirpFilter{authors: []int{id}}, feed.Feed{
+		Title:       name + " on Chirpy",
+		Description: "Chirps by " + name,
+		HomeURL:     cfg.BaseURL + "/api/chirps?author_id=" + strconv.Itoa(id),
+	})
+}
+
+func (cfg *ApiConfig) GetHashtagFeedHandler(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if !entities.ValidTag(tag) {
+		writeError(w, 400, "Invalid hashtag")
+		return
+	}
+	cfg.writeFeed(w, r, chirpFilter{hashtags: []string{tag}}, feed.Feed{
+		Title:       "#" + tag + " on Chirpy",
+		Description: "Chirps tagged #" + tag,
+		HomeURL:     cfg.BaseURL + "/api/hashtags/" + url.PathEscape(tag),
+	})
+}
+
+// writeFeed renders the newest chirps matching filter in the format named by
+// the request path, as anonymous readers would see them in GetChirpHandler.
+// Conditional requests are answered with the feed's ETag alone: the newest
+// update time can move backwards when chirps are deleted or hidden, so it
+// makes an unreliable Last-Modified.
+func (cfg *ApiConfig) writeFeed(w http.ResponseWriter, r *http.Request, filter chirpFilter, doc feed.Feed) {
+	format, ok := feedFormats[r.PathValue("feed")]
+	if !ok {
+		writeError(w, 404, "Feeds are feed.atom, feed.rss or feed.json")
+		return
+	}
+
+	// GET CHIRPS
+	chirps := cfg.filterChirps(filter, 0)
+	order := chirpOrder{name: "-created_at", key: chirpCreatedKey, desc: true}
+	order.sort(chirps)
+	chirps = chirps[:min(len(chirps), feedSize)]
+
+	// BUILD FEED
+	doc.FeedURL = cfg.BaseURL + r.URL.Path
+	for _, chirp := range chirps {
+		if chirp.UpdatedAt.After(doc.Updated) {
+			doc.Updated = chirp.UpdatedAt
+		}
+		doc.Items = append(doc.Items, cfg.feedItem(chirp))
+	}
+	data, err := format.render(doc)
+	if err != nil {
+		log.Printf("Error Rendering Feed: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	sum := sha256.Sum256(data)
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+func (cfg *ApiConfig) feedItem(chirp db.Chirp) feed.Item {
+	chirpURL := cfg.BaseURL + "/api/chirps/" + strconv.Itoa(chirp.ID)
+	title := chirp.Body
+	if utf8.RuneCountInString(title) > feedTitleLength {
+		title = string([]rune(title)[:feedTitleLength-1]) + "…"
+	}
+	return feed.Item{
+		ID:        chirpURL,
+		URL:       chirpURL,
+		Title:     title,
+		Content:   chirp.Body,
+		Author:    displayName(cfg.DB.Users[chirp.UserID]),
+		AuthorURL: cfg.BaseURL + "/users/" + strconv.Itoa(chirp.UserID) + "/feed.atom",
+		Tags:      chirp.Entities.Tags(),
+		Published: chirp.CreatedAt,
+		Updated:   chirp.UpdatedAt,
+	}
+}
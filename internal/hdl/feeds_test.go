@@ -0,0 +1,63 @@
+package hdl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// Deleting the newest chirp moves the feed's newest update time backwards, so
+// a reader holding an old Last-Modified would be told nothing had changed.
+// Revalidation must go through the ETag instead.
+func TestFeedRevalidatesByETag(t *testing.T) {
+	cfg := newTestConfig(t)
+	userID, _ := newTestUser(t, cfg, "a@example.com")
+	_, err := cfg.DB.CreateChirp(db.Chirp{UserID: userID, Body: "older"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newest, err := cfg.DB.CreateChirp(db.Chirp{UserID: userID, Body: "newer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "/users/" + strconv.Itoa(userID) + "/feed.atom"
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		req.SetPathValue("id", strconv.Itoa(userID))
+		req.SetPathValue("feed", "feed.atom")
+		res := httptest.NewRecorder()
+		cfg.GetUserFeedHandler(res, req)
+		return res
+	}
+
+	res := get(nil)
+	etag := res.Header().Get("ETag")
+	if res.Code != 200 || etag == "" {
+		t.Fatalf("status %d, ETag %q", res.Code, etag)
+	}
+	if res.Header().Get("Last-Modified") != "" {
+		t.Error("feed sent a Last-Modified header")
+	}
+	if res = get(map[string]string{"If-None-Match": etag}); res.Code != 304 {
+		t.Errorf("unchanged feed: status %d, want 304", res.Code)
+	}
+
+	err = cfg.DB.DeleteChirp(newest.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = get(map[string]string{
+		"If-None-Match":     etag,
+		"If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+	})
+	if res.Code != 200 {
+		t.Errorf("feed after a delete: status %d, want 200", res.Code)
+	}
+}
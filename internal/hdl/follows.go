@@ -0,0 +1,109 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func (cfg *ApiConfig) PostFollowHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	followerID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// FOLLOW USER
+	followedID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	if cfg.DB.IsBlocked(followerID, followedID) {
+		writeError(w, 403, "You cannot interact with this user")
+		return
+	}
+	err = cfg.DB.FollowUser(followerID, followedID)
+	if errors.Is(err, db.ErrSelfFollow) {
+		writeError(w, 400, "You cannot follow yourself")
+		return
+	} else if errors.Is(err, db.ErrInvalidUserID) {
+		writeError(w, 404, "No User by that ID")
+		return
+	} else if err != nil {
+		log.Printf("Error following user: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.Timeline.Follow(followerID, followedID)
+	cfg.notify(followedID, db.NotifyFollow, followerID, 0)
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+func (cfg *ApiConfig) DeleteFollowHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	followerID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// UNFOLLOW USER
+	followedID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	err = cfg.DB.UnfollowUser(followerID, followedID)
+	if errors.Is(err, db.ErrNotFollowing) {
+		writeError(w, 404, "You do not follow this user")
+		return
+	} else if err != nil {
+		log.Printf("Error unfollowing user: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.Timeline.Unfollow(followerID, followedID)
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+// GetFollowListHandler serves a user's followers and following lists. They
+// share one route so that it does not conflict with
+// GET /api/users/by-handle/{handle}.
+func (cfg *ApiConfig) GetFollowListHandler(w http.ResponseWriter, r *http.Request) {
+	followLists := map[string]func(int) []db.Follow{
+		"followers": cfg.DB.GetFollowers,
+		"following": cfg.DB.GetFollowing,
+	}
+	list, ok := followLists[r.PathValue("list")]
+	if !ok {
+		writeError(w, 404, "Lists are followers or following")
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	if _, ok := cfg.DB.Users[id]; !ok {
+		writeError(w, 404, "No User by that ID")
+		return
+	}
+	type responseStruct struct {
+		Count int         `json:"count"`
+		Users []db.Follow `json:"users"`
+	}
+	users := list(id)
+	writeResponse(w, 200, responseStruct{
+		Count: len(users),
+		Users: users,
+	})
+}
@@ -0,0 +1,37 @@
+package hdl
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
+)
+
+const defaultTrendingResults = 10
+
+func (cfg *ApiConfig) GetHashtagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if !entities.ValidTag(tag) {
+		writeError(w, 400, "Invalid hashtag")
+		return
+	}
+	cfg.listChirps(w, r, tag)
+}
+
+func (cfg *ApiConfig) GetTrendingHandler(w http.ResponseWriter, r *http.Request) {
+	// QUERY PARAMETERS
+	limit := defaultTrendingResults
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			writeError(w, 400, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+			return
+		}
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.Trending.Top(limit, time.Now()))
+}
@@ -6,16 +6,46 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/LoreviQ/PrivateWebServer/internal/activitypub"
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
 	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entitlements"
+	"github.com/LoreviQ/PrivateWebServer/internal/moderation"
+	"github.com/LoreviQ/PrivateWebServer/internal/oidc"
+	"github.com/LoreviQ/PrivateWebServer/internal/scheduler"
+	"github.com/LoreviQ/PrivateWebServer/internal/search"
+	"github.com/LoreviQ/PrivateWebServer/internal/stream"
+	"github.com/LoreviQ/PrivateWebServer/internal/timeline"
+	"github.com/LoreviQ/PrivateWebServer/internal/trending"
+	"github.com/LoreviQ/PrivateWebServer/internal/webauthn"
 )
 
 type ApiConfig struct {
-	Port           string
-	DB_Directory   string
-	JWT_Secret     []byte
-	FileserverHits int
-	DB             db.Database
+	Port            string
+	BaseURL         string
+	DB_Directory    string
+	Audit_Directory string
+	JWT_Secret      []byte
+	Polka_Secret    []byte
+	FileserverHits  int
+	Admins          []int
+	Subscriptions   db.SubscriptionPolicy
+	Plans           entitlements.Plans
+	DB              db.Database
+	Audit           *audit.Log
+	OIDC            *oidc.Provider
+	WebAuthn        *webauthn.WebAuthn
+	Search          *search.Index
+	Timeline        *timeline.Timeline
+	Trending        *trending.Tracker
+	Moderation      *moderation.Pipeline
+	Scheduler       *scheduler.Scheduler
+	Stream          *stream.Hub
+	Federation      *activitypub.Client
+	Deliveries      *activitypub.Queue
+	DeliveryRetries []time.Duration
 }
 
 func (cfg *ApiConfig) HandleFlags() {
@@ -24,7 +54,9 @@ func (cfg *ApiConfig) HandleFlags() {
 	if *dbg {
 		log.Printf("Entering debug mode\n")
 		cfg.DB_Directory = "./database/debugDB.json"
+		cfg.Audit_Directory = "./database/debugAudit.log"
 		os.Remove(cfg.DB_Directory)
+		os.Remove(cfg.Audit_Directory)
 	}
 }
 
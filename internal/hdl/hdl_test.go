@@ -0,0 +1,114 @@
+package hdl
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entitlements"
+	"github.com/LoreviQ/PrivateWebServer/internal/moderation"
+	"github.com/LoreviQ/PrivateWebServer/internal/search"
+	"github.com/LoreviQ/PrivateWebServer/internal/stream"
+	"github.com/LoreviQ/PrivateWebServer/internal/timeline"
+	"github.com/LoreviQ/PrivateWebServer/internal/trending"
+)
+
+// newTestConfig builds a config backed by a fresh database in a temporary
+// directory, using the repository's plan and moderation configuration.
+func newTestConfig(t *testing.T) *ApiConfig {
+	t.Helper()
+	cfg := &ApiConfig{
+		Port:       "8080",
+		BaseURL:    "http://localhost:8080",
+		JWT_Secret: []byte("test-secret"),
+		DB:         db.InitialiseDatabase(filepath.Join(t.TempDir(), "database.json")),
+		Search:     search.New(),
+		Timeline:   timeline.New(timeline.Config{FanoutLimit: 1000, InboxSize: 800}),
+		Trending:   trending.New(24 * time.Hour),
+		Stream:     stream.New(stream.Config{Backlog: 100, BufferSize: 16}),
+	}
+	cfg.DB.ObserveChirps(cfg.Search)
+	cfg.DB.ObserveChirps(cfg.Timeline)
+	cfg.DB.ObserveChirps(cfg.Trending)
+	cfg.DB.ObserveChirps(cfg.Stream)
+	var err error
+	cfg.Plans, err = entitlements.Load("../../config/plans.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Moderation, err = moderation.Load("../../config/moderation.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// newTestUser adds a user and returns their ID and an access token.
+func newTestUser(t *testing.T, cfg *ApiConfig, email string) (int, string) {
+	t.Helper()
+	user, err := cfg.DB.AddUser(email, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.IssueAccessToken(user.ID, cfg.JWT_Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.ID, token
+}
+
+// upgradeTestUser gives a user an active Chirpy Red subscription.
+func upgradeTestUser(t *testing.T, cfg *ApiConfig, userID int) {
+	t.Helper()
+	policy := db.SubscriptionPolicy{Period: db.DefaultSubscriptionPeriod}
+	_, _, err := cfg.DB.ApplySubscriptionEvent(db.SubscriptionEvent{Type: "user.upgraded", UserID: userID}, policy, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// postChirp posts a chirp through the API and returns it.
+func postChirp(t *testing.T, cfg *ApiConfig, token string, body map[string]any) db.Chirp {
+	t.Helper()
+	res := serve(t, "POST /api/chirps", cfg.PostChirpHandler, "POST", "/api/chirps", token, body)
+	if res.Code != 201 {
+		t.Fatalf("posting chirp: status %d: %s", res.Code, res.Body)
+	}
+	chirp := db.Chirp{}
+	err := json.Unmarshal(res.Body.Bytes(), &chirp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chirp
+}
+
+// serve runs a handler with a JSON body and bearer token, returning the
+// recorded response. Path values are taken from pattern.
+func serve(t *testing.T, pattern string, handler http.HandlerFunc, method, target, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	return recorder
+}
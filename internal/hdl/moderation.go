@@ -0,0 +1,366 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/moderation"
+)
+
+// moderateChirp runs a chirp through the moderation pipeline. It responds with
+// a 400 and reports false if the chirp is rejected.
+func (cfg *ApiConfig) moderateChirp(w http.ResponseWriter, body string, media []string) (moderation.Decision, bool) {
+	decision := cfg.Moderation.Moderate(body, media)
+	if decision.Rejected {
+		writeError(w, 400, "Chirp rejected: "+strings.Join(decision.Reasons, "; "))
+		return moderation.Decision{}, false
+	}
+	return decision, true
+}
+
+// flagChirp queues a stored chirp for review if moderation flagged it.
+func (cfg *ApiConfig) flagChirp(chirp db.Chirp, decision moderation.Decision) {
+	if !decision.Flagged {
+		return
+	}
+	_, err := cfg.DB.AddReport(db.Report{
+		ChirpID: chirp.ID,
+		UserID:  chirp.UserID,
+		Reason:  "Flagged by moderation: " + strings.Join(decision.Reasons, "; "),
+	})
+	if err != nil {
+		log.Printf("Error Flagging Chirp: %s", err)
+	}
+}
+
+// isModerator reports whether a user may work the moderation queue. Admins
+// are always moderators.
+func (cfg *ApiConfig) isModerator(userID int) bool {
+	return cfg.isAdmin(userID) || cfg.DB.Users[userID].Moderator
+}
+
+// canView reports whether a viewer, 0 if anonymous, may see a chirp. Hidden
+// chirps remain visible to their author and to moderators.
+func (cfg *ApiConfig) canView(chirp db.Chirp, viewerID int) bool {
+	return !chirp.Hidden || (viewerID != 0 && (chirp.UserID == viewerID || cfg.isModerator(viewerID)))
+}
+
+func (cfg *ApiConfig) authenticateModerator(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return 0, false
+	}
+	if !cfg.isModerator(id) {
+		writeError(w, 403, "Moderator access required")
+		return 0, false
+	}
+	return id, true
+}
+
+// recordAction stores a moderation action and audits it.
+func (cfg *ApiConfig) recordAction(r *http.Request, action db.ModerationAction) (db.ModerationAction, error) {
+	action, err := cfg.DB.RecordModerationAction(action)
+	if err != nil {
+		return db.ModerationAction{}, err
+	}
+	details := map[string]string{
+		"action_id": strconv.Itoa(action.ID),
+		"type":      action.Type,
+		"user_id":   strconv.Itoa(action.UserID),
+		"reason":    action.Reason,
+	}
+	if action.ChirpID != 0 {
+		details["chirp_id"] = strconv.Itoa(action.ChirpID)
+	}
+	cfg.recordEvent(r, eventModeration, action.ModeratorID, audit.Success, details)
+	return action, nil
+}
+
+type moderationRequest struct {
+	Reason   string `json:"reason"`
+	ReportID int    `json:"report_id"`
+	Days     int    `json:"days"`
+}
+
+func decodeModerationRequest(w http.ResponseWriter, r *http.Request) (moderationRequest, bool) {
+	request, err := decodeRequest(w, r, moderationRequest{})
+	if err != nil {
+		return moderationRequest{}, false
+	}
+	if request.Reason == "" || len(request.Reason) > maxReasonLength {
+		writeError(w, 400, "reason must be between 1 and "+strconv.Itoa(maxReasonLength)+" characters")
+		return moderationRequest{}, false
+	}
+	if request.Days < 0 {
+		writeError(w, 400, "days must not be negative")
+		return moderationRequest{}, false
+	}
+	return request, true
+}
+
+func (cfg *ApiConfig) PostHideChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.handleChirpAction(w, r, db.ActionHideChirp)
+}
+
+func (cfg *ApiConfig) PostUnhideChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.handleChirpAction(w, r, db.ActionUnhideChirp)
+}
+
+func (cfg *ApiConfig) DeleteModeratedChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.handleChirpAction(w, r, db.ActionDeleteChirp)
+}
+
+func (cfg *ApiConfig) handleChirpAction(w http.ResponseWriter, r *http.Request, actionType string) {
+	// CHECKING AUTHENTICATION
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// REQUEST
+	chirpID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	chirp, ok := cfg.DB.Chirps[chirpID]
+	if !ok {
+		writeError(w, 404, "No Chirp by that ID")
+		return
+	}
+	request, ok := decodeModerationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// UPDATE CHIRP
+	switch actionType {
+	case db.ActionHideChirp:
+		_, err = cfg.DB.SetChirpHidden(chirpID, true)
+	case db.ActionUnhideChirp:
+		chirp, err = cfg.DB.SetChirpHidden(chirpID, false)
+	case db.ActionDeleteChirp:
+		err = cfg.DB.DeleteChirp(chirpID)
+	}
+	if err != nil {
+		log.Printf("Error Moderating Chirp: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	if actionType == db.ActionUnhideChirp {
+		cfg.federateChirp(chirp, "Create")
+	} else {
+		cfg.federateDelete(chirp)
+	}
+
+	// RECORD ACTION
+	action, err := cfg.recordAction(r, db.ModerationAction{
+		ModeratorID: moderatorID,
+		Type:        actionType,
+		UserID:      chirp.UserID,
+		ChirpID:     chirpID,
+		ReportID:    request.ReportID,
+		Reason:      request.Reason,
+	})
+	if err != nil {
+		log.Printf("Error Recording Moderation Action: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, action)
+}
+
+func (cfg *ApiConfig) PostSuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// REQUEST
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	request, ok := decodeModerationRequest(w, r)
+	if !ok {
+		return
+	}
+	if cfg.isModerator(userID) {
+		writeError(w, 403, "Moderators cannot be suspended")
+		return
+	}
+
+	// SUSPEND USER
+	suspension := db.Suspension{
+		UserID:      userID,
+		ModeratorID: moderatorID,
+		Reason:      request.Reason,
+	}
+	if request.Days > 0 {
+		suspension.Until = time.Now().UTC().AddDate(0, 0, request.Days)
+	}
+	err = cfg.DB.SuspendUser(suspension)
+	if errors.Is(err, db.ErrInvalidUserID) {
+		writeError(w, 404, "No User by that ID")
+		return
+	} else if err != nil {
+		log.Printf("Error Suspending User: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RECORD ACTION
+	action, err := cfg.recordAction(r, db.ModerationAction{
+		ModeratorID: moderatorID,
+		Type:        db.ActionSuspendUser,
+		UserID:      userID,
+		ReportID:    request.ReportID,
+		Reason:      request.Reason,
+	})
+	if err != nil {
+		log.Printf("Error Recording Moderation Action: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, action)
+}
+
+func (cfg *ApiConfig) PostUnsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// REQUEST
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	request, ok := decodeModerationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// LIFT SUSPENSION
+	err = cfg.DB.LiftSuspension(userID)
+	if errors.Is(err, db.ErrNotSuspended) {
+		writeError(w, 404, "User is not suspended")
+		return
+	} else if err != nil {
+		log.Printf("Error Lifting Suspension: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RECORD ACTION
+	action, err := cfg.recordAction(r, db.ModerationAction{
+		ModeratorID: moderatorID,
+		Type:        db.ActionUnsuspendUser,
+		UserID:      userID,
+		Reason:      request.Reason,
+	})
+	if err != nil {
+		log.Printf("Error Recording Moderation Action: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, action)
+}
+
+func (cfg *ApiConfig) GetModerationActionsHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	_, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// QUERY PARAMETERS
+	userID := 0
+	if userParam := r.URL.Query().Get("user_id"); userParam != "" {
+		var err error
+		userID, err = strconv.Atoi(userParam)
+		if err != nil {
+			writeError(w, 400, "Invalid ID")
+			return
+		}
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.DB.ListModerationActions(userID))
+}
+
+// GetMyModerationActionsHandler lists the actions taken against the caller,
+// which they may appeal.
+func (cfg *ApiConfig) GetMyModerationActionsHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.DB.ListModerationActions(id))
+}
+
+func (cfg *ApiConfig) PutModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setModerator(w, r, true)
+}
+
+func (cfg *ApiConfig) DeleteModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setModerator(w, r, false)
+}
+
+func (cfg *ApiConfig) setModerator(w http.ResponseWriter, r *http.Request, moderator bool) {
+	// CHECKING AUTHENTICATION
+	adminID, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	if !cfg.isAdmin(adminID) {
+		writeError(w, 403, "Admin access required")
+		return
+	}
+
+	// UPDATE USER
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	err = cfg.DB.SetModerator(userID, moderator)
+	if errors.Is(err, db.ErrInvalidUserID) {
+		writeError(w, 404, "No User by that ID")
+		return
+	} else if err != nil {
+		log.Printf("Error Updating Moderator: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventModeration, adminID, audit.Success, map[string]string{
+		"type":    "set_moderator",
+		"user_id": strconv.Itoa(userID),
+		"value":   strconv.FormatBool(moderator),
+	})
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
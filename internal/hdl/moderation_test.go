@@ -0,0 +1,103 @@
+package hdl
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// newTestModerator adds a user with moderator rights.
+func newTestModerator(t *testing.T, cfg *ApiConfig, email string) (int, string) {
+	t.Helper()
+	id, token := newTestUser(t, cfg, email)
+	err := cfg.DB.SetModerator(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, token
+}
+
+func TestModeratorsCannotReviewReportsAboutThemselves(t *testing.T) {
+	cfg := newTestConfig(t)
+	reporterID, _ := newTestUser(t, cfg, "reporter@example.com")
+	modID, modToken := newTestModerator(t, cfg, "mod@example.com")
+	_, otherModToken := newTestModerator(t, cfg, "other@example.com")
+	adminID, adminToken := newTestModerator(t, cfg, "admin@example.com")
+	cfg.Admins = []int{adminID}
+
+	report, err := cfg.DB.AddReport(db.Report{ReporterID: reporterID, UserID: modID, Reason: "rude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := "/admin/reports/" + strconv.Itoa(report.ID) + "/claim"
+	resolve := "/admin/reports/" + strconv.Itoa(report.ID) + "/resolve"
+	resolution := map[string]string{"resolution": "dismissed"}
+
+	res := serve(t, "POST /admin/reports/{id}/claim", cfg.PostClaimReportHandler, "POST", claim, modToken, nil)
+	if res.Code != 403 {
+		t.Errorf("claiming a report about yourself: status %d, want 403", res.Code)
+	}
+	res = serve(t, "POST /admin/reports/{id}/resolve", cfg.PostResolveReportHandler, "POST", resolve, modToken, resolution)
+	if res.Code != 403 {
+		t.Errorf("resolving a report about yourself: status %d, want 403", res.Code)
+	}
+	res = serve(t, "POST /admin/reports/{id}/claim", cfg.PostClaimReportHandler, "POST", claim, otherModToken, nil)
+	if res.Code != 200 {
+		t.Errorf("another moderator claiming the report: status %d, want 200", res.Code)
+	}
+
+	report, err = cfg.DB.AddReport(db.Report{ReporterID: reporterID, UserID: adminID, Reason: "rude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve = "/admin/reports/" + strconv.Itoa(report.ID) + "/resolve"
+	res = serve(t, "POST /admin/reports/{id}/resolve", cfg.PostResolveReportHandler, "POST", resolve, adminToken, resolution)
+	if res.Code != 200 {
+		t.Errorf("admin resolving a report about themselves: status %d, want 200", res.Code)
+	}
+}
+
+func TestModeratorsCannotReviewTheirOwnAppeals(t *testing.T) {
+	cfg := newTestConfig(t)
+	modID, modToken := newTestModerator(t, cfg, "mod@example.com")
+	otherModID, otherModToken := newTestModerator(t, cfg, "other@example.com")
+	_, thirdModToken := newTestModerator(t, cfg, "third@example.com")
+
+	action, err := cfg.DB.RecordModerationAction(db.ModerationAction{
+		ModeratorID: otherModID,
+		Type:        db.ActionSuspendUser,
+		UserID:      modID,
+		Reason:      "spam",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appeal, err := cfg.DB.AddAppeal(modID, action.ID, "it was not spam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "/admin/appeals/" + strconv.Itoa(appeal.ID) + "/resolve"
+	decision := map[string]string{"decision": db.AppealUpheld}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"appellant", modToken, 403},
+		{"moderator who acted", otherModToken, 403},
+		{"independent moderator", thirdModToken, 200},
+	}
+	for _, tt := range tests {
+		res := serve(t, "POST /admin/appeals/{id}/resolve", cfg.PostResolveAppealHandler, "POST", target, tt.token, decision)
+		if res.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, res.Code, tt.want)
+		}
+	}
+
+	res := serve(t, "POST /admin/appeals/{id}/resolve", cfg.PostResolveAppealHandler, "POST", "/admin/appeals/999/resolve", thirdModToken, decision)
+	if res.Code != 404 {
+		t.Errorf("unknown appeal: status %d, want 404", res.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// notify records a notification if the recipient has that type enabled.
+// Nobody is notified of their own actions. Failing to notify is logged but
+// never fails the request that triggered it.
+func (cfg *ApiConfig) notify(recipientID int, notificationType string, actorID int, chirpID int) {
+	if recipientID == 0 || recipientID == actorID {
+		return
+	}
+	if cfg.DB.IsBlocked(recipientID, actorID) || cfg.DB.IsMuted(recipientID, actorID) {
+		return
+	}
+	if !cfg.DB.GetNotificationPreferences(recipientID)[notificationType] {
+		return
+	}
+	_, err := cfg.DB.AddNotification(db.Notification{
+		UserID:  recipientID,
+		Type:    notificationType,
+		ActorID: actorID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("Error Adding Notification: %s", err)
+	}
+}
+
+// notifyChirp notifies the users a new or edited chirp replies to, quotes or
+// mentions.
+func (cfg *ApiConfig) notifyChirp(chirp db.Chirp) {
+	if parent, ok := cfg.DB.Chirps[chirp.ReplyTo]; ok {
+		cfg.notify(parent.UserID, db.NotifyReply, chirp.UserID, chirp.ID)
+	}
+	if quoted, ok := cfg.DB.Chirps[chirp.QuoteOf]; ok {
+		cfg.notify(quoted.UserID, db.NotifyQuote, chirp.UserID, chirp.ID)
+	}
+	for _, mention := range chirp.Entities.Mentions {
+		cfg.notify(mention.UserID, db.NotifyMention, chirp.UserID, chirp.ID)
+	}
+}
+
+func (cfg *ApiConfig) GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// QUERY PARAMETERS
+	query := r.URL.Query()
+	limit := defaultPageSize
+	if limitParam := query.Get("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			writeError(w, 400, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+			return
+		}
+	}
+	before := 0
+	if beforeParam := query.Get("before"); beforeParam != "" {
+		before, err = strconv.Atoi(beforeParam)
+		if err != nil || before < 1 {
+			writeError(w, 400, "before must be a notification ID")
+			return
+		}
+	}
+	unreadOnly := false
+	if unreadParam := query.Get("unread"); unreadParam != "" {
+		unreadOnly, err = strconv.ParseBool(unreadParam)
+		if err != nil {
+			writeError(w, 400, "unread must be true or false")
+			return
+		}
+	}
+
+	// RESPONSE
+	type responseStruct struct {
+		UnreadCount   int               `json:"unread_count"`
+		Notifications []db.Notification `json:"notifications"`
+	}
+	notifications, unread := cfg.DB.GetNotifications(id, before, limit, unreadOnly)
+	writeResponse(w, 200, responseStruct{
+		UnreadCount:   unread,
+		Notifications: notifications,
+	})
+}
+
+func (cfg *ApiConfig) PostNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		IDs []int `json:"ids"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+
+	// MARK READ
+	err = cfg.DB.MarkNotificationsRead(id, request.IDs)
+	if errors.Is(err, db.ErrNotificationNotFound) {
+		writeError(w, 404, "No Notification by that ID")
+		return
+	} else if err != nil {
+		log.Printf("Error Marking Notifications Read: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+func (cfg *ApiConfig) GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.DB.GetNotificationPreferences(id))
+}
+
+func (cfg *ApiConfig) PutNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	request, err := decodeRequest(w, r, map[string]bool{})
+	if err != nil {
+		return
+	}
+	for notificationType := range request {
+		if !slices.Contains(db.NotificationTypes, notificationType) {
+			writeError(w, 400, "Unknown notification type: "+notificationType)
+			return
+		}
+	}
+
+	// UPDATE PREFERENCES
+	preferences, err := cfg.DB.UpdateNotificationPreferences(id, request)
+	if err != nil {
+		log.Printf("Error Updating Notification Preferences: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, preferences)
+}
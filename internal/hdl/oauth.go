@@ -0,0 +1,506 @@
+package hdl
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
+	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const authCodeLifetime = time.Minute
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<html>
+
+<body>
+    <h1>Authorise {{.ClientName}}</h1>
+    <p><b>{{.ClientName}}</b> would like to:</p>
+    <ul>
+        {{range .Scopes}}<li>{{.}}</li>{{end}}
+    </ul>
+    {{if .Error}}<p style="color: red">{{.Error}}</p>{{end}}
+    <form method="POST" action="/oauth/authorize">
+        <input type="hidden" name="client_id" value="{{.ClientID}}">
+        <input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+        <input type="hidden" name="response_type" value="code">
+        <input type="hidden" name="scope" value="{{.Scope}}">
+        <input type="hidden" name="state" value="{{.State}}">
+        <input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+        <input type="hidden" name="code_challenge_method" value="S256">
+        <p><label>Email <input type="email" name="email"></label></p>
+        <p><label>Password <input type="password" name="password"></label></p>
+        <button type="submit" name="decision" value="approve">Approve</button>
+        <button type="submit" name="decision" value="deny">Deny</button>
+    </form>
+</body>
+
+</html>`))
+
+var scopeDescriptions = map[string]string{
+	scopeChirpsWrite: "Post and delete chirps on your behalf",
+}
+
+type authorizeRequest struct {
+	Client        db.OAuthClient
+	RedirectURI   string
+	Scope         string
+	State         string
+	CodeChallenge string
+}
+
+func (cfg *ApiConfig) PostOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Public       bool     `json:"public"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	if request.Name == "" || len(request.RedirectURIs) == 0 {
+		writeError(w, 400, "A name and at least one redirect URI are required")
+		return
+	}
+	for _, redirectURI := range request.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			writeError(w, 400, "Redirect URIs must be absolute and have no fragment")
+			return
+		}
+	}
+
+	// REGISTER CLIENT
+	clientID, err := auth.RandomString(16)
+	if err != nil {
+		log.Printf("Error Generating Client ID: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	client := db.OAuthClient{
+		ID:           clientID,
+		Name:         request.Name,
+		RedirectURIs: request.RedirectURIs,
+		OwnerID:      id,
+	}
+	secret := ""
+	if !request.Public {
+		secret, err = auth.RandomString(32)
+		if err != nil {
+			log.Printf("Error Generating Client Secret: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+		client.SecretHash, err = bcrypt.GenerateFromPassword([]byte(secret), 10)
+		if err != nil {
+			log.Printf("Error Generating Client Secret Hash: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+	}
+	client, err = cfg.DB.AddOAuthClient(client)
+	if err != nil {
+		log.Printf("Error Adding OAuth Client: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	type responseStruct struct {
+		ClientID     string   `json:"client_id"`
+		ClientSecret string   `json:"client_secret,omitempty"`
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+	}
+	writeResponse(w, 201, responseStruct{
+		ClientID:     client.ID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+	})
+}
+
+func (cfg *ApiConfig) GetOAuthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok := cfg.parseAuthorizeRequest(w, r, r.URL.Query())
+	if !ok {
+		return
+	}
+	renderConsent(w, 200, request, "")
+}
+
+func (cfg *ApiConfig) PostOAuthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeError(w, 400, "Invalid form")
+		return
+	}
+	request, ok := cfg.parseAuthorizeRequest(w, r, r.PostForm)
+	if !ok {
+		return
+	}
+
+	// CONSENT
+	if r.PostForm.Get("decision") != "approve" {
+		cfg.recordEvent(r, eventOAuthConsent, 0, audit.Failure, map[string]string{"client_id": request.Client.ID, "reason": "denied"})
+		redirectAuthorizeError(w, r, request, "access_denied", "The user denied the request")
+		return
+	}
+	user, err := cfg.DB.AuthenticateUser(r.PostForm.Get("email"), []byte(r.PostForm.Get("password")))
+	if errors.Is(err, db.ErrInvalidEmail) || errors.Is(err, db.ErrIncorrectPassword) {
+		target, _ := cfg.DB.GetUserByEmail(r.PostForm.Get("email"))
+		cfg.recordEvent(r, eventLogin, target.ID, audit.Failure, map[string]string{"method": "oauth_consent", "email": r.PostForm.Get("email"), "client_id": request.Client.ID})
+		renderConsent(w, 401, request, "Incorrect email or password")
+		return
+	} else if err != nil {
+		log.Printf("Error Authenticating User: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// ISSUE CODE
+	code, err := auth.RandomString(32)
+	if err != nil {
+		log.Printf("Error Generating Authorization Code: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	err = cfg.DB.AddAuthCode(db.AuthCode{
+		Code:          code,
+		ClientID:      request.Client.ID,
+		UserID:        user.ID,
+		RedirectURI:   request.RedirectURI,
+		Scope:         request.Scope,
+		CodeChallenge: request.CodeChallenge,
+		ExpiresAt:     time.Now().Add(authCodeLifetime),
+	})
+	if err != nil {
+		log.Printf("Error Storing Authorization Code: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventOAuthConsent, user.ID, audit.Success, map[string]string{"client_id": request.Client.ID, "scope": request.Scope})
+
+	// RESPONSE
+	redirectURI, _ := url.Parse(request.RedirectURI)
+	query := redirectURI.Query()
+	query.Set("code", code)
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (cfg *ApiConfig) PostOAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := cfg.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	var userID int
+	var scope, refreshToken string
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code, err := cfg.DB.ConsumeAuthCode(r.PostForm.Get("code"))
+		if errors.Is(err, db.ErrInvalidCode) {
+			writeOAuthError(w, 400, "invalid_grant", "Authorization code is invalid or expired")
+			return
+		} else if err != nil {
+			log.Printf("Error Consuming Authorization Code: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+		if code.ClientID != client.ID || code.RedirectURI != r.PostForm.Get("redirect_uri") {
+			writeOAuthError(w, 400, "invalid_grant", "Authorization code was not issued to this client")
+			return
+		}
+		if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+			writeOAuthError(w, 400, "invalid_grant", "PKCE verification failed")
+			return
+		}
+		userID = code.UserID
+		scope = code.Scope
+		refreshToken, err = auth.IssueOAuthRefreshToken(userID, client.ID, scope, cfg.JWT_Secret, cfg.DB)
+		if err != nil {
+			log.Printf("Error Creating Refresh Token: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+
+	case "refresh_token":
+		refreshToken = r.PostForm.Get("refresh_token")
+		claims, err := auth.ParseToken(refreshToken, "chirpy-oauth-refresh", cfg.JWT_Secret)
+		if err != nil || claims.ClientID != client.ID || !cfg.DB.IsTokenValid(refreshToken) {
+			writeOAuthError(w, 400, "invalid_grant", "Refresh token is invalid")
+			return
+		}
+		scope = claims.Scope
+		if requested := r.PostForm.Get("scope"); requested != "" {
+			for _, s := range strings.Fields(requested) {
+				if !slices.Contains(strings.Fields(claims.Scope), s) {
+					writeOAuthError(w, 400, "invalid_scope", "Requested scope exceeds the original grant")
+					return
+				}
+			}
+			scope = requested
+		}
+		userID, err = strconv.Atoi(claims.Subject)
+		if err != nil {
+			writeOAuthError(w, 400, "invalid_grant", "Refresh token is invalid")
+			return
+		}
+
+	default:
+		writeOAuthError(w, 400, "unsupported_grant_type", "")
+		return
+	}
+
+	// CREATE ACCESS TOKEN
+	accessToken, claims, err := auth.IssueScopedAccessToken(userID, client.ID, scope, cfg.JWT_Secret)
+	if err != nil {
+		log.Printf("Error Creating Access Token: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	cfg.recordEvent(r, eventOAuthToken, userID, audit.Success, map[string]string{"client_id": client.ID, "grant_type": r.PostForm.Get("grant_type")})
+	type responseStruct struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, 200, responseStruct{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(claims.ExpiresAt.Time).Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	})
+}
+
+// PostOAuthIntrospectHandler implements RFC 7662. Clients may only introspect
+// tokens that were issued to them; anything else is reported as inactive.
+func (cfg *ApiConfig) PostOAuthIntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := cfg.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	type responseStruct struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Subject   string `json:"sub,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		Issuer    string `json:"iss,omitempty"`
+		ExpiresAt int64  `json:"exp,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
+	}
+	token := r.PostForm.Get("token")
+	claims, tokenType, ok := cfg.parseClientToken(token, client.ID)
+	if !ok {
+		writeResponse(w, 200, responseStruct{Active: false})
+		return
+	}
+	writeResponse(w, 200, responseStruct{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Subject:   claims.Subject,
+		TokenType: tokenType,
+		Issuer:    claims.Issuer,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		IssuedAt:  claims.IssuedAt.Unix(),
+	})
+}
+
+// PostOAuthRevokeHandler implements RFC 7009. Unknown or foreign tokens are
+// ignored and still answered with 200, as the RFC requires.
+func (cfg *ApiConfig) PostOAuthRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := cfg.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	claims, tokenType, ok := cfg.parseClientToken(token, client.ID)
+	if !ok {
+		w.WriteHeader(200)
+		return
+	}
+	var err error
+	if tokenType == "refresh_token" {
+		err = cfg.DB.RevokeToken(token)
+	} else {
+		err = cfg.DB.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+	}
+	if err != nil {
+		log.Printf("Error Revoking Token: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	userID, _ := strconv.Atoi(claims.Subject)
+	cfg.recordEvent(r, eventOAuthRevoke, userID, audit.Success, map[string]string{"client_id": client.ID, "token_type": tokenType})
+	w.WriteHeader(200)
+}
+
+func (cfg *ApiConfig) parseClientToken(token, clientID string) (auth.AccessClaims, string, bool) {
+	claims, err := auth.ParseToken(token, "chirpy-access", cfg.JWT_Secret)
+	if err == nil {
+		if claims.ClientID != clientID || cfg.DB.IsAccessTokenRevoked(claims.ID) {
+			return auth.AccessClaims{}, "", false
+		}
+		return claims, "access_token", true
+	}
+	claims, err = auth.ParseToken(token, "chirpy-oauth-refresh", cfg.JWT_Secret)
+	if err == nil {
+		if claims.ClientID != clientID || !cfg.DB.IsTokenValid(token) {
+			return auth.AccessClaims{}, "", false
+		}
+		return claims, "refresh_token", true
+	}
+	return auth.AccessClaims{}, "", false
+}
+
+// authenticateClient accepts client credentials via HTTP Basic or the request
+// body. Public clients authenticate with their client ID alone and rely on PKCE.
+func (cfg *ApiConfig) authenticateClient(w http.ResponseWriter, r *http.Request) (db.OAuthClient, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, 400, "invalid_request", "Invalid form")
+		return db.OAuthClient{}, false
+	}
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client, err := cfg.DB.GetOAuthClient(clientID)
+	if err == nil && client.SecretHash != nil {
+		err = bcrypt.CompareHashAndPassword(client.SecretHash, []byte(secret))
+	}
+	if err != nil {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		writeOAuthError(w, 401, "invalid_client", "Client authentication failed")
+		return db.OAuthClient{}, false
+	}
+	return client, true
+}
+
+// parseAuthorizeRequest validates an authorization request. Problems with the
+// client or redirect URI are shown to the user; anything else is reported back
+// to the client's redirect URI as RFC 6749 section 4.1.2.1 describes.
+func (cfg *ApiConfig) parseAuthorizeRequest(w http.ResponseWriter, r *http.Request, params url.Values) (authorizeRequest, bool) {
+	client, err := cfg.DB.GetOAuthClient(params.Get("client_id"))
+	if err != nil {
+		writeError(w, 400, "Unknown client")
+		return authorizeRequest{}, false
+	}
+	redirectURI := params.Get("redirect_uri")
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		writeError(w, 400, "Redirect URI is not registered for this client")
+		return authorizeRequest{}, false
+	}
+
+	request := authorizeRequest{
+		Client:        client,
+		RedirectURI:   redirectURI,
+		Scope:         params.Get("scope"),
+		State:         params.Get("state"),
+		CodeChallenge: params.Get("code_challenge"),
+	}
+	if params.Get("response_type") != "code" {
+		redirectAuthorizeError(w, r, request, "unsupported_response_type", "Only the code response type is supported")
+		return authorizeRequest{}, false
+	}
+	if request.CodeChallenge == "" || params.Get("code_challenge_method") != "S256" {
+		redirectAuthorizeError(w, r, request, "invalid_request", "PKCE with S256 is required")
+		return authorizeRequest{}, false
+	}
+	scopes := strings.Fields(request.Scope)
+	if len(scopes) == 0 {
+		redirectAuthorizeError(w, r, request, "invalid_scope", "A scope is required")
+		return authorizeRequest{}, false
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(grantableScopes, scope) {
+			redirectAuthorizeError(w, r, request, "invalid_scope", "Unknown scope "+scope)
+			return authorizeRequest{}, false
+		}
+	}
+	return request, true
+}
+
+func renderConsent(w http.ResponseWriter, responseCode int, request authorizeRequest, errorText string) {
+	scopes := []string{}
+	for _, scope := range strings.Fields(request.Scope) {
+		scopes = append(scopes, scopeDescriptions[scope])
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(responseCode)
+	err := consentTemplate.Execute(w, map[string]any{
+		"ClientName":    request.Client.Name,
+		"ClientID":      request.Client.ID,
+		"RedirectURI":   request.RedirectURI,
+		"Scope":         request.Scope,
+		"Scopes":        scopes,
+		"State":         request.State,
+		"CodeChallenge": request.CodeChallenge,
+		"Error":         errorText,
+	})
+	if err != nil {
+		log.Printf("Error Rendering Consent Page: %s", err)
+	}
+}
+
+func redirectAuthorizeError(w http.ResponseWriter, r *http.Request, request authorizeRequest, errorCode, description string) {
+	redirectURI, _ := url.Parse(request.RedirectURI)
+	query := redirectURI.Query()
+	query.Set("error", errorCode)
+	query.Set("error_description", description)
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func writeOAuthError(w http.ResponseWriter, responseCode int, errorCode, description string) {
+	type responseStruct struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, responseCode, responseStruct{
+		Error:            errorCode,
+		ErrorDescription: description,
+	})
+}
@@ -0,0 +1,280 @@
+package hdl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testRedirectURI = "https://app.example/callback"
+
+type oauthTest struct {
+	cfg      *ApiConfig
+	userID   int
+	clientID string
+	secret   string
+	verifier string
+}
+
+// newOAuthTest registers a confidential client and a user with a password who
+// can approve its requests.
+func newOAuthTest(t *testing.T) *oauthTest {
+	t.Helper()
+	cfg := newTestConfig(t)
+	_, ownerToken := newTestUser(t, cfg, "developer@example.com")
+	res := serve(t, "POST /api/oauth/clients", cfg.PostOAuthClientHandler, "POST", "/api/oauth/clients", ownerToken, map[string]any{
+		"name":          "Test App",
+		"redirect_uris": []string{testRedirectURI},
+	})
+	if res.Code != 201 {
+		t.Fatalf("registering client: status %d", res.Code)
+	}
+	client := struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}{}
+	err := json.Unmarshal(res.Body.Bytes(), &client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := cfg.DB.AddUser("user@example.com", hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := auth.NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &oauthTest{cfg: cfg, userID: user.ID, clientID: client.ClientID, secret: client.ClientSecret, verifier: verifier}
+}
+
+// post sends a form to an OAuth endpoint.
+func (o *oauthTest) post(handler http.HandlerFunc, target string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	return recorder
+}
+
+// authorize submits the consent form and returns the redirect it produces.
+func (o *oauthTest) authorize(t *testing.T, decision, password string) (int, url.Values) {
+	t.Helper()
+	res := o.post(o.cfg.PostOAuthAuthorizeHandler, "/oauth/authorize", url.Values{
+		"client_id":             {o.clientID},
+		"redirect_uri":          {testRedirectURI},
+		"response_type":         {"code"},
+		"scope":                 {scopeChirpsWrite},
+		"state":                 {"xyz"},
+		"code_challenge":        {auth.PKCEChallenge(o.verifier)},
+		"code_challenge_method": {"S256"},
+		"email":                 {"user@example.com"},
+		"password":              {password},
+		"decision":              {decision},
+	})
+	if res.Code != http.StatusFound {
+		return res.Code, nil
+	}
+	location, err := url.Parse(res.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), testRedirectURI+"?") {
+		t.Fatalf("redirected to %s, want the registered URI", location)
+	}
+	return res.Code, location.Query()
+}
+
+// code approves the request and returns the authorization code.
+func (o *oauthTest) code(t *testing.T) string {
+	t.Helper()
+	status, query := o.authorize(t, "approve", "password")
+	if status != http.StatusFound || query.Get("code") == "" || query.Get("state") != "xyz" {
+		t.Fatalf("authorize: status %d, query %v", status, query)
+	}
+	return query.Get("code")
+}
+
+// exchange redeems a code at the token endpoint.
+func (o *oauthTest) exchange(code, redirectURI, verifier string) *httptest.ResponseRecorder {
+	return o.post(o.cfg.PostOAuthTokenHandler, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {o.clientID},
+		"client_secret": {o.secret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error"`
+}
+
+func decodeToken(t *testing.T, res *httptest.ResponseRecorder) tokenResponse {
+	t.Helper()
+	token := tokenResponse{}
+	err := json.Unmarshal(res.Body.Bytes(), &token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestOAuthCodeExchange(t *testing.T) {
+	o := newOAuthTest(t)
+	res := o.exchange(o.code(t), testRedirectURI, o.verifier)
+	token := decodeToken(t, res)
+	if res.Code != 200 || token.AccessToken == "" || token.RefreshToken == "" || token.Scope != scopeChirpsWrite {
+		t.Fatalf("token exchange: status %d, response %s", res.Code, res.Body)
+	}
+	if res.Header().Get("Cache-Control") != "no-store" {
+		t.Error("token response may be cached")
+	}
+
+	res = serve(t, "POST /api/chirps", o.cfg.PostChirpHandler, "POST", "/api/chirps", token.AccessToken, map[string]string{"body": "posted by an app"})
+	if res.Code != 201 {
+		t.Errorf("chirping with the issued token: status %d, want 201", res.Code)
+	}
+}
+
+func TestOAuthTokenExchangeRejectsBadGrants(t *testing.T) {
+	tests := []struct {
+		name        string
+		redirectURI string
+		verifier    func(o *oauthTest) string
+	}{
+		{"wrong verifier", testRedirectURI, func(*oauthTest) string { return "not-the-verifier-that-was-challenged-at-all" }},
+		{"missing verifier", testRedirectURI, func(*oauthTest) string { return "" }},
+		{"mismatched redirect_uri", "https://app.example/other", func(o *oauthTest) string { return o.verifier }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOAuthTest(t)
+			code := o.code(t)
+			res := o.exchange(code, tt.redirectURI, tt.verifier(o))
+			if token := decodeToken(t, res); res.Code != 400 || token.Error != "invalid_grant" {
+				t.Errorf("status %d, response %s, want 400 invalid_grant", res.Code, res.Body)
+			}
+			// A failed attempt still uses up the code.
+			res = o.exchange(code, testRedirectURI, o.verifier)
+			if res.Code != 400 {
+				t.Errorf("redeeming the code after a failed attempt: status %d, want 400", res.Code)
+			}
+		})
+	}
+}
+
+func TestOAuthCodesAreSingleUse(t *testing.T) {
+	o := newOAuthTest(t)
+	code := o.code(t)
+	if res := o.exchange(code, testRedirectURI, o.verifier); res.Code != 200 {
+		t.Fatalf("first exchange: status %d, want 200", res.Code)
+	}
+	res := o.exchange(code, testRedirectURI, o.verifier)
+	if token := decodeToken(t, res); res.Code != 400 || token.Error != "invalid_grant" {
+		t.Errorf("reused code: status %d, response %s, want 400 invalid_grant", res.Code, res.Body)
+	}
+}
+
+func TestOAuthConsent(t *testing.T) {
+	o := newOAuthTest(t)
+	status, query := o.authorize(t, "deny", "password")
+	if status != http.StatusFound || query.Get("error") != "access_denied" || query.Get("code") != "" {
+		t.Errorf("deny: status %d, query %v, want access_denied", status, query)
+	}
+	if status, _ := o.authorize(t, "approve", "wrong"); status != 401 {
+		t.Errorf("wrong password: status %d, want 401", status)
+	}
+
+	req := httptest.NewRequest("GET", "/oauth/authorize?"+url.Values{
+		"client_id":             {o.clientID},
+		"redirect_uri":          {"https://evil.example/callback"},
+		"response_type":         {"code"},
+		"scope":                 {scopeChirpsWrite},
+		"code_challenge":        {auth.PKCEChallenge(o.verifier)},
+		"code_challenge_method": {"S256"},
+	}.Encode(), nil)
+	res := httptest.NewRecorder()
+	o.cfg.GetOAuthAuthorizeHandler(res, req)
+	if res.Code != 400 || res.Header().Get("Location") != "" {
+		t.Errorf("unregistered redirect_uri: status %d, location %q, want 400 without a redirect", res.Code, res.Header().Get("Location"))
+	}
+}
+
+func TestOAuthRevokedTokensAreInactive(t *testing.T) {
+	o := newOAuthTest(t)
+	token := decodeToken(t, o.exchange(o.code(t), testRedirectURI, o.verifier))
+	introspect := func(tokenString string) bool {
+		res := o.post(o.cfg.PostOAuthIntrospectHandler, "/oauth/introspect", url.Values{
+			"client_id":     {o.clientID},
+			"client_secret": {o.secret},
+			"token":         {tokenString},
+		})
+		result := struct {
+			Active bool `json:"active"`
+		}{}
+		err := json.Unmarshal(res.Body.Bytes(), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Active
+	}
+	revoke := func(tokenString string) {
+		res := o.post(o.cfg.PostOAuthRevokeHandler, "/oauth/revoke", url.Values{
+			"client_id":     {o.clientID},
+			"client_secret": {o.secret},
+			"token":         {tokenString},
+		})
+		if res.Code != 200 {
+			t.Fatalf("revoke: status %d, want 200", res.Code)
+		}
+	}
+
+	if !introspect(token.AccessToken) || !introspect(token.RefreshToken) {
+		t.Fatal("freshly issued tokens are not active")
+	}
+	revoke(token.AccessToken)
+	revoke(token.RefreshToken)
+	if introspect(token.AccessToken) || introspect(token.RefreshToken) {
+		t.Error("revoked tokens are still active")
+	}
+	res := serve(t, "POST /api/chirps", o.cfg.PostChirpHandler, "POST", "/api/chirps", token.AccessToken, map[string]string{"body": "after revocation"})
+	if res.Code != 401 {
+		t.Errorf("chirping with a revoked token: status %d, want 401", res.Code)
+	}
+	res = o.post(o.cfg.PostOAuthTokenHandler, "/oauth/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {o.clientID},
+		"client_secret": {o.secret},
+		"refresh_token": {token.RefreshToken},
+	})
+	if res.Code != 400 {
+		t.Errorf("refreshing with a revoked token: status %d, want 400", res.Code)
+	}
+}
+
+func TestOAuthScopesLimitTokens(t *testing.T) {
+	o := newOAuthTest(t)
+	accessToken, _, err := auth.IssueScopedAccessToken(o.userID, o.clientID, scopeAccount, o.cfg.JWT_Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := serve(t, "POST /api/chirps", o.cfg.PostChirpHandler, "POST", "/api/chirps", accessToken, map[string]string{"body": "out of scope"})
+	if res.Code != 403 {
+		t.Errorf("chirping with an account-only token: status %d, want 403", res.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package hdl
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/oidc"
+)
+
+const (
+	oidcStateCookie   = "oidc_state"
+	oidcStateLifetime = 10 * 60
+)
+
+func (cfg *ApiConfig) GetOIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.OIDC == nil {
+		writeError(w, 404, "External login is not configured")
+		return
+	}
+	authURL, state, err := cfg.OIDC.AuthCodeURL()
+	if err != nil {
+		log.Printf("Error Starting OIDC Login: %s", err)
+		writeError(w, 502, "Identity provider unavailable")
+		return
+	}
+	cfg.setOIDCStateCookie(w, state, oidcStateLifetime)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (cfg *ApiConfig) GetOIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.OIDC == nil {
+		writeError(w, 404, "External login is not configured")
+		return
+	}
+
+	// QUERY PARAMETERS
+	query := r.URL.Query()
+	if idpError := query.Get("error"); idpError != "" {
+		writeError(w, 401, "Identity provider denied login: "+idpError)
+		return
+	}
+	state := query.Get("state")
+	code := query.Get("code")
+	if state == "" || code == "" {
+		writeError(w, 400, "Missing state or code")
+		return
+	}
+
+	// CHECKING STATE BELONGS TO THIS BROWSER
+	cookie, err := r.Cookie(oidcStateCookie)
+	cfg.setOIDCStateCookie(w, "", -1)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		writeError(w, 400, "Login was not started from this browser. Please try again")
+		return
+	}
+
+	// VERIFY IDENTITY
+	claims, err := cfg.OIDC.Exchange(state, code)
+	if errors.Is(err, oidc.ErrUnknownState) {
+		writeError(w, 400, "Login session expired. Please try again")
+		return
+	} else if err != nil {
+		cfg.recordEvent(r, eventLogin, 0, audit.Failure, map[string]string{"method": "oidc", "reason": err.Error()})
+		log.Printf("Error Completing OIDC Login: %s", err)
+		writeError(w, 401, "Could not verify identity")
+		return
+	}
+
+	// LINK IDENTITY TO USER
+	user, err := cfg.linkExternalIdentity(claims)
+	if err != nil {
+		log.Printf("Error Linking External Identity: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventLogin, user.ID, audit.Success, map[string]string{"method": "oidc", "issuer": cfg.OIDC.Issuer()})
+
+	cfg.writeLoginResponse(w, user)
+}
+
+// setOIDCStateCookie ties a login to the browser that started it, so an
+// attacker cannot complete their own login in a victim's browser. A negative
+// maxAge deletes the cookie.
+func (cfg *ApiConfig) setOIDCStateCookie(w http.ResponseWriter, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.BaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Known identities map straight to their user. New identities are linked to an
+// existing account only when the IdP has verified the email address, otherwise
+// a fresh password-less account is created.
+func (cfg *ApiConfig) linkExternalIdentity(claims oidc.Claims) (db.User, error) {
+	issuer := cfg.OIDC.Issuer()
+	user, err := cfg.DB.GetIdentityUser(issuer, claims.Subject)
+	if err == nil {
+		return user, nil
+	} else if !errors.Is(err, db.ErrIdentityNotFound) {
+		return db.User{}, err
+	}
+
+	email := ""
+	if claims.EmailVerified {
+		email = claims.Email
+	}
+	if email != "" {
+		user, err = cfg.DB.GetUserByEmail(email)
+	}
+	if email == "" || errors.Is(err, db.ErrInvalidEmail) {
+		user, err = cfg.DB.AddUser(email, nil)
+	}
+	if err != nil {
+		return db.User{}, err
+	}
+
+	err = cfg.DB.LinkIdentity(issuer, claims.Subject, claims.Email, user.ID)
+	return user, err
+}
@@ -0,0 +1,40 @@
+package hdl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/oidc"
+)
+
+// The callback must come from the browser that started the login, or an
+// attacker could sign a victim into the attacker's account.
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.OIDC = oidc.NewProvider(oidc.Config{Issuer: "http://127.0.0.1:0"})
+	tests := []struct {
+		name    string
+		cookie  string
+		status  int
+		message string
+	}{
+		{"no cookie", "", 400, "not started from this browser"},
+		{"other login", "other-state", 400, "not started from this browser"},
+		{"matching cookie", "state", 400, "Login session expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/oidc/callback?state=state&code=code", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			recorder := httptest.NewRecorder()
+			cfg.GetOIDCCallbackHandler(recorder, req)
+			if recorder.Code != tt.status || !strings.Contains(recorder.Body.String(), tt.message) {
+				t.Errorf("got %d %s, want %d containing %q", recorder.Code, recorder.Body, tt.status, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package hdl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// chirpOrder is a total order over chirps: by key, then by ID to break ties.
+type chirpOrder struct {
+	name string
+	key  func(db.Chirp) int64
+	desc bool
+}
+
+// chirpCursor marks a page boundary by the sort key and ID of the chirp on it,
+// so pages stay stable when chirps are added or deleted. Before cursors select
+// the page preceding the boundary.
+type chirpCursor struct {
+	Order  string `json:"o"`
+	Key    int64  `json:"k"`
+	ID     int    `json:"i"`
+	Before bool   `json:"b,omitempty"`
+}
+
+type chirpPage struct {
+	Chirps []db.Chirp
+	Next   string
+	Prev   string
+}
+
+func chirpIDKey(chirp db.Chirp) int64 {
+	return int64(chirp.ID)
+}
+
+func (o chirpOrder) compare(chirp db.Chirp, key int64, id int) int {
+	result := 0
+	switch chirpKey := o.key(chirp); {
+	case chirpKey < key:
+		result = -1
+	case chirpKey > key:
+		result = 1
+	case chirp.ID < id:
+		result = -1
+	case chirp.ID > id:
+		result = 1
+	}
+	if o.desc {
+		return -result
+	}
+	return result
+}
+
+func (o chirpOrder) sort(chirps []db.Chirp) {
+	sort.Slice(chirps, func(i, j int) bool {
+		return o.compare(chirps[i], o.key(chirps[j]), chirps[j].ID) < 0
+	})
+}
+
+func (o chirpOrder) cursor(chirp db.Chirp, before bool) string {
+	data, _ := json.Marshal(chirpCursor{
+		Order:  o.name,
+		Key:    o.key(chirp),
+		ID:     chirp.ID,
+		Before: before,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (o chirpOrder) decodeCursor(encoded string) (chirpCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return chirpCursor{}, errInvalidCursor
+	}
+	cursor := chirpCursor{}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.Order != o.name {
+		return chirpCursor{}, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// paginate returns one page of chirps, which must already be sorted by o.
+func (o chirpOrder) paginate(chirps []db.Chirp, cursor *chirpCursor, limit int) chirpPage {
+	start, end := 0, min(limit, len(chirps))
+	if cursor != nil {
+		boundary := sort.Search(len(chirps), func(i int) bool {
+			return o.compare(chirps[i], cursor.Key, cursor.ID) >= 0
+		})
+		if cursor.Before {
+			start, end = max(0, boundary-limit), boundary
+		} else {
+			if boundary < len(chirps) && o.compare(chirps[boundary], cursor.Key, cursor.ID) == 0 {
+				boundary++
+			}
+			start, end = boundary, min(boundary+limit, len(chirps))
+		}
+	}
+
+	page := chirpPage{Chirps: chirps[start:end]}
+	if end < len(chirps) && end > start {
+		page.Next = o.cursor(chirps[end-1], false)
+	}
+	if start > 0 && end > start {
+		page.Prev = o.cursor(chirps[start], true)
+	}
+	return page
+}
+
+// parsePageParams reads limit and cursor. It reports paginated=false when the
+// client asked for neither, in which case the full list should be returned.
+func parsePageParams(w http.ResponseWriter, r *http.Request, order chirpOrder) (cursor *chirpCursor, limit int, paginated bool, ok bool) {
+	query := r.URL.Query()
+	limitParam := query.Get("limit")
+	cursorParam := query.Get("cursor")
+	if limitParam == "" && cursorParam == "" {
+		return nil, 0, false, true
+	}
+
+	limit = defaultPageSize
+	if limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			writeError(w, 400, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+			return nil, 0, false, false
+		}
+	}
+	if cursorParam != "" {
+		decoded, err := order.decodeCursor(cursorParam)
+		if err != nil {
+			writeError(w, 400, "Invalid cursor")
+			return nil, 0, false, false
+		}
+		cursor = &decoded
+	}
+	return cursor, limit, true, true
+}
+
+// writeChirpPage responds with a page of chirps and RFC 8288 Link headers
+// pointing at the neighbouring pages.
+func (cfg *ApiConfig) writeChirpPage(w http.ResponseWriter, r *http.Request, page chirpPage, viewerID int) {
+	links := []struct{ rel, cursor string }{{"next", page.Next}, {"prev", page.Prev}}
+	for _, l := range links {
+		if l.cursor == "" {
+			continue
+		}
+		link := url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		query := link.Query()
+		query.Set("cursor", l.cursor)
+		link.RawQuery = query.Encode()
+		w.Header().Add("Link", "<"+link.String()+`>; rel="`+l.rel+`"`)
+	}
+	type responseStruct struct {
+		Chirps []chirpView `json:"chirps"`
+		Next   string      `json:"next,omitempty"`
+		Prev   string      `json:"prev,omitempty"`
+	}
+	writeResponse(w, 200, responseStruct{
+		Chirps: cfg.chirpViews(page.Chirps, viewerID),
+		Next:   page.Next,
+		Prev:   page.Prev,
+	})
+}
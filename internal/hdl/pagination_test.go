@@ -0,0 +1,118 @@
+package hdl
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func testChirps(ids ...int) []db.Chirp {
+	chirps := make([]db.Chirp, len(ids))
+	for i, id := range ids {
+		chirps[i] = db.Chirp{ID: id}
+	}
+	return chirps
+}
+
+func pageIDs(page chirpPage) []int {
+	ids := make([]int, len(page.Chirps))
+	for i, chirp := range page.Chirps {
+		ids[i] = chirp.ID
+	}
+	return ids
+}
+
+func decode(t *testing.T, order chirpOrder, encoded string) *chirpCursor {
+	t.Helper()
+	cursor, err := order.decodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cursor
+}
+
+func TestPaginateWalksForwardAndBack(t *testing.T) {
+	order := chirpOrder{name: "-id", key: chirpIDKey, desc: true}
+	chirps := testChirps(1, 2, 3, 4, 5, 6, 7)
+	order.sort(chirps)
+
+	var forward [][]int
+	var pages []chirpPage
+	page := order.paginate(chirps, nil, 3)
+	for {
+		forward = append(forward, pageIDs(page))
+		pages = append(pages, page)
+		if page.Next == "" {
+			break
+		}
+		page = order.paginate(chirps, decode(t, order, page.Next), 3)
+	}
+	want := [][]int{{7, 6, 5}, {4, 3, 2}, {1}}
+	if !slices.EqualFunc(forward, want, slices.Equal) {
+		t.Fatalf("forward pages = %v, want %v", forward, want)
+	}
+	if pages[0].Prev != "" {
+		t.Error("first page has a prev cursor")
+	}
+
+	back := order.paginate(chirps, decode(t, order, pages[2].Prev), 3)
+	if got := pageIDs(back); !slices.Equal(got, want[1]) {
+		t.Errorf("prev of last page = %v, want %v", got, want[1])
+	}
+	back = order.paginate(chirps, decode(t, order, back.Prev), 3)
+	if got := pageIDs(back); !slices.Equal(got, want[0]) || back.Prev != "" {
+		t.Errorf("prev of second page = %v (prev %q), want %v", got, back.Prev, want[0])
+	}
+}
+
+// Cursors hold the boundary chirp's key rather than an offset, so deleting
+// chirps already seen neither skips nor repeats any on the following page.
+func TestPaginateIsStableUnderDeletes(t *testing.T) {
+	order := chirpOrder{name: "-id", key: chirpIDKey, desc: true}
+	chirps := testChirps(1, 2, 3, 4, 5, 6)
+	order.sort(chirps)
+
+	first := order.paginate(chirps, nil, 2)
+	remaining := testChirps(1, 2, 3, 4)
+	order.sort(remaining)
+	next := order.paginate(remaining, decode(t, order, first.Next), 2)
+	if got := pageIDs(next); !slices.Equal(got, []int{4, 3}) {
+		t.Errorf("page after deletes = %v, want [4 3]", got)
+	}
+}
+
+func TestPaginateBreaksTiesByID(t *testing.T) {
+	order := chirpOrder{name: "-created_at", key: func(db.Chirp) int64 { return 0 }, desc: true}
+	chirps := testChirps(1, 2, 3, 4, 5)
+	order.sort(chirps)
+
+	var seen []int
+	page := order.paginate(chirps, nil, 2)
+	for {
+		seen = append(seen, pageIDs(page)...)
+		if page.Next == "" {
+			break
+		}
+		page = order.paginate(chirps, decode(t, order, page.Next), 2)
+	}
+	if !slices.Equal(seen, []int{5, 4, 3, 2, 1}) {
+		t.Errorf("walked %v, want [5 4 3 2 1]", seen)
+	}
+}
+
+func TestDecodeCursorRejectsOtherOrders(t *testing.T) {
+	byID := chirpOrder{name: "-id", key: chirpIDKey, desc: true}
+	byDate := chirpOrder{name: "-created_at", key: chirpCreatedKey, desc: true}
+	encoded := byID.cursor(db.Chirp{ID: 3}, false)
+
+	_, err := byDate.decodeCursor(encoded)
+	if !errors.Is(err, errInvalidCursor) {
+		t.Errorf("cursor from another order: err = %v, want errInvalidCursor", err)
+	}
+	_, err = byID.decodeCursor("not base64!")
+	if !errors.Is(err, errInvalidCursor) {
+		t.Errorf("garbage cursor: err = %v, want errInvalidCursor", err)
+	}
+}
@@ -0,0 +1,352 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// maxScheduleAhead is how far in the future a chirp can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+type pendingRequest struct {
+	Body      string     `json:"body"`
+	Media     []string   `json:"media"`
+	ReplyTo   int        `json:"reply_to"`
+	QuoteOf   int        `json:"quote_of"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// checkPending validates a draft or scheduled chirp as it would be checked
+// on posting, so problems are reported now rather than at publication.
+func (cfg *ApiConfig) checkPending(w http.ResponseWriter, userID int, request pendingRequest) bool {
+	err := cfg.checkChirp(userID, request.Body, request.Media, request.ReplyTo, request.QuoteOf)
+	if err != nil {
+		writeChirpError(w, err)
+		return false
+	}
+	_, ok := cfg.moderateChirp(w, request.Body, request.Media)
+	return ok
+}
+
+// checkSchedule validates a publication time and the user's allowance of
+// scheduled chirps.
+func (cfg *ApiConfig) checkSchedule(w http.ResponseWriter, userID int, publishAt time.Time) bool {
+	now := time.Now()
+	if !publishAt.After(now) || publishAt.After(now.Add(maxScheduleAhead)) {
+		writeError(w, 400, "publish_at must be in the future and within a year")
+		return false
+	}
+	limit := cfg.entitlements(userID).MaxScheduledChirps
+	if limit == 0 {
+		writeError(w, 403, "Your plan does not include scheduling chirps")
+		return false
+	}
+	if cfg.DB.CountScheduledChirps(userID) >= limit {
+		writeError(w, 403, "Your plan allows "+strconv.Itoa(limit)+" scheduled chirps")
+		return false
+	}
+	return true
+}
+
+func (cfg *ApiConfig) PostDraftHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	request, err := decodeRequest(w, r, pendingRequest{})
+	if err != nil {
+		return
+	}
+	if !cfg.checkPending(w, id, request) {
+		return
+	}
+
+	// SAVE DRAFT
+	draft, err := cfg.DB.SavePendingChirp(db.PendingChirp{
+		UserID:  id,
+		Status:  db.PendingDraft,
+		Body:    request.Body,
+		Media:   request.Media,
+		ReplyTo: request.ReplyTo,
+		QuoteOf: request.QuoteOf,
+	})
+	if err != nil {
+		log.Printf("Error Saving Draft: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 201, draft)
+}
+
+func (cfg *ApiConfig) PutDraftHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	draftID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	draft, err := cfg.DB.GetPendingChirp(draftID, id)
+	if err != nil || draft.Status != db.PendingDraft {
+		writeError(w, 404, "No Draft by that ID")
+		return
+	}
+	request, err := decodeRequest(w, r, pendingRequest{})
+	if err != nil {
+		return
+	}
+	if !cfg.checkPending(w, id, request) {
+		return
+	}
+
+	// SAVE DRAFT
+	draft.Body = request.Body
+	draft.Media = request.Media
+	draft.ReplyTo = request.ReplyTo
+	draft.QuoteOf = request.QuoteOf
+	draft, err = cfg.DB.SavePendingChirp(draft)
+	if err != nil {
+		log.Printf("Error Saving Draft: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, draft)
+}
+
+// PostPublishDraftHandler publishes a draft now, or schedules it if the
+// request sets publish_at.
+func (cfg *ApiConfig) PostPublishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	draftID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	draft, err := cfg.DB.GetPendingChirp(draftID, id)
+	if err != nil || draft.Status != db.PendingDraft {
+		writeError(w, 404, "No Draft by that ID")
+		return
+	}
+	type requestStruct struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+	request := requestStruct{}
+	if r.ContentLength != 0 {
+		request, err = decodeRequest(w, r, requestStruct{})
+		if err != nil {
+			return
+		}
+	}
+
+	// SCHEDULE DRAFT
+	if request.PublishAt != nil {
+		if !cfg.checkSchedule(w, id, *request.PublishAt) {
+			return
+		}
+		draft.Status = db.PendingScheduled
+		draft.PublishAt = request.PublishAt.UTC()
+		draft, err = cfg.DB.SavePendingChirp(draft)
+		if err != nil {
+			log.Printf("Error Scheduling Draft: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+		cfg.Scheduler.Schedule(draft.ID, draft.PublishAt)
+		writeResponse(w, 200, draft)
+		return
+	}
+
+	// PUBLISH DRAFT
+	chirp, err := cfg.publishPending(draft)
+	if err != nil {
+		writeChirpError(w, err)
+		return
+	}
+	err = cfg.DB.DeletePendingChirp(draft.ID, id)
+	if err != nil {
+		log.Printf("Error Deleting Draft: %s", err)
+	}
+
+	// RESPONSE
+	writeResponse(w, 201, cfg.chirpView(chirp, id))
+}
+
+func (cfg *ApiConfig) PostScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	request, err := decodeRequest(w, r, pendingRequest{})
+	if err != nil {
+		return
+	}
+	if request.PublishAt == nil {
+		writeError(w, 400, "publish_at is required")
+		return
+	}
+	if !cfg.checkPending(w, id, request) || !cfg.checkSchedule(w, id, *request.PublishAt) {
+		return
+	}
+
+	// SCHEDULE CHIRP
+	pending, err := cfg.DB.SavePendingChirp(db.PendingChirp{
+		UserID:    id,
+		Status:    db.PendingScheduled,
+		Body:      request.Body,
+		Media:     request.Media,
+		ReplyTo:   request.ReplyTo,
+		QuoteOf:   request.QuoteOf,
+		PublishAt: request.PublishAt.UTC(),
+	})
+	if err != nil {
+		log.Printf("Error Scheduling Chirp: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.Scheduler.Schedule(pending.ID, pending.PublishAt)
+
+	// RESPONSE
+	writeResponse(w, 201, pending)
+}
+
+func (cfg *ApiConfig) GetDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listPending(w, r, db.PendingDraft)
+}
+
+// GetScheduledChirpsHandler lists chirps waiting to publish along with any
+// that failed to.
+func (cfg *ApiConfig) GetScheduledChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listPending(w, r, db.PendingScheduled, db.PendingFailed)
+}
+
+func (cfg *ApiConfig) listPending(w http.ResponseWriter, r *http.Request, statuses ...string) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.DB.ListPendingChirps(id, statuses...))
+}
+
+func (cfg *ApiConfig) DeleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.deletePending(w, r, "Draft", db.PendingDraft)
+}
+
+func (cfg *ApiConfig) DeleteScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.deletePending(w, r, "Scheduled Chirp", db.PendingScheduled, db.PendingFailed)
+}
+
+func (cfg *ApiConfig) deletePending(w http.ResponseWriter, r *http.Request, kind string, statuses ...string) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// DELETE PENDING CHIRP
+	pendingID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	pending, err := cfg.DB.GetPendingChirp(pendingID, id)
+	if err != nil || !slices.Contains(statuses, pending.Status) {
+		writeError(w, 404, "No "+kind+" by that ID")
+		return
+	}
+	cfg.Scheduler.Cancel(pendingID)
+	err = cfg.DB.DeletePendingChirp(pendingID, id)
+	if err != nil {
+		log.Printf("Error Deleting Pending Chirp: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+// PublishScheduled publishes a scheduled chirp that has fallen due. It is run
+// by the scheduler; chirps that can no longer be posted are marked failed.
+func (cfg *ApiConfig) PublishScheduled(id int) {
+	pending, err := cfg.DB.GetPendingChirp(id, 0)
+	if err != nil || pending.Status != db.PendingScheduled {
+		return
+	}
+	chirp, err := cfg.publishPending(pending)
+	if err != nil {
+		var chirpErr chirpError
+		if !errors.As(err, &chirpErr) {
+			log.Printf("Error Publishing Scheduled Chirp: %s", err)
+		}
+		err = cfg.DB.FailPendingChirp(id, err.Error())
+		if err != nil {
+			log.Printf("Error Updating Scheduled Chirp: %s", err)
+		}
+		return
+	}
+	err = cfg.DB.DeletePendingChirp(id, 0)
+	if err != nil {
+		log.Printf("Error Deleting Scheduled Chirp: %s", err)
+	}
+	log.Printf("Published scheduled chirp %d as chirp %d", id, chirp.ID)
+}
+
+// publishPending posts a draft or scheduled chirp, checking it again since
+// the author's plan, the chirps it involves or the moderation rules may have
+// changed since it was saved.
+func (cfg *ApiConfig) publishPending(pending db.PendingChirp) (db.Chirp, error) {
+	if _, suspended := cfg.DB.GetSuspension(pending.UserID, time.Now()); suspended {
+		return db.Chirp{}, chirpError{403, "Your account is suspended"}
+	}
+	err := cfg.checkChirp(pending.UserID, pending.Body, pending.Media, pending.ReplyTo, pending.QuoteOf)
+	if err != nil {
+		return db.Chirp{}, err
+	}
+	decision := cfg.Moderation.Moderate(pending.Body, pending.Media)
+	if decision.Rejected {
+		return db.Chirp{}, chirpError{400, "Chirp rejected: " + strings.Join(decision.Reasons, "; ")}
+	}
+	return cfg.publishChirp(db.Chirp{
+		UserID:  pending.UserID,
+		Media:   pending.Media,
+		ReplyTo: pending.ReplyTo,
+		QuoteOf: pending.QuoteOf,
+	}, decision)
+}
@@ -0,0 +1,36 @@
+package hdl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/polkasim"
+)
+
+// TestPolkaScenarios runs the Polka simulator's integration scenarios against
+// the webhook and subscription handlers.
+func TestPolkaScenarios(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Polka_Secret = []byte("whsec")
+	cfg.Subscriptions = db.SubscriptionPolicy{Period: db.DefaultSubscriptionPeriod, GracePeriod: 3 * 24 * time.Hour}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users", cfg.PostUserHandler)
+	mux.HandleFunc("POST /api/login", cfg.PostLoginHandler)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.PostPolkaWebhook)
+	mux.HandleFunc("GET /api/users/subscription", cfg.GetSubscriptionHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sim := polkasim.New(server.URL, cfg.Polka_Secret)
+	for _, scenario := range polkasim.Scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			err := sim.Run(scenario)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+// profile is what anyone can see about a user.
+type profile struct {
+	ID             int        `json:"id"`
+	Handle         string     `json:"handle,omitempty"`
+	DisplayName    string     `json:"display_name"`
+	Bio            string     `json:"bio,omitempty"`
+	AvatarURL      string     `json:"avatar_url,omitempty"`
+	JoinedAt       *time.Time `json:"joined_at,omitempty"`
+	FollowersCount int        `json:"followers_count"`
+	FollowingCount int        `json:"following_count"`
+}
+
+func (cfg *ApiConfig) publicProfile(user db.User) profile {
+	p := profile{
+		ID:             user.ID,
+		Handle:         user.Handle,
+		DisplayName:    displayName(user),
+		Bio:            user.Bio,
+		AvatarURL:      user.AvatarURL,
+		FollowersCount: len(cfg.DB.GetFollowers(user.ID)),
+		FollowingCount: len(cfg.DB.GetFollowing(user.ID)),
+	}
+	// Some users from before join dates were recorded have none.
+	if !user.CreatedAt.IsZero() {
+		p.JoinedAt = &user.CreatedAt
+	}
+	return p
+}
+
+// displayName is how a user is named to people who cannot see their email.
+func displayName(user db.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	if user.Handle != "" {
+		return "@" + user.Handle
+	}
+	return "User " + strconv.Itoa(user.ID)
+}
+
+func isWebURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func (cfg *ApiConfig) GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	user, ok := cfg.DB.Users[id]
+	if !ok {
+		writeError(w, 404, "No User by that ID")
+		return
+	}
+	cfg.writeProfile(w, r, user)
+}
+
+func (cfg *ApiConfig) GetProfileByHandleHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := cfg.DB.GetUserByHandle(strings.TrimPrefix(r.PathValue("handle"), "@"))
+	if err != nil {
+		writeError(w, 404, "No User by that handle")
+		return
+	}
+	cfg.writeProfile(w, r, user)
+}
+
+func (cfg *ApiConfig) writeProfile(w http.ResponseWriter, r *http.Request, user db.User) {
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	if cfg.DB.IsBlocked(viewerID, user.ID) {
+		writeError(w, 404, "No User by that ID")
+		return
+	}
+	writeResponse(w, 200, cfg.publicProfile(user))
+}
+
+func (cfg *ApiConfig) PatchProfileHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	user := cfg.DB.Users[id]
+	updated := db.Profile{
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL,
+	}
+	if request.Handle != nil {
+		updated.Handle = strings.TrimPrefix(*request.Handle, "@")
+	}
+	if request.DisplayName != nil {
+		updated.DisplayName = strings.TrimSpace(*request.DisplayName)
+	}
+	if request.Bio != nil {
+		updated.Bio = strings.TrimSpace(*request.Bio)
+	}
+	if request.AvatarURL != nil {
+		updated.AvatarURL = *request.AvatarURL
+	}
+
+	// VALIDATION
+	if updated.Handle != "" && !entities.ValidHandle(updated.Handle) {
+		writeError(w, 400, "Handles are up to 30 letters, digits or underscores and include a letter")
+		return
+	}
+	if utf8.RuneCountInString(updated.DisplayName) > maxDisplayNameLength {
+		writeError(w, 400, "Display name is too long")
+		return
+	}
+	if utf8.RuneCountInString(updated.Bio) > maxBioLength {
+		writeError(w, 400, "Bio is too long")
+		return
+	}
+	if updated.AvatarURL != "" && !isWebURL(updated.AvatarURL) {
+		writeError(w, 400, "Avatar must be an http or https URL")
+		return
+	}
+
+	// MODERATION
+	// Only fields being changed are checked, so an old flag is not reported
+	// again on every edit. Handles cannot be masked, so any match rules them
+	// out; their words are split on underscores to be checked one by one.
+	flagged := []string{}
+	if request.Handle != nil {
+		handleWords := strings.ReplaceAll(updated.Handle, "_", " ")
+		decision := cfg.Moderation.Moderate(handleWords, nil)
+		if decision.Rejected || decision.Text != handleWords {
+			writeError(w, 400, "This handle is not allowed")
+			return
+		}
+		if decision.Flagged {
+			flagged = append(flagged, decision.Reasons...)
+		}
+	}
+	fields := []struct {
+		requested *string
+		value     *string
+	}{
+		{request.DisplayName, &updated.DisplayName},
+		{request.Bio, &updated.Bio},
+	}
+	for _, field := range fields {
+		if field.requested == nil {
+			continue
+		}
+		decision := cfg.Moderation.Moderate(*field.value, nil)
+		if decision.Rejected {
+			writeError(w, 400, "Profile rejected: "+strings.Join(decision.Reasons, "; "))
+			return
+		}
+		*field.value = decision.Text
+		if decision.Flagged {
+			flagged = append(flagged, decision.Reasons...)
+		}
+	}
+
+	// UPDATE PROFILE
+	user, err = cfg.DB.UpdateProfile(id, updated)
+	if errors.Is(err, db.ErrTakenHandle) {
+		writeError(w, 400, "This handle has already been taken")
+		return
+	} else if err != nil {
+		log.Printf("Error updating profile: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	cfg.flagProfile(user, flagged)
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.publicProfile(user))
+}
+
+// flagProfile reports a user whose profile was flagged by the moderation
+// pipeline, so a moderator can review it.
+func (cfg *ApiConfig) flagProfile(user db.User, reasons []string) {
+	if len(reasons) == 0 {
+		return
+	}
+	_, err := cfg.DB.AddReport(db.Report{
+		UserID: user.ID,
+		Reason: "Profile flagged by moderation: " + strings.Join(reasons, "; "),
+	})
+	if err != nil {
+		log.Printf("Error Flagging Profile: %s", err)
+	}
+}
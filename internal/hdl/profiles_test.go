@@ -0,0 +1,114 @@
+package hdl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func patchProfile(t *testing.T, cfg *ApiConfig, token string, body map[string]string) (int, profile) {
+	t.Helper()
+	res := serve(t, "PATCH /api/users/profile", cfg.PatchProfileHandler, "PATCH", "/api/users/profile", token, body)
+	updated := profile{}
+	if res.Code == 200 {
+		err := json.Unmarshal(res.Body.Bytes(), &updated)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.Code, updated
+}
+
+func TestProfileModeration(t *testing.T) {
+	cfg := newTestConfig(t)
+	userID, token := newTestUser(t, cfg, "a@example.com")
+
+	code, updated := patchProfile(t, cfg, token, map[string]string{"display_name": "Fornax Fan", "bio": "I love kerfuffle"})
+	if code != 200 || updated.DisplayName != "**** Fan" || updated.Bio != "I love ****" {
+		t.Errorf("masking: status %d, profile %+v", code, updated)
+	}
+	if stored := cfg.DB.Users[userID]; stored.Bio != "I love ****" {
+		t.Errorf("stored bio %q was not masked", stored.Bio)
+	}
+
+	for _, handle := range []string{"fornax", "F0RNAX", "fornax_fan"} {
+		if code, _ := patchProfile(t, cfg, token, map[string]string{"handle": handle}); code != 400 {
+			t.Errorf("handle %q: status %d, want 400", handle, code)
+		}
+	}
+	if code, _ := patchProfile(t, cfg, token, map[string]string{"handle": "astronomer"}); code != 200 {
+		t.Errorf("clean handle: status %d, want 200", code)
+	}
+}
+
+func TestFlaggedProfilesAreReported(t *testing.T) {
+	cfg := newTestConfig(t)
+	userID, token := newTestUser(t, cfg, "a@example.com")
+
+	code, _ := patchProfile(t, cfg, token, map[string]string{"bio": "buy followers here"})
+	if code != 200 {
+		t.Fatalf("status %d, want 200", code)
+	}
+	reports := cfg.DB.ListReports(db.ReportOpen)
+	if len(reports) != 1 || reports[0].UserID != userID || reports[0].ChirpID != 0 {
+		t.Fatalf("reports = %+v, want one about the user", reports)
+	}
+
+	// Editing another field does not report the same bio again.
+	code, _ = patchProfile(t, cfg, token, map[string]string{"display_name": "Spammy"})
+	if code != 200 {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if reports := cfg.DB.ListReports(db.ReportOpen); len(reports) != 1 {
+		t.Errorf("got %d reports, want 1", len(reports))
+	}
+}
+
+// The handle lookup shares a prefix with the follow lists, so the routes are
+// registered together as in main to catch conflicts between the patterns.
+func TestProfileByHandleRoute(t *testing.T) {
+	cfg := newTestConfig(t)
+	userID, token := newTestUser(t, cfg, "a@example.com")
+	if code, _ := patchProfile(t, cfg, token, map[string]string{"handle": "astronomer"}); code != 200 {
+		t.Fatalf("setting handle: status %d, want 200", code)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{id}/{list}", cfg.GetFollowListHandler)
+	mux.HandleFunc("GET /api/users/{id}", cfg.GetProfileHandler)
+	mux.HandleFunc("GET /api/users/by-handle/{handle}", cfg.GetProfileByHandleHandler)
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/users/by-handle/astronomer", 200},
+		{"/api/users/by-handle/@astronomer", 200},
+		{"/api/users/by-handle/nobody", 404},
+		{"/api/users/" + strconv.Itoa(userID), 200},
+		{"/api/users/" + strconv.Itoa(userID) + "/followers", 200},
+		{"/api/users/" + strconv.Itoa(userID) + "/following", 200},
+		{"/api/users/" + strconv.Itoa(userID) + "/friends", 404},
+	}
+	for _, tt := range tests {
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest("GET", tt.target, nil))
+		if res.Code != tt.want {
+			t.Errorf("GET %s: status %d, want %d", tt.target, res.Code, tt.want)
+		}
+	}
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest("GET", "/api/users/by-handle/astronomer", nil))
+	found := profile{}
+	err := json.Unmarshal(res.Body.Bytes(), &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != userID {
+		t.Errorf("by-handle returned user %d, want %d", found.ID, userID)
+	}
+}
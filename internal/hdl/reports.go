@@ -0,0 +1,197 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+const maxReasonLength = 500
+
+var reportResolutions = []string{"dismissed", "actioned"}
+
+func (cfg *ApiConfig) PostReportHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		ChirpID int    `json:"chirp_id"`
+		UserID  int    `json:"user_id"`
+		Reason  string `json:"reason"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	if request.Reason == "" || len(request.Reason) > maxReasonLength {
+		writeError(w, 400, "reason must be between 1 and "+strconv.Itoa(maxReasonLength)+" characters")
+		return
+	}
+	if request.ChirpID != 0 {
+		chirp, ok := cfg.DB.Chirps[request.ChirpID]
+		if !ok || !cfg.canView(chirp, id) {
+			writeError(w, 404, "No Chirp by that ID")
+			return
+		}
+		request.UserID = chirp.UserID
+	}
+	if request.UserID == id {
+		writeError(w, 400, "You cannot report yourself")
+		return
+	}
+
+	// ADD REPORT
+	report, err := cfg.DB.AddReport(db.Report{
+		ReporterID: id,
+		ChirpID:    request.ChirpID,
+		UserID:     request.UserID,
+		Reason:     request.Reason,
+	})
+	if errors.Is(err, db.ErrInvalidUserID) {
+		writeError(w, 404, "No User by that ID")
+		return
+	} else if err != nil {
+		log.Printf("Error Adding Report: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventReport, id, audit.Success, map[string]string{
+		"report_id": strconv.Itoa(report.ID),
+		"status":    report.Status,
+	})
+
+	// RESPONSE
+	writeResponse(w, 201, report)
+}
+
+func (cfg *ApiConfig) GetReportsHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	_, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// QUERY PARAMETERS
+	status := r.URL.Query().Get("status")
+	if status != "" && status != db.ReportOpen && status != db.ReportClaimed && status != db.ReportResolved {
+		writeError(w, 400, "status must be open, claimed or resolved")
+		return
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, cfg.DB.ListReports(status))
+}
+
+func (cfg *ApiConfig) PostClaimReportHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// CLAIM REPORT
+	reportID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	if !cfg.canReview(w, reportID, moderatorID) {
+		return
+	}
+	report, err := cfg.DB.ClaimReport(reportID, moderatorID)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+	cfg.recordEvent(r, eventReport, moderatorID, audit.Success, map[string]string{
+		"report_id": strconv.Itoa(report.ID),
+		"status":    report.Status,
+	})
+
+	// RESPONSE
+	writeResponse(w, 200, report)
+}
+
+func (cfg *ApiConfig) PostResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+
+	// REQUEST
+	reportID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 400, "Invalid ID")
+		return
+	}
+	type requestStruct struct {
+		Resolution string `json:"resolution"`
+		Note       string `json:"note"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	if !slices.Contains(reportResolutions, request.Resolution) {
+		writeError(w, 400, "resolution must be dismissed or actioned")
+		return
+	}
+
+	// RESOLVE REPORT
+	if !cfg.canReview(w, reportID, moderatorID) {
+		return
+	}
+	report, err := cfg.DB.ResolveReport(reportID, moderatorID, request.Resolution, request.Note)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+	cfg.recordEvent(r, eventReport, moderatorID, audit.Success, map[string]string{
+		"report_id":  strconv.Itoa(report.ID),
+		"status":     report.Status,
+		"resolution": report.Resolution,
+	})
+
+	// RESPONSE
+	writeResponse(w, 200, report)
+}
+
+// canReview stops moderators handling reports about themselves, unless they
+// are an admin.
+func (cfg *ApiConfig) canReview(w http.ResponseWriter, reportID, moderatorID int) bool {
+	report, err := cfg.DB.GetReport(reportID)
+	if err != nil {
+		writeReportError(w, err)
+		return false
+	}
+	if report.UserID == moderatorID && !cfg.isAdmin(moderatorID) {
+		writeError(w, 403, "You cannot review a report about yourself")
+		return false
+	}
+	return true
+}
+
+func writeReportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrReportNotFound):
+		writeError(w, 404, "No Report by that ID")
+	case errors.Is(err, db.ErrReportClaimed):
+		writeError(w, 409, "Report is claimed by another moderator")
+	case errors.Is(err, db.ErrReportResolved):
+		writeError(w, 409, "Report is already resolved")
+	default:
+		log.Printf("Error Updating Report: %s", err)
+		w.WriteHeader(500)
+	}
+}
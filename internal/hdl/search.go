@@ -0,0 +1,53 @@
+package hdl
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/search"
+)
+
+func (cfg *ApiConfig) GetSearchHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// QUERY PARAMETERS
+	query, err := search.ParseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, 400, "Invalid query: "+err.Error())
+		return
+	}
+	limit := defaultPageSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			writeError(w, 400, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+			return
+		}
+	}
+
+	// SEARCH
+	type resultStruct struct {
+		db.Chirp
+		Score float64 `json:"score"`
+	}
+	results := []resultStruct{}
+	for _, result := range cfg.Search.Search(query) {
+		chirp, ok := cfg.DB.Chirps[result.ID]
+		if !ok || !cfg.shows(chirp, viewerID) {
+			continue
+		}
+		results = append(results, resultStruct{Chirp: chirp, Score: result.Score})
+		if len(results) == limit {
+			break
+		}
+	}
+
+	// RESPONSE
+	writeResponse(w, 200, results)
+}
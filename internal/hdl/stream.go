@@ -0,0 +1,231 @@
+package hdl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/stream"
+)
+
+// streamHeartbeat keeps idle streams from being closed by proxies.
+const streamHeartbeat = 30 * time.Second
+
+// streamRequest is what a stream client is subscribed to: chirps matching
+// filter, limited to the viewer's home timeline if timeline is set.
+type streamRequest struct {
+	viewerID int
+	filter   chirpFilter
+	timeline bool
+}
+
+type streamMessage struct {
+	ID      string     `json:"id,omitempty"`
+	Type    string     `json:"type"`
+	ChirpID int        `json:"chirp_id,omitempty"`
+	Chirp   *chirpView `json:"chirp,omitempty"`
+}
+
+func (cfg *ApiConfig) parseStreamRequest(w http.ResponseWriter, r *http.Request) (streamRequest, bool) {
+	viewerID, err := cfg.viewer(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return streamRequest{}, false
+	}
+	filter, ok := parseChirpFilter(w, r)
+	if !ok {
+		return streamRequest{}, false
+	}
+	request := streamRequest{viewerID: viewerID, filter: filter}
+	if value := r.URL.Query().Get("timeline"); value != "" {
+		request.timeline, err = strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, 400, "timeline must be true or false")
+			return streamRequest{}, false
+		}
+		if request.timeline && viewerID == 0 {
+			writeError(w, 401, "Authentication required for the timeline stream")
+			return streamRequest{}, false
+		}
+	}
+	return request, true
+}
+
+// streamMessage returns the message to send for an event, and false if the
+// client should not see it. Deleted chirps can only be filtered by author, so
+// they are sent to every client following that author's chirps.
+func (cfg *ApiConfig) streamMessage(request streamRequest, event stream.Event) (streamMessage, bool) {
+	authorID := event.UserID
+	if len(request.filter.authors) > 0 && !slices.Contains(request.filter.authors, authorID) {
+		return streamMessage{}, false
+	}
+	if request.timeline && authorID != request.viewerID && !cfg.DB.IsFollowing(request.viewerID, authorID) {
+		return streamMessage{}, false
+	}
+	message := streamMessage{ID: event.ID, Type: event.Type, ChirpID: event.ChirpID}
+	if event.Chirp == nil {
+		if request.viewerID != 0 && (cfg.DB.IsBlocked(request.viewerID, authorID) || cfg.DB.IsMuted(request.viewerID, authorID)) {
+			return streamMessage{}, false
+		}
+		return message, true
+	}
+	if !request.filter.matches(*event.Chirp, cfg.DB.Users) || !cfg.shows(*event.Chirp, request.viewerID) {
+		return streamMessage{}, false
+	}
+	view := cfg.chirpView(*event.Chirp, 0)
+	message.Chirp = &view
+	return message, true
+}
+
+// lastEventID reads where a reconnecting client left off. Browsers send the
+// Last-Event-ID header; other clients may use the last_event_id parameter.
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
+// GetStreamHandler pushes chirp changes as Server-Sent Events. Clients that
+// fall too far behind are disconnected and resume with Last-Event-ID; a
+// resync event means some changes were missed and the client should refetch.
+func (cfg *ApiConfig) GetStreamHandler(w http.ResponseWriter, r *http.Request) {
+	// REQUEST
+	request, ok := cfg.parseStreamRequest(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Printf("Error Streaming: response cannot be flushed")
+		w.WriteHeader(500)
+		return
+	}
+
+	// SUBSCRIBE
+	sub, missed, complete := cfg.Stream.Subscribe(lastEventID(r))
+	defer sub.Close()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		writeSSE(w, streamMessage{Type: "resync"})
+	}
+	for _, event := range missed {
+		if message, ok := cfg.streamMessage(request, event); ok {
+			writeSSE(w, message)
+		}
+	}
+	flusher.Flush()
+
+	// STREAM
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-sub.Events:
+			if !open {
+				if sub.Dropped() {
+					writeSSE(w, streamMessage{Type: "overflow"})
+					flusher.Flush()
+				}
+				return
+			}
+			message, ok := cfg.streamMessage(request, event)
+			if !ok {
+				continue
+			}
+			writeSSE(w, message)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func writeSSE(w http.ResponseWriter, message streamMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error Marshalling Stream Event: %s", err)
+		return
+	}
+	if message.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", message.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.Type, data)
+}
+
+// GetStreamWebSocketHandler pushes the same messages as GetStreamHandler over
+// a WebSocket, one JSON text message per event. Clients resume with the
+// last_event_id parameter.
+func (cfg *ApiConfig) GetStreamWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	// REQUEST
+	request, ok := cfg.parseStreamRequest(w, r)
+	if !ok {
+		return
+	}
+	conn, err := stream.Upgrade(w, r)
+	if errors.Is(err, stream.ErrNotWebSocket) {
+		writeError(w, 400, "Expected a WebSocket handshake")
+		return
+	} else if err != nil {
+		log.Printf("Error Upgrading Connection: %s", err)
+		return
+	}
+	go conn.ReadLoop()
+
+	// SUBSCRIBE
+	sub, missed, complete := cfg.Stream.Subscribe(lastEventID(r))
+	defer sub.Close()
+	send := func(message streamMessage) bool {
+		data, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Error Marshalling Stream Event: %s", err)
+			return true
+		}
+		return conn.WriteText(data) == nil
+	}
+	if !complete && !send(streamMessage{Type: "resync"}) {
+		return
+	}
+	for _, event := range missed {
+		if message, ok := cfg.streamMessage(request, event); ok && !send(message) {
+			return
+		}
+	}
+
+	// STREAM
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-conn.Closed():
+			return
+		case event, open := <-sub.Events:
+			if !open {
+				if sub.Dropped() {
+					conn.Close(stream.ClosePolicy)
+				} else {
+					conn.Close(stream.CloseGoingAway)
+				}
+				return
+			}
+			if message, ok := cfg.streamMessage(request, event); ok && !send(message) {
+				return
+			}
+		case <-heartbeat.C:
+			if conn.Ping() != nil {
+				return
+			}
+		}
+	}
+}
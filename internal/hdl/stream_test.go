@@ -0,0 +1,308 @@
+package hdl
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/stream"
+)
+
+type sseEvent struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// readSSE reads one block of a Server-Sent Events stream. Blocks without an
+// event, like the retry hint and heartbeats, have an empty Type.
+func readSSE(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	event := sseEvent{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %s", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return event
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Type = value
+		case "data":
+			event.Data = value
+		}
+	}
+}
+
+// openStream connects to the SSE endpoint and waits until it is subscribed.
+func openStream(t *testing.T, server *httptest.Server, lastEventID string) (*bufio.Reader, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	if res.StatusCode != 200 || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(res.Body)
+	// The retry hint is written after subscribing.
+	readSSE(t, reader)
+	return reader, cancel
+}
+
+func TestStreamResumesWithLastEventID(t *testing.T) {
+	cfg := newTestConfig(t)
+	userID, _ := newTestUser(t, cfg, "a@example.com")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/stream", cfg.GetStreamHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	chirp := func(body string) db.Chirp {
+		created, err := cfg.DB.CreateChirp(db.Chirp{UserID: userID, Body: body})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+
+	reader, cancel := openStream(t, server, "")
+	first := chirp("first")
+	event := readSSE(t, reader)
+	if event.Type != stream.EventCreated || event.ID == "" || !strings.Contains(event.Data, `"first"`) {
+		t.Fatalf("first event = %+v", event)
+	}
+	cancel()
+
+	second := chirp("second")
+	err := cfg.DB.DeleteChirp(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, cancel = openStream(t, server, event.ID)
+	defer cancel()
+	want := []struct {
+		eventType string
+		body      string
+	}{
+		{stream.EventCreated, `"second"`},
+		{stream.EventDeleted, `"chirp_id":1`},
+	}
+	for _, w := range want {
+		event := readSSE(t, reader)
+		if event.Type != w.eventType || !strings.Contains(event.Data, w.body) {
+			t.Errorf("resumed event = %+v, want %s containing %s", event, w.eventType, w.body)
+		}
+	}
+	if second.ID != 2 {
+		t.Fatalf("second chirp has ID %d, the deletion check assumes 2", second.ID)
+	}
+
+	// An ID from before a restart cannot be resumed from.
+	reader, cancel = openStream(t, server, "previousrun-1")
+	defer cancel()
+	if event := readSSE(t, reader); event.Type != "resync" {
+		t.Errorf("stale Last-Event-ID: first event %+v, want resync", event)
+	}
+}
+
+// pipeWriter is a flushable ResponseWriter whose writes block until the test
+// reads them, standing in for a client that reads slowly.
+type pipeWriter struct {
+	header http.Header
+	pipe   *io.PipeWriter
+}
+
+func (w *pipeWriter) Header() http.Header         { return w.header }
+func (w *pipeWriter) WriteHeader(int)             {}
+func (w *pipeWriter) Write(p []byte) (int, error) { return w.pipe.Write(p) }
+func (w *pipeWriter) Flush()                      {}
+
+// publishBurst sends more events than a subscriber with a buffer of one can
+// hold while it is blocked writing the first.
+func publishBurst(t *testing.T, cfg *ApiConfig, userID int) {
+	t.Helper()
+	for i := 0; i < 3; i++ {
+		chirp, err := cfg.DB.CreateChirp(db.Chirp{UserID: userID, Body: "burst"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Stream.ChirpSaved(chirp)
+	}
+}
+
+func TestStreamDropsSlowSSEClients(t *testing.T) {
+	cfg := newTestConfig(t)
+	userID, _ := newTestUser(t, cfg, "a@example.com")
+	cfg.Stream = stream.New(stream.Config{Backlog: 10, BufferSize: 1})
+
+	pipeReader, pipe := io.Pipe()
+	defer pipeReader.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest("GET", "/api/stream", nil).WithContext(ctx)
+	go func() {
+		cfg.GetStreamHandler(&pipeWriter{header: http.Header{}, pipe: pipe}, req)
+		pipe.Close()
+	}()
+	reader := bufio.NewReader(pipeReader)
+	readSSE(t, reader)
+
+	publishBurst(t, cfg, userID)
+	var last sseEvent
+	for {
+		_, err := reader.Peek(1)
+		if err != nil {
+			break
+		}
+		last = readSSE(t, reader)
+	}
+	if last.Type != "overflow" {
+		t.Errorf("last event = %+v, want overflow before the stream ends", last)
+	}
+}
+
+// hijackWriter hands the handler one end of an in-memory connection, so
+// nothing is buffered between the server and a client that is not reading.
+type hijackWriter struct {
+	header http.Header
+	conn   net.Conn
+}
+
+func (w *hijackWriter) Header() http.Header         { return w.header }
+func (w *hijackWriter) WriteHeader(int)             {}
+func (w *hijackWriter) Write(p []byte) (int, error) { return w.conn.Write(p) }
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
+
+// readFrame reads an unmasked frame sent by the server.
+func readFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	header := make([]byte, 2)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		t.Fatalf("reading frame: %s", err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(reader, extended)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+	payload := make([]byte, length)
+	if err == nil {
+		_, err = io.ReadFull(reader, payload)
+	}
+	if err != nil {
+		t.Fatalf("reading frame: %s", err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+func TestStreamClosesSlowWebSocketClients(t *testing.T) {
+	cfg := newTestConfig(t)
+	userID, _ := newTestUser(t, cfg, "a@example.com")
+	cfg.Stream = stream.New(stream.Config{Backlog: 10, BufferSize: 1})
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	// An unknown last_event_id makes the handler send resync once it has
+	// subscribed, which tells the test when to publish.
+	req := httptest.NewRequest("GET", "/api/stream/ws?last_event_id=unknown", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	go cfg.GetStreamWebSocketHandler(&hijackWriter{header: http.Header{}, conn: serverConn}, req)
+
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(clientConn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 101 || res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake: status %d, accept %q", res.StatusCode, res.Header.Get("Sec-WebSocket-Accept"))
+	}
+	if opcode, payload := readFrame(t, reader); opcode != 0x1 || !strings.Contains(string(payload), `"resync"`) {
+		t.Fatalf("first message = %x %s, want resync", opcode, payload)
+	}
+
+	publishBurst(t, cfg, userID)
+	for {
+		opcode, payload := readFrame(t, reader)
+		if opcode != 0x8 {
+			continue
+		}
+		if len(payload) < 2 || binary.BigEndian.Uint16(payload) != stream.ClosePolicy {
+			t.Errorf("close payload %x, want code %d", payload, stream.ClosePolicy)
+		}
+		return
+	}
+}
+
+// Deleted chirps carry only their author, so delete events must still respect
+// author filters, the timeline and blocks.
+func TestStreamFiltersDeleteEvents(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewerID, _ := newTestUser(t, cfg, "viewer@example.com")
+	followedID, _ := newTestUser(t, cfg, "followed@example.com")
+	strangerID, _ := newTestUser(t, cfg, "stranger@example.com")
+	blockedID, _ := newTestUser(t, cfg, "blocked@example.com")
+	err := cfg.DB.FollowUser(viewerID, followedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.DB.BlockUser(viewerID, blockedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := map[string]streamRequest{
+		"author filter": {filter: chirpFilter{authors: []int{followedID}}},
+		"timeline":      {viewerID: viewerID, timeline: true},
+		"signed in":     {viewerID: viewerID},
+	}
+	tests := []struct {
+		request string
+		author  int
+		want    bool
+	}{
+		{"author filter", followedID, true},
+		{"author filter", strangerID, false},
+		{"timeline", followedID, true},
+		{"timeline", viewerID, true},
+		{"timeline", strangerID, false},
+		{"signed in", strangerID, true},
+		{"signed in", blockedID, false},
+	}
+	for _, tt := range tests {
+		event := stream.Event{Type: stream.EventDeleted, ChirpID: 1, UserID: tt.author}
+		if _, ok := cfg.streamMessage(requests[tt.request], event); ok != tt.want {
+			t.Errorf("%s, deletion by user %d: sent = %v, want %v", tt.request, tt.author, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func (cfg *ApiConfig) GetSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// GET SUBSCRIPTION
+	subscription, err := cfg.DB.GetSubscription(id)
+	if errors.Is(err, db.ErrNoSubscription) {
+		subscription = db.Subscription{UserID: id, Plan: db.PlanFree, Status: "none"}
+	} else if err != nil {
+		log.Printf("Error Getting Subscription: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	type responseStruct struct {
+		db.Subscription
+		ActivePlan string `json:"active_plan"`
+		ChirpyRed  bool   `json:"is_chirpy_red"`
+	}
+	activePlan := subscription.ActivePlan(time.Now())
+	writeResponse(w, 200, responseStruct{
+		Subscription: subscription,
+		ActivePlan:   activePlan,
+		ChirpyRed:    activePlan != db.PlanFree,
+	})
+}
+
+// ExpireSubscriptions periodically lapses subscriptions that were not renewed.
+func (cfg *ApiConfig) ExpireSubscriptions(interval time.Duration) {
+	for range time.Tick(interval) {
+		changed, err := cfg.DB.ExpireSubscriptions(cfg.Subscriptions, time.Now())
+		if err != nil {
+			log.Printf("Error Expiring Subscriptions: %s", err)
+		} else if changed > 0 {
+			log.Printf("Updated %d lapsed subscriptions", changed)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package hdl
+
+import (
+	"net/http"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func (cfg *ApiConfig) GetTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// QUERY PARAMETERS
+	order := chirpOrder{name: "-id", key: chirpIDKey, desc: true}
+	cursor, limit, paginated, ok := parsePageParams(w, r, order)
+	if !ok {
+		return
+	}
+
+	// GET TIMELINE
+	chirpIDs := cfg.Timeline.Home(id)
+	chirps := make([]db.Chirp, 0, len(chirpIDs))
+	for _, chirpID := range chirpIDs {
+		if chirp, ok := cfg.DB.Chirps[chirpID]; ok && cfg.shows(chirp, id) {
+			chirps = append(chirps, chirp)
+		}
+	}
+
+	// RESPONSE
+	if !paginated {
+		writeResponse(w, 200, cfg.chirpViews(chirps, id))
+		return
+	}
+	cfg.writeChirpPage(w, r, order.paginate(chirps, cursor, limit), id)
+}
@@ -5,7 +5,7 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
 	"github.com/LoreviQ/PrivateWebServer/internal/db"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -53,9 +53,9 @@ func (cfg *ApiConfig) PostUserHandler(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *ApiConfig) PutUserHandler(w http.ResponseWriter, r *http.Request) {
 	// CHECKING AUTHENTICATION
-	id, err := auth.AuthenticateAccessToken(r, cfg.JWT_Secret)
+	id, err := cfg.authenticate(r, scopeAccount)
 	if err != nil {
-		writeError(w, 401, "Inavlid Token. Please log in again")
+		writeAuthError(w, err)
 		return
 	}
 
@@ -76,12 +76,19 @@ func (cfg *ApiConfig) PutUserHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		return
 	}
+	previous := cfg.DB.Users[id]
 	user, err := cfg.DB.UpdateUser(id, request.Email, hash)
 	if err != nil {
 		log.Printf("Error updating user: %s", err)
 		w.WriteHeader(500)
 		return
 	}
+	details := map[string]string{"password_changed": "true"}
+	if previous.Email != user.Email {
+		details["old_email"] = previous.Email
+		details["new_email"] = user.Email
+	}
+	cfg.recordEvent(r, eventAccountUpdate, id, audit.Success, details)
 
 	// RESPONSE
 	type responseStruct struct {
@@ -0,0 +1,270 @@
+package hdl
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/webauthn"
+)
+
+const webauthnTimeoutMillis = 300000
+
+type credentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func (cfg *ApiConfig) PostWebAuthnRegisterBeginHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	user, ok := cfg.DB.Users[id]
+	if !ok {
+		writeError(w, 404, "User not found")
+		return
+	}
+
+	// CREATE CHALLENGE
+	challenge, err := cfg.WebAuthn.BeginRegistration(id)
+	if err != nil {
+		log.Printf("Error Creating WebAuthn Challenge: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	type rpStruct struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	type userStruct struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	}
+	type paramStruct struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	}
+	type selectionStruct struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	}
+	type optionsStruct struct {
+		Challenge              string                 `json:"challenge"`
+		RP                     rpStruct               `json:"rp"`
+		User                   userStruct             `json:"user"`
+		PubKeyCredParams       []paramStruct          `json:"pubKeyCredParams"`
+		Timeout                int                    `json:"timeout"`
+		Attestation            string                 `json:"attestation"`
+		ExcludeCredentials     []credentialDescriptor `json:"excludeCredentials"`
+		AuthenticatorSelection selectionStruct        `json:"authenticatorSelection"`
+	}
+	type responseStruct struct {
+		PublicKey optionsStruct `json:"publicKey"`
+	}
+	params := []paramStruct{}
+	for _, alg := range webauthn.SupportedAlgorithms {
+		params = append(params, paramStruct{Type: "public-key", Alg: alg})
+	}
+	name := user.Email
+	if name == "" {
+		name = "user-" + strconv.Itoa(user.ID)
+	}
+	rp := cfg.WebAuthn.Config()
+	writeResponse(w, 200, responseStruct{
+		PublicKey: optionsStruct{
+			Challenge: challenge,
+			RP:        rpStruct{ID: rp.RPID, Name: rp.RPName},
+			User: userStruct{
+				ID:          webauthn.Encode([]byte(strconv.Itoa(user.ID))),
+				Name:        name,
+				DisplayName: name,
+			},
+			PubKeyCredParams:   params,
+			Timeout:            webauthnTimeoutMillis,
+			Attestation:        "none",
+			ExcludeCredentials: credentialDescriptors(user.Credentials),
+			AuthenticatorSelection: selectionStruct{
+				ResidentKey:      "preferred",
+				UserVerification: "preferred",
+			},
+		},
+	})
+}
+
+func (cfg *ApiConfig) PostWebAuthnRegisterFinishHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	// REQUEST
+	type requestStruct struct {
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON"`
+			AttestationObject string `json:"attestationObject"`
+		} `json:"response"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	clientData, err1 := webauthn.Decode(request.Response.ClientDataJSON)
+	attestation, err2 := webauthn.Decode(request.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		writeError(w, 400, "Invalid credential encoding")
+		return
+	}
+
+	// VERIFY CREDENTIAL
+	credential, err := cfg.WebAuthn.FinishRegistration(id, clientData, attestation)
+	if err != nil {
+		writeError(w, 400, "Could not verify credential: "+err.Error())
+		return
+	}
+	err = cfg.DB.AddCredential(id, db.WebAuthnCredential{
+		ID:        credential.ID,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+	})
+	if errors.Is(err, db.ErrCredentialExists) {
+		writeError(w, 400, "This credential is already registered")
+		return
+	} else if err != nil {
+		log.Printf("Error Storing Credential: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventPasskeyAdded, id, audit.Success, nil)
+
+	// RESPONSE
+	writeResponse(w, 201, credentialDescriptor{
+		Type: "public-key",
+		ID:   webauthn.Encode(credential.ID),
+	})
+}
+
+func (cfg *ApiConfig) PostWebAuthnLoginBeginHandler(w http.ResponseWriter, r *http.Request) {
+	// REQUEST
+	type requestStruct struct {
+		Email string `json:"email"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+
+	// Without an email the client must use a discoverable credential.
+	userID := 0
+	allowCredentials := []credentialDescriptor{}
+	if request.Email != "" {
+		user, err := cfg.DB.GetUserByEmail(request.Email)
+		if err != nil || len(user.Credentials) == 0 {
+			writeError(w, 404, "No passkeys registered for this email")
+			return
+		}
+		userID = user.ID
+		allowCredentials = credentialDescriptors(user.Credentials)
+	}
+
+	// CREATE CHALLENGE
+	challenge, err := cfg.WebAuthn.BeginLogin(userID)
+	if err != nil {
+		log.Printf("Error Creating WebAuthn Challenge: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// RESPONSE
+	type optionsStruct struct {
+		Challenge        string                 `json:"challenge"`
+		RPID             string                 `json:"rpId"`
+		Timeout          int                    `json:"timeout"`
+		AllowCredentials []credentialDescriptor `json:"allowCredentials"`
+		UserVerification string                 `json:"userVerification"`
+	}
+	type responseStruct struct {
+		PublicKey optionsStruct `json:"publicKey"`
+	}
+	writeResponse(w, 200, responseStruct{
+		PublicKey: optionsStruct{
+			Challenge:        challenge,
+			RPID:             cfg.WebAuthn.Config().RPID,
+			Timeout:          webauthnTimeoutMillis,
+			AllowCredentials: allowCredentials,
+			UserVerification: "preferred",
+		},
+	})
+}
+
+func (cfg *ApiConfig) PostWebAuthnLoginFinishHandler(w http.ResponseWriter, r *http.Request) {
+	// REQUEST
+	type requestStruct struct {
+		RawID    string `json:"rawId"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON"`
+			AuthenticatorData string `json:"authenticatorData"`
+			Signature         string `json:"signature"`
+		} `json:"response"`
+	}
+	request, err := decodeRequest(w, r, requestStruct{})
+	if err != nil {
+		return
+	}
+	credentialID, err1 := webauthn.Decode(request.RawID)
+	clientData, err2 := webauthn.Decode(request.Response.ClientDataJSON)
+	authData, err3 := webauthn.Decode(request.Response.AuthenticatorData)
+	signature, err4 := webauthn.Decode(request.Response.Signature)
+	if errors.Join(err1, err2, err3, err4) != nil {
+		writeError(w, 400, "Invalid assertion encoding")
+		return
+	}
+
+	// AUTHENTICATE USER
+	user, stored, err := cfg.DB.GetUserByCredential(credentialID)
+	if err != nil {
+		cfg.recordEvent(r, eventLogin, 0, audit.Failure, map[string]string{"method": "passkey", "reason": "unknown credential"})
+		writeError(w, 401, "Unknown credential")
+		return
+	}
+	signCount, err := cfg.WebAuthn.FinishLogin(user.ID, webauthn.Credential{
+		ID:        stored.ID,
+		PublicKey: stored.PublicKey,
+		SignCount: stored.SignCount,
+	}, clientData, authData, signature)
+	if err != nil {
+		cfg.recordEvent(r, eventLogin, user.ID, audit.Failure, map[string]string{"method": "passkey", "reason": err.Error()})
+		writeError(w, 401, "Could not verify assertion: "+err.Error())
+		return
+	}
+	err = cfg.DB.UpdateCredentialCounter(user.ID, stored.ID, signCount)
+	if err != nil {
+		log.Printf("Error Updating Credential: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEvent(r, eventLogin, user.ID, audit.Success, map[string]string{"method": "passkey"})
+
+	cfg.writeLoginResponse(w, user)
+}
+
+func credentialDescriptors(credentials []db.WebAuthnCredential) []credentialDescriptor {
+	descriptors := []credentialDescriptor{}
+	for _, c := range credentials {
+		descriptors = append(descriptors, credentialDescriptor{
+			Type: "public-key",
+			ID:   webauthn.Encode(c.ID),
+		})
+	}
+	return descriptors
+}
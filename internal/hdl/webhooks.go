@@ -1,47 +1,143 @@
 package hdl
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
-	"os"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
 	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+const (
+	webhookTolerance   = 5 * time.Minute
+	maxWebhookBodySize = 1 << 20
+	eventPolkaWebhook  = "polka_webhook"
 )
 
 func (cfg *ApiConfig) PostPolkaWebhook(w http.ResponseWriter, r *http.Request) {
+	// AUTHENTICATION
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		writeError(w, 400, "Could not read body")
+		return
+	}
+	err = auth.VerifyWebhook(r.Header.Get("Polka-Signature"), body, cfg.Polka_Secret, webhookTolerance, time.Now())
+	if err != nil {
+		cfg.recordEvent(r, eventPolkaWebhook, 0, audit.Failure, map[string]string{"reason": err.Error()})
+		w.WriteHeader(401)
+		return
+	}
+
 	// REQUEST
 	type requestStruct struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID int `json:"user_id"`
+		ID        string    `json:"id"`
+		Event     string    `json:"event"`
+		CreatedAt time.Time `json:"created_at"`
+		Data      struct {
+			UserID    int       `json:"user_id"`
+			Plan      string    `json:"plan"`
+			PeriodEnd time.Time `json:"period_end"`
 		} `json:"data"`
 	}
-	request, err := decodeRequest(w, r, requestStruct{})
-	if err != nil {
-		log.Printf("Error decoding Request: %s", err)
-		w.WriteHeader(500)
+	request := requestStruct{}
+	err = json.Unmarshal(body, &request)
+	if err != nil || request.ID == "" {
+		writeError(w, 400, "Invalid webhook payload")
 		return
 	}
 
-	// AUTHENTICATION
-	err = auth.AuthenticateAPI(r, os.Getenv("POLKA_API_KEY"))
+	// IDEMPOTENCY
+	claimed, err := cfg.DB.ClaimWebhook(db.Webhook{
+		ID:      request.ID,
+		Event:   request.Event,
+		UserID:  request.Data.UserID,
+		Payload: body,
+	}, time.Now())
 	if err != nil {
-		w.WriteHeader(401)
+		log.Printf("Error Recording Webhook: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !claimed {
+		w.WriteHeader(200)
 		return
 	}
 
-	// UPDATING USER TO CHIRPY RED
-	if request.Event != "user.upgraded" {
+	// UPDATING SUBSCRIPTION
+	details := map[string]string{"source": "polka", "webhook_id": request.ID, "event": request.Event}
+	subscription, applied, err := cfg.DB.ApplySubscriptionEvent(db.SubscriptionEvent{
+		Type:      request.Event,
+		UserID:    request.Data.UserID,
+		Plan:      request.Data.Plan,
+		PeriodEnd: request.Data.PeriodEnd,
+		CreatedAt: request.CreatedAt,
+	}, cfg.Subscriptions, time.Now())
+	if errors.Is(err, db.ErrUnknownSubscriptionEvent) {
+		cfg.completeWebhook(request.ID, db.WebhookIgnored, nil)
 		w.WriteHeader(200)
 		return
+	} else if err != nil {
+		cfg.completeWebhook(request.ID, db.WebhookFailed, err)
+		details["reason"] = err.Error()
+		cfg.recordEvent(r, eventSubscription, request.Data.UserID, audit.Failure, details)
+		if errors.Is(err, db.ErrInvalidUserID) {
+			writeError(w, 404, "No user with ID "+strconv.Itoa(request.Data.UserID))
+			return
+		}
+		w.WriteHeader(500)
+		return
+	}
+	if !applied {
+		cfg.completeWebhook(request.ID, db.WebhookIgnored, errors.New("stale event"))
+		w.WriteHeader(200)
+		return
+	}
+	cfg.completeWebhook(request.ID, db.WebhookProcessed, nil)
+	details["status"] = subscription.Status
+	details["plan"] = subscription.Plan
+	cfg.recordEvent(r, eventSubscription, request.Data.UserID, audit.Success, details)
+
+	// RESPONSE
+	w.WriteHeader(200)
+}
+
+func (cfg *ApiConfig) completeWebhook(id, status string, processingErr error) {
+	errorText := ""
+	if processingErr != nil {
+		errorText = processingErr.Error()
 	}
+	err := cfg.DB.CompleteWebhook(id, status, errorText)
+	if err != nil {
+		log.Printf("Error Recording Webhook: %s", err)
+	}
+}
 
-	err = cfg.DB.AddChirpyRed(request.Data.UserID)
+func (cfg *ApiConfig) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	// CHECKING AUTHENTICATION
+	id, err := cfg.authenticate(r, scopeAccount)
 	if err != nil {
-		w.WriteHeader(404)
+		writeAuthError(w, err)
+		return
+	}
+	if !cfg.isAdmin(id) {
+		writeError(w, 403, "Admin access required")
 		return
 	}
 
+	// QUERY PARAMETERS
+	status := r.URL.Query().Get("status")
+
 	// RESPONSE
-	w.WriteHeader(200)
+	webhooks := cfg.DB.ListWebhooks(status)
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ReceivedAt.After(webhooks[j].ReceivedAt)
+	})
+	writeResponse(w, 200, webhooks)
 }
@@ -0,0 +1,51 @@
+package moderation
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://)?((?:[a-z0-9-]+\.)+[a-z]{2,})(?:[/:?#]\S*)?`)
+
+// LinkBlocklist matches links in the text or media to blocked domains and
+// their subdomains.
+type LinkBlocklist struct {
+	domains []string
+	action  Action
+}
+
+func NewLinkBlocklist(domains []string, action Action) *LinkBlocklist {
+	blocklist := &LinkBlocklist{action: action}
+	for _, domain := range domains {
+		blocklist.domains = append(blocklist.domains, strings.ToLower(strings.TrimPrefix(domain, ".")))
+	}
+	return blocklist
+}
+
+func (b *LinkBlocklist) Apply(content *Content) (Action, []string) {
+	hosts := []string{}
+	for _, match := range linkPattern.FindAllStringSubmatch(content.Text, -1) {
+		hosts = append(hosts, match[1])
+	}
+	for _, media := range content.Media {
+		if mediaURL, err := url.Parse(media); err == nil {
+			hosts = append(hosts, mediaURL.Hostname())
+		}
+	}
+	for _, host := range hosts {
+		if b.blocked(strings.ToLower(host)) {
+			return b.action, []string{"links to a blocked site"}
+		}
+	}
+	return "", nil
+}
+
+func (b *LinkBlocklist) blocked(host string) bool {
+	for _, domain := range b.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Action is what a filter does with content that matches it.
+type Action string
+
+const (
+	Mask   Action = "mask"
+	Flag   Action = "flag"
+	Reject Action = "reject"
+)
+
+// Content is what gets moderated: the chirp body, which filters may mask, and
+// its media URLs.
+type Content struct {
+	Text  string
+	Media []string
+}
+
+// Decision is the outcome of running content through the pipeline. Text is
+// the body with masks applied. Rejected content must not be stored; flagged
+// content is stored and queued for review.
+type Decision struct {
+	Text     string
+	Rejected bool
+	Flagged  bool
+	Reasons  []string
+}
+
+type Filter interface {
+	Apply(content *Content) (Action, []string)
+}
+
+// Reloader is implemented by filters backed by files that can change while
+// the server runs.
+type Reloader interface {
+	Reload() error
+}
+
+type Pipeline struct {
+	filters []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// Moderate runs every filter in order. Masks from earlier filters are seen by
+// later ones, and a rejection stops the pipeline.
+func (p *Pipeline) Moderate(text string, media []string) Decision {
+	content := Content{Text: text, Media: media}
+	decision := Decision{Reasons: []string{}}
+	for _, filter := range p.filters {
+		action, reasons := filter.Apply(&content)
+		switch action {
+		case Reject:
+			decision.Rejected = true
+			decision.Reasons = append(decision.Reasons, reasons...)
+			decision.Text = content.Text
+			return decision
+		case Flag:
+			decision.Flagged = true
+			decision.Reasons = append(decision.Reasons, reasons...)
+		}
+	}
+	decision.Text = content.Text
+	return decision
+}
+
+// Watch reloads file backed filters every interval, logging failures through
+// report. It never returns.
+func (p *Pipeline) Watch(interval time.Duration, report func(error)) {
+	for range time.Tick(interval) {
+		for _, filter := range p.filters {
+			if reloader, ok := filter.(Reloader); ok {
+				err := reloader.Reload()
+				if err != nil {
+					report(err)
+				}
+			}
+		}
+	}
+}
+
+type config struct {
+	Filters []filterConfig `json:"filters"`
+}
+
+type filterConfig struct {
+	Type      string      `json:"type"`
+	Action    Action      `json:"action"`
+	Path      string      `json:"path"`
+	Rules     []RegexRule `json:"rules"`
+	Blocklist []string    `json:"blocklist"`
+}
+
+// Load builds a pipeline from a JSON config file. Word list paths are relative
+// to the config file.
+func Load(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := config{}
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []Filter{}
+	for i, fc := range cfg.Filters {
+		var filter Filter
+		switch fc.Type {
+		case "wordlist":
+			if !validAction(fc.Action) {
+				return nil, fmt.Errorf("filter %d: invalid action %q", i, fc.Action)
+			}
+			filter, err = NewWordList(filepath.Join(filepath.Dir(path), fc.Path), fc.Action)
+		case "regex":
+			filter, err = NewRegexFilter(fc.Rules)
+		case "links":
+			if !validAction(fc.Action) || fc.Action == Mask {
+				return nil, fmt.Errorf("filter %d: links filters must flag or reject", i)
+			}
+			filter = NewLinkBlocklist(fc.Blocklist, fc.Action)
+		default:
+			return nil, fmt.Errorf("filter %d: unknown type %q", i, fc.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("filter %d: %w", i, err)
+		}
+		filters = append(filters, filter)
+	}
+	return NewPipeline(filters...), nil
+}
+
+func validAction(action Action) bool {
+	return action == Mask || action == Flag || action == Reject
+}
+
+// strongest returns the more severe of two actions; "" means no match.
+func strongest(a, b Action) Action {
+	rank := map[Action]int{"": 0, Mask: 1, Flag: 2, Reject: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
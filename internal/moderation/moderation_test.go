@@ -0,0 +1,194 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNormalise(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Fornax", "fornax"},
+		{"f0rn4x", "fornax"},
+		{"FORNAAAAX", "fornaax"},
+		{"fórnåx", "fornax"},
+		{"Ｆｏｒｎａｘ", "fornax"},
+		{"fоrnax", "fornax"}, // Cyrillic о
+		{"for\u200bnax", "fornax"},
+		{"fornax\u0301", "fornax"},
+		{"$h!t", "shit"},
+		{"book", "book"},
+		{"boooook", "book"},
+	}
+	for _, tt := range tests {
+		if got := Normalise(tt.in); got != tt.want {
+			t.Errorf("Normalise(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func writeWordList(t *testing.T, path, contents string) {
+	t.Helper()
+	err := os.WriteFile(path, []byte(contents), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestWordList(t *testing.T, action Action) *WordList {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "words.txt")
+	writeWordList(t, path, "# blocked words\nfornax\n\nkerfuffle\n")
+	list, err := NewWordList(path, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestWordListMasks(t *testing.T) {
+	list := newTestWordList(t, Mask)
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"a clean chirp", "a clean chirp"},
+		{"what the fornax", "what the ****"},
+		{"FORNAX!", "****!"},
+		{"(f0rn4x)", "(****)"},
+		{"f.o.r.n.a.x  kerfuffle", "****  ****"},
+		{"fornaxes", "fornaxes"},
+		{"# blocked words", "# blocked words"},
+	}
+	for _, tt := range tests {
+		content := Content{Text: tt.in}
+		action, _ := list.Apply(&content)
+		if content.Text != tt.want {
+			t.Errorf("Apply(%q) text = %q, want %q", tt.in, content.Text, tt.want)
+		}
+		if matched := tt.in != tt.want; matched != (action == Mask) {
+			t.Errorf("Apply(%q) action = %q", tt.in, action)
+		}
+	}
+}
+
+// Double letters in the list must not be lost, or "ass" would also match "as".
+func TestWordListKeepsDoubleLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	writeWordList(t, path, "ass\nfornax\n")
+	list, err := NewWordList(path, Reject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in      string
+		matched bool
+	}{
+		{"as", false},
+		{"pass", false},
+		{"class", false},
+		{"ass", true},
+		{"AAASSSS", true},
+		{"a.s.s", true},
+		{"fornnax", true},
+		{"ffoorrnnaaxx", true},
+	}
+	for _, tt := range tests {
+		action, _ := list.Apply(&Content{Text: tt.in})
+		if matched := action == Reject; matched != tt.matched {
+			t.Errorf("Apply(%q) matched = %v, want %v", tt.in, matched, tt.matched)
+		}
+	}
+}
+
+func TestWordListFlagLeavesTextAlone(t *testing.T) {
+	list := newTestWordList(t, Flag)
+	content := Content{Text: "what the fornax"}
+	action, reasons := list.Apply(&content)
+	if action != Flag || len(reasons) != 1 {
+		t.Errorf("Apply = %q %v, want flag with a reason", action, reasons)
+	}
+	if content.Text != "what the fornax" {
+		t.Errorf("flagging changed the text to %q", content.Text)
+	}
+}
+
+func TestWordListReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	writeWordList(t, path, "fornax\n")
+	list, err := NewWordList(path, Reject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeWordList(t, path, "kerfuffle\n")
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if action, _ := list.Apply(&Content{Text: "fornax"}); action != "" {
+		t.Error("word removed from the file still matches after Reload")
+	}
+	if action, _ := list.Apply(&Content{Text: "kerfuffle"}); action != Reject {
+		t.Error("word added to the file does not match after Reload")
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	rules, err := NewRegexFilter([]RegexRule{{Pattern: `(?i)\bbuy followers\b`, Action: Flag, Reason: "spam"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline := NewPipeline(
+		newTestWordList(t, Mask),
+		rules,
+		NewLinkBlocklist([]string{"evil.example"}, Reject),
+	)
+
+	decision := pipeline.Moderate("fornax, buy followers", nil)
+	if decision.Text != "****, buy followers" || !decision.Flagged || decision.Rejected {
+		t.Errorf("Moderate = %+v, want masked and flagged", decision)
+	}
+	if !slices.Equal(decision.Reasons, []string{"spam"}) {
+		t.Errorf("reasons = %v, want [spam]", decision.Reasons)
+	}
+
+	decision = pipeline.Moderate("hello", []string{"https://cdn.evil.example/cat.png"})
+	if !decision.Rejected {
+		t.Errorf("media on a blocked subdomain was not rejected: %+v", decision)
+	}
+	decision = pipeline.Moderate("see notevil.example", nil)
+	if decision.Rejected {
+		t.Error("a domain sharing a suffix with a blocked one was rejected")
+	}
+}
+
+func TestLoadRejectsBadConfig(t *testing.T) {
+	tests := map[string]string{
+		"unknown type":    `{"filters": [{"type": "nope"}]}`,
+		"bad action":      `{"filters": [{"type": "wordlist", "path": "words.txt", "action": "delete"}]}`,
+		"masking links":   `{"filters": [{"type": "links", "action": "mask"}]}`,
+		"missing list":    `{"filters": [{"type": "wordlist", "path": "missing.txt", "action": "mask"}]}`,
+		"malformed JSON":  `{"filters": [`,
+		"invalid pattern": `{"filters": [{"type": "regex", "rules": [{"pattern": "(", "action": "flag"}]}]}`,
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeWordList(t, filepath.Join(dir, "words.txt"), "fornax\n")
+			path := filepath.Join(dir, "moderation.json")
+			writeWordList(t, path, config)
+			if _, err := Load(path); err == nil {
+				t.Error("Load accepted a bad config")
+			}
+		})
+	}
+}
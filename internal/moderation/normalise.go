@@ -0,0 +1,74 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't',
+}
+
+// folds maps accented and look-alike letters to their plain Latin form.
+var folds = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'α': 'a', 'а': 'a',
+	'ç': 'c', 'с': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'е': 'e', 'ε': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ı': 'i', 'і': 'i',
+	'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ο': 'o', 'о': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y', 'у': 'y',
+	'х': 'x', 'р': 'p', 'к': 'k', 'ѕ': 's',
+}
+
+// Normalise reduces a word to a canonical form for matching: lower case,
+// full-width and accented letters folded to ASCII, leet-speak decoded,
+// invisible characters dropped and runs of a letter cut to two. Runs are kept
+// at two rather than one so that words spelt with a double letter stay
+// distinct from those without; "ass" must not become "as".
+func Normalise(word string) string {
+	var b strings.Builder
+	var last rune
+	run := 0
+	for _, r := range strings.ToLower(word) {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r = unicode.ToLower(r - 0xFF01 + '!')
+		}
+		if folded, ok := folds[r]; ok {
+			r = folded
+		}
+		if decoded, ok := leet[r]; ok {
+			r = decoded
+		}
+		if unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if r == last {
+			run++
+		} else {
+			run = 1
+		}
+		if run > 2 && unicode.IsLetter(r) {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return b.String()
+}
+
+// collapseRepeats drops every repeated letter, so "fornnax" becomes "fornax".
+func collapseRepeats(word string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range word {
+		if r == last && unicode.IsLetter(r) {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return b.String()
+}
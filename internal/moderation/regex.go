@@ -0,0 +1,52 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type RegexRule struct {
+	Pattern string `json:"pattern"`
+	Action  Action `json:"action"`
+	Reason  string `json:"reason"`
+}
+
+// RegexFilter applies each matching rule; the most severe action wins.
+type RegexFilter struct {
+	rules    []RegexRule
+	patterns []*regexp.Regexp
+}
+
+func NewRegexFilter(rules []RegexRule) (*RegexFilter, error) {
+	filter := &RegexFilter{rules: rules}
+	for _, rule := range rules {
+		if !validAction(rule.Action) {
+			return nil, fmt.Errorf("rule %q: invalid action %q", rule.Pattern, rule.Action)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		filter.patterns = append(filter.patterns, pattern)
+	}
+	return filter, nil
+}
+
+func (f *RegexFilter) Apply(content *Content) (Action, []string) {
+	var action Action
+	reasons := []string{}
+	for i, pattern := range f.patterns {
+		rule := f.rules[i]
+		if !pattern.MatchString(content.Text) {
+			continue
+		}
+		action = strongest(action, rule.Action)
+		if rule.Action == Mask {
+			content.Text = pattern.ReplaceAllLiteralString(content.Text, maskText)
+		}
+		if rule.Reason != "" {
+			reasons = append(reasons, rule.Reason)
+		}
+	}
+	return action, reasons
+}
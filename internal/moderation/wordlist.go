@@ -0,0 +1,175 @@
+package moderation
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maskText = "****"
+
+// WordList matches words from a file, one per line, after normalising case,
+// look-alike characters and leet-speak. Lines starting with # are comments.
+type WordList struct {
+	path    string
+	action  Action
+	mu      *sync.RWMutex
+	words   map[string][]string // normalised words keyed by collapseRepeats
+	modTime time.Time
+}
+
+func NewWordList(path string, action Action) (*WordList, error) {
+	list := &WordList{
+		path:   path,
+		action: action,
+		mu:     &sync.RWMutex{},
+	}
+	err := list.load()
+	return list, err
+}
+
+// Reload rereads the file if it has changed since it was last loaded.
+func (l *WordList) Reload() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return err
+	}
+	l.mu.RLock()
+	unchanged := info.ModTime().Equal(l.modTime)
+	l.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	return l.load()
+}
+
+func (l *WordList) load() error {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	words := map[string][]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word := Normalise(line)
+		key := collapseRepeats(word)
+		words[key] = append(words[key], word)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.words = words
+	l.modTime = info.ModTime()
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *WordList) Apply(content *Content) (Action, []string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	matched := false
+	var masked strings.Builder
+	text := content.Text
+	for text != "" {
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end == -1 {
+			end = len(text)
+		} else if end == 0 {
+			_, size := utf8.DecodeRuneInString(text)
+			masked.WriteString(text[:size])
+			text = text[size:]
+			continue
+		}
+		field := text[:end]
+		text = text[end:]
+
+		// Match the word without surrounding punctuation, so "fornax!" is
+		// caught, falling back to the whole field for words like "$hit".
+		core := strings.TrimFunc(field, isPunctuation)
+		switch {
+		case core != "" && l.matches(core):
+			matched = true
+			field = strings.Replace(field, core, maskText, 1)
+		case l.matches(field):
+			matched = true
+			field = maskText
+		}
+		masked.WriteString(field)
+	}
+	if !matched {
+		return "", nil
+	}
+	if l.action == Mask {
+		content.Text = masked.String()
+	}
+	return l.action, []string{"contains a blocked word"}
+}
+
+// matches checks a word as written and with the punctuation inside it
+// removed, so f.o.r.n.a.x is caught too. A word matches a listed one if it
+// repeats each letter at least as often, catching padding like "fornnax"
+// without letting "ass" match "as".
+func (l *WordList) matches(word string) bool {
+	normalised := Normalise(word)
+	letters := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, normalised)
+	for _, candidate := range []string{normalised, letters} {
+		for _, listed := range l.words[collapseRepeats(candidate)] {
+			if repeatsAtLeast(candidate, listed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// repeatsAtLeast reports whether each run of a letter in word is at least as
+// long as the matching run in listed. Both must collapse to the same string.
+func repeatsAtLeast(word, listed string) bool {
+	wordRuns, listedRuns := runLengths(word), runLengths(listed)
+	for i := range wordRuns {
+		if wordRuns[i] < listedRuns[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func runLengths(word string) []int {
+	runs := []int{}
+	var last rune
+	for _, r := range word {
+		if r == last && unicode.IsLetter(r) {
+			runs[len(runs)-1]++
+			continue
+		}
+		runs = append(runs, 1)
+		last = r
+	}
+	return runs
+}
+
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,306 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownState = errors.New("unknown or expired login state")
+
+const sessionLifetime = 10 * time.Minute
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+type session struct {
+	nonce    string
+	verifier string
+	expires  time.Time
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is a relying party for a single OpenID Connect identity provider.
+// Discovery is performed lazily so the server can start before the IdP.
+type Provider struct {
+	cfg      Config
+	client   *http.Client
+	meta     *discovery
+	keys     map[string]*rsa.PublicKey
+	sessions map[string]session
+	mu       *sync.Mutex
+}
+
+func NewProvider(cfg Config) *Provider {
+	return &Provider{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     make(map[string]*rsa.PublicKey),
+		sessions: make(map[string]session),
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (p *Provider) Issuer() string {
+	return p.cfg.Issuer
+}
+
+// AuthCodeURL starts a login, returning the URL the user agent should be sent
+// to and the state the callback must present. Callers should bind the state to
+// the user agent so a login cannot be completed in a different browser.
+func (p *Provider) AuthCodeURL() (string, string, error) {
+	meta, err := p.discover()
+	if err != nil {
+		return "", "", err
+	}
+	state, err := auth.RandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := auth.RandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := auth.NewPKCEVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	for key, s := range p.sessions {
+		if now.After(s.expires) {
+			delete(p.sessions, key)
+		}
+	}
+	p.sessions[state] = session{
+		nonce:    nonce,
+		verifier: verifier,
+		expires:  now.Add(sessionLifetime),
+	}
+	p.mu.Unlock()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {"openid email"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {auth.PKCEChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + query.Encode(), state, nil
+}
+
+// Exchange completes a login started by AuthCodeURL, returning the verified ID token claims.
+func (p *Provider) Exchange(state, code string) (Claims, error) {
+	p.mu.Lock()
+	s, ok := p.sessions[state]
+	delete(p.sessions, state)
+	p.mu.Unlock()
+	if !ok || time.Now().After(s.expires) {
+		return Claims{}, ErrUnknownState
+	}
+
+	meta, err := p.discover()
+	if err != nil {
+		return Claims{}, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {s.verifier},
+	}
+	req, err := http.NewRequest("POST", meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Claims{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return Claims{}, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tokenResponse)
+	if err != nil {
+		return Claims{}, err
+	}
+	if tokenResponse.IDToken == "" {
+		return Claims{}, errors.New("token response has no id_token")
+	}
+	return p.verify(tokenResponse.IDToken, s.nonce)
+}
+
+func (p *Provider) verify(rawIDToken, nonce string) (Claims, error) {
+	claims := Claims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, &claims, p.keyFunc,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Claims{}, err
+	}
+	if claims.Nonce != nonce {
+		return Claims{}, errors.New("id token nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return Claims{}, errors.New("id token has no subject")
+	}
+	return claims, nil
+}
+
+func (p *Provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	// Unknown key IDs are expected after the IdP rotates keys, so refetch once.
+	err := p.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok = p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no signing key with id %q", kid)
+	}
+	return key, nil
+}
+
+func (p *Provider) discover() (*discovery, error) {
+	p.mu.Lock()
+	meta := p.meta
+	p.mu.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+
+	resp, err := p.client.Get(strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("discovery returned %s", resp.Status)
+	}
+	meta = &discovery{}
+	err = json.NewDecoder(resp.Body).Decode(meta)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	p.mu.Lock()
+	p.meta = meta
+	p.mu.Unlock()
+	return meta, nil
+}
+
+func (p *Provider) fetchKeys() error {
+	meta, err := p.discover()
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Get(meta.JWKSURI)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("jwks endpoint returned %s", resp.Status)
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return err
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,236 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is a minimal OpenID Connect provider. Codes it issues remember the
+// PKCE challenge and nonce of the authorization request that created them.
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims func(nonce string) Claims
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: make(map[string]authorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discovery{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		authz, ok := idp.codes[r.FormValue("code")]
+		delete(idp.codes, r.FormValue("code"))
+		idp.mu.Unlock()
+		if !ok || !auth.VerifyPKCE(r.FormValue("code_verifier"), authz.challenge) {
+			w.WriteHeader(400)
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims(authz.nonce))
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	idp.claims = func(nonce string) Claims {
+		return idp.validClaims(nonce)
+	}
+	return idp
+}
+
+func (idp *mockIdP) validClaims(nonce string) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    idp.server.URL,
+			Subject:   "alice",
+			Audience:  jwt.ClaimStrings{"chirpy"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		Nonce:         nonce,
+		Email:         "alice@example.com",
+		EmailVerified: true,
+	}
+}
+
+// authorize plays the user approving the login at the authorization URL,
+// returning the code the IdP would redirect back with.
+func (idp *mockIdP) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+	code, err := auth.RandomString(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.codes[code] = authorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+	return code
+}
+
+func newTestProvider(idp *mockIdP) *Provider {
+	return NewProvider(Config{
+		Issuer:      idp.server.URL,
+		ClientID:    "chirpy",
+		RedirectURL: "http://localhost/api/oidc/callback",
+	})
+}
+
+func TestLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(idp)
+
+	authURL, state, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := idp.authorize(t, authURL)
+	claims, err := provider.Exchange(state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "alice" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	_, err = provider.Exchange(state, code)
+	if !errors.Is(err, ErrUnknownState) {
+		t.Errorf("reusing state: err = %v, want ErrUnknownState", err)
+	}
+}
+
+func TestLoginRejectsBadTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(idp *mockIdP, nonce string) Claims
+	}{
+		{"wrong nonce", func(idp *mockIdP, nonce string) Claims {
+			claims := idp.validClaims(nonce)
+			claims.Nonce = "replayed"
+			return claims
+		}},
+		{"wrong audience", func(idp *mockIdP, nonce string) Claims {
+			claims := idp.validClaims(nonce)
+			claims.Audience = jwt.ClaimStrings{"someone-else"}
+			return claims
+		}},
+		{"wrong issuer", func(idp *mockIdP, nonce string) Claims {
+			claims := idp.validClaims(nonce)
+			claims.Issuer = "https://evil.example"
+			return claims
+		}},
+		{"expired", func(idp *mockIdP, nonce string) Claims {
+			claims := idp.validClaims(nonce)
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			return claims
+		}},
+		{"no subject", func(idp *mockIdP, nonce string) Claims {
+			claims := idp.validClaims(nonce)
+			claims.Subject = ""
+			return claims
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.claims = func(nonce string) Claims {
+				return tt.claims(idp, nonce)
+			}
+			provider := newTestProvider(idp)
+			authURL, state, err := provider.AuthCodeURL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = provider.Exchange(state, idp.authorize(t, authURL))
+			if err == nil {
+				t.Error("Exchange accepted a bad ID token")
+			}
+		})
+	}
+}
+
+func TestLoginRejectsUnknownState(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(idp)
+	authURL, _, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.Exchange("forged", idp.authorize(t, authURL))
+	if !errors.Is(err, ErrUnknownState) {
+		t.Errorf("err = %v, want ErrUnknownState", err)
+	}
+}
+
+// The code is bound to the PKCE challenge of the login that requested it, so
+// it cannot be redeemed through a different login session.
+func TestLoginRejectsCodeFromOtherSession(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(idp)
+	authURL, _, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := idp.authorize(t, authURL)
+	_, otherState, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.Exchange(otherState, code)
+	if err == nil {
+		t.Error("Exchange redeemed a code issued to another session")
+	}
+}
@@ -0,0 +1,201 @@
+package polkasim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+)
+
+// Simulator plays the part of Polka, the payment provider, against a running
+// Chirpy server: it creates checkouts and delivers signed webhook events.
+type Simulator struct {
+	ServerURL string
+	Secret    []byte
+	client    *http.Client
+	seq       int
+	mu        *sync.Mutex
+}
+
+type Event struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      EventData `json:"data"`
+}
+
+type EventData struct {
+	UserID    int        `json:"user_id"`
+	Plan      string     `json:"plan,omitempty"`
+	PeriodEnd *time.Time `json:"period_end,omitempty"`
+}
+
+type Checkout struct {
+	ID     string
+	UserID int
+	Plan   string
+	sim    *Simulator
+}
+
+type Subscription struct {
+	Plan       string `json:"plan"`
+	Status     string `json:"status"`
+	ActivePlan string `json:"active_plan"`
+	ChirpyRed  bool   `json:"is_chirpy_red"`
+}
+
+func New(serverURL string, secret []byte) *Simulator {
+	return &Simulator{
+		ServerURL: serverURL,
+		Secret:    secret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		mu:        &sync.Mutex{},
+	}
+}
+
+func (s *Simulator) nextID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().UnixNano(), s.seq)
+}
+
+// NewEvent builds an event stamped with the current time. Events are only
+// sent when passed to Deliver, so callers can reorder or repeat them.
+func (s *Simulator) NewEvent(eventType string, userID int, plan string) Event {
+	return Event{
+		ID:        s.nextID("evt"),
+		Event:     eventType,
+		CreatedAt: time.Now().UTC(),
+		Data: EventData{
+			UserID: userID,
+			Plan:   plan,
+		},
+	}
+}
+
+func (s *Simulator) CreateCheckout(userID int, plan string) *Checkout {
+	return &Checkout{
+		ID:     s.nextID("chk"),
+		UserID: userID,
+		Plan:   plan,
+		sim:    s,
+	}
+}
+
+// Complete pays for the checkout, which Polka reports as an upgrade.
+func (c *Checkout) Complete() (Event, int, error) {
+	event := c.sim.NewEvent("user.upgraded", c.UserID, c.Plan)
+	status, err := c.sim.Deliver(event)
+	return event, status, err
+}
+
+// Deliver sends a correctly signed event and returns the HTTP status.
+func (s *Simulator) Deliver(event Event) (int, error) {
+	return s.deliver(event, s.Secret, time.Now())
+}
+
+// DeliverForged sends an event signed with the wrong secret.
+func (s *Simulator) DeliverForged(event Event) (int, error) {
+	return s.deliver(event, []byte("not-the-secret"), time.Now())
+}
+
+// DeliverSignedAt sends an event whose signature timestamp is signedAt, to
+// exercise the server's replay window.
+func (s *Simulator) DeliverSignedAt(event Event, signedAt time.Time) (int, error) {
+	return s.deliver(event, s.Secret, signedAt)
+}
+
+// DeliverWithRetries redelivers until the server acknowledges with a 2xx,
+// as Polka does, returning the status of every attempt.
+func (s *Simulator) DeliverWithRetries(event Event, attempts int, backoff time.Duration) ([]int, error) {
+	statuses := []int{}
+	for i := 0; i < attempts; i++ {
+		status, err := s.Deliver(event)
+		if err != nil {
+			return statuses, err
+		}
+		statuses = append(statuses, status)
+		if status >= 200 && status < 300 {
+			break
+		}
+		time.Sleep(backoff)
+	}
+	return statuses, nil
+}
+
+func (s *Simulator) deliver(event Event, secret []byte, signedAt time.Time) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", s.ServerURL+"/api/polka/webhooks", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Polka-Signature", auth.SignWebhook(body, secret, signedAt))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// CreateUser registers a Chirpy user and logs in, returning its ID and access token.
+func (s *Simulator) CreateUser(email, password string) (int, string, error) {
+	credentials := map[string]string{"email": email, "password": password}
+	err := s.call("POST", "/api/users", "", credentials, 201, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	var login struct {
+		ID    int    `json:"id"`
+		Token string `json:"token"`
+	}
+	err = s.call("POST", "/api/login", "", credentials, 200, &login)
+	return login.ID, login.Token, err
+}
+
+func (s *Simulator) GetSubscription(token string) (Subscription, error) {
+	subscription := Subscription{}
+	err := s.call("GET", "/api/users/subscription", token, nil, 200, &subscription)
+	return subscription, err
+}
+
+func (s *Simulator) call(method, path, token string, body any, expected int, response any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.ServerURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: expected %d, got %d: %s", method, path, expected, resp.StatusCode, data)
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
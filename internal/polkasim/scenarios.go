@@ -0,0 +1,203 @@
+package polkasim
+
+import (
+	"fmt"
+	"time"
+)
+
+type Scenario struct {
+	Name string
+	Run  func(s *Simulator, user TestUser) error
+}
+
+type TestUser struct {
+	ID    int
+	Token string
+}
+
+var Scenarios = []Scenario{
+	{Name: "checkout upgrades user", Run: scenarioUpgrade},
+	{Name: "duplicate delivery is processed once", Run: scenarioDuplicate},
+	{Name: "retries after failed delivery", Run: scenarioRetry},
+	{Name: "forged and replayed deliveries are rejected", Run: scenarioForged},
+	{Name: "out-of-order events keep newest state", Run: scenarioOutOfOrder},
+	{Name: "downgrade keeps access until period end", Run: scenarioDowngrade},
+	{Name: "payment failure enters grace period", Run: scenarioPaymentFailed},
+	{Name: "refund revokes access", Run: scenarioRefund},
+}
+
+// RunAll runs every scenario against a fresh user and returns the failures
+// keyed by scenario name.
+func (s *Simulator) RunAll() map[string]error {
+	failures := map[string]error{}
+	for _, scenario := range Scenarios {
+		err := s.Run(scenario)
+		if err != nil {
+			failures[scenario.Name] = err
+		}
+	}
+	return failures
+}
+
+func (s *Simulator) Run(scenario Scenario) error {
+	email := s.nextID("polkasim") + "@example.com"
+	id, token, err := s.CreateUser(email, "polkasim-password")
+	if err != nil {
+		return fmt.Errorf("creating user: %w", err)
+	}
+	return scenario.Run(s, TestUser{ID: id, Token: token})
+}
+
+func scenarioUpgrade(s *Simulator, user TestUser) error {
+	err := s.expectState(user, "none", false)
+	if err != nil {
+		return err
+	}
+	_, status, err := s.CreateCheckout(user.ID, "chirpy_red").Complete()
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("upgrade returned %d", status)
+	}
+	return s.expectState(user, "active", true)
+}
+
+func scenarioDuplicate(s *Simulator, user TestUser) error {
+	event, _, err := s.CreateCheckout(user.ID, "chirpy_red").Complete()
+	if err != nil {
+		return err
+	}
+	cancel := s.NewEvent("subscription.cancelled", user.ID, "")
+	err = s.expectDelivery(cancel, 200)
+	if err != nil {
+		return err
+	}
+	// A redelivered upgrade must not undo the later cancellation.
+	err = s.expectDelivery(event, 200)
+	if err != nil {
+		return err
+	}
+	return s.expectState(user, "cancelled", true)
+}
+
+func scenarioRetry(s *Simulator, user TestUser) error {
+	event := s.NewEvent("user.upgraded", user.ID+1000000, "chirpy_red")
+	statuses, err := s.DeliverWithRetries(event, 3, 10*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	if len(statuses) != 3 || statuses[0] != 404 {
+		return fmt.Errorf("expected three 404 attempts for unknown user, got %v", statuses)
+	}
+
+	event = s.NewEvent("user.upgraded", user.ID, "chirpy_red")
+	statuses, err = s.DeliverWithRetries(event, 3, 10*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	if len(statuses) != 1 || statuses[0] != 200 {
+		return fmt.Errorf("expected one successful attempt, got %v", statuses)
+	}
+	return s.expectState(user, "active", true)
+}
+
+func scenarioForged(s *Simulator, user TestUser) error {
+	event := s.NewEvent("user.upgraded", user.ID, "chirpy_red")
+	status, err := s.DeliverForged(event)
+	if err != nil {
+		return err
+	}
+	if status != 401 {
+		return fmt.Errorf("forged delivery returned %d", status)
+	}
+	status, err = s.DeliverSignedAt(event, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if status != 401 {
+		return fmt.Errorf("stale delivery returned %d", status)
+	}
+	return s.expectState(user, "none", false)
+}
+
+func scenarioOutOfOrder(s *Simulator, user TestUser) error {
+	upgrade := s.NewEvent("user.upgraded", user.ID, "chirpy_red")
+	refund := s.NewEvent("payment.refunded", user.ID, "")
+	renew := s.NewEvent("subscription.renewed", user.ID, "")
+	for _, event := range []Event{renew, upgrade, refund} {
+		err := s.expectDelivery(event, 200)
+		if err != nil {
+			return err
+		}
+	}
+	// The renewal was created last, so the earlier upgrade and refund are stale.
+	return s.expectState(user, "active", true)
+}
+
+func scenarioDowngrade(s *Simulator, user TestUser) error {
+	_, _, err := s.CreateCheckout(user.ID, "chirpy_red").Complete()
+	if err != nil {
+		return err
+	}
+	err = s.expectDelivery(s.NewEvent("user.downgraded", user.ID, "free"), 200)
+	if err != nil {
+		return err
+	}
+	return s.expectState(user, "cancelled", true)
+}
+
+func scenarioPaymentFailed(s *Simulator, user TestUser) error {
+	_, _, err := s.CreateCheckout(user.ID, "chirpy_red").Complete()
+	if err != nil {
+		return err
+	}
+	err = s.expectDelivery(s.NewEvent("payment.failed", user.ID, ""), 200)
+	if err != nil {
+		return err
+	}
+	err = s.expectState(user, "past_due", true)
+	if err != nil {
+		return err
+	}
+	err = s.expectDelivery(s.NewEvent("subscription.renewed", user.ID, ""), 200)
+	if err != nil {
+		return err
+	}
+	return s.expectState(user, "active", true)
+}
+
+func scenarioRefund(s *Simulator, user TestUser) error {
+	_, _, err := s.CreateCheckout(user.ID, "chirpy_red").Complete()
+	if err != nil {
+		return err
+	}
+	err = s.expectDelivery(s.NewEvent("payment.refunded", user.ID, ""), 200)
+	if err != nil {
+		return err
+	}
+	return s.expectState(user, "refunded", false)
+}
+
+func (s *Simulator) expectDelivery(event Event, expected int) error {
+	status, err := s.Deliver(event)
+	if err != nil {
+		return err
+	}
+	if status != expected {
+		return fmt.Errorf("%s %s returned %d, expected %d", event.Event, event.ID, status, expected)
+	}
+	return nil
+}
+
+func (s *Simulator) expectState(user TestUser, status string, chirpyRed bool) error {
+	subscription, err := s.GetSubscription(user.Token)
+	if err != nil {
+		return err
+	}
+	if subscription.Status != status || subscription.ChirpyRed != chirpyRed {
+		return fmt.Errorf("expected status %q with chirpy red %t, got %q with %t",
+			status, chirpyRed, subscription.Status, subscription.ChirpyRed)
+	}
+	return nil
+}
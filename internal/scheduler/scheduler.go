@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Scheduler runs a job for each ID when its due time arrives. It keeps
+// nothing on disk: callers rebuild it from their own store on startup, and
+// jobs already overdue then run straight away.
+type Scheduler struct {
+	mu    *sync.Mutex
+	run   func(id int)
+	due   map[int]time.Time
+	queue *jobQueue
+	wake  chan struct{}
+}
+
+type job struct {
+	id int
+	at time.Time
+}
+
+// jobQueue is a min-heap on due time. Cancelled and rescheduled jobs are left
+// in place and skipped when they no longer match due.
+type jobQueue []job
+
+func (q jobQueue) Len() int           { return len(q) }
+func (q jobQueue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+func (q jobQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *jobQueue) Push(x any)        { *q = append(*q, x.(job)) }
+func (q *jobQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+func New(run func(id int)) *Scheduler {
+	return &Scheduler{
+		mu:    &sync.Mutex{},
+		run:   run,
+		due:   make(map[int]time.Time),
+		queue: &jobQueue{},
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Rebuild replaces every job with those given, keyed by ID.
+func (s *Scheduler) Rebuild(jobs map[int]time.Time) {
+	s.mu.Lock()
+	s.due = make(map[int]time.Time)
+	s.queue = &jobQueue{}
+	for id, at := range jobs {
+		s.due[id] = at
+		*s.queue = append(*s.queue, job{id: id, at: at})
+	}
+	heap.Init(s.queue)
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Schedule runs the job at the given time, replacing any earlier schedule
+// for the same ID.
+func (s *Scheduler) Schedule(id int, at time.Time) {
+	s.mu.Lock()
+	s.due[id] = at
+	heap.Push(s.queue, job{id: id, at: at})
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *Scheduler) Cancel(id int) {
+	s.mu.Lock()
+	delete(s.due, id)
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run waits for jobs to fall due and runs them one at a time. It never
+// returns, so call it in its own goroutine.
+func (s *Scheduler) Run() {
+	timer := time.NewTimer(time.Hour)
+	for {
+		for _, id := range s.popDue(time.Now()) {
+			s.run(id)
+		}
+		timer.Reset(s.untilNext(time.Now()))
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+func (s *Scheduler) popDue(now time.Time) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []int{}
+	for s.queue.Len() > 0 {
+		next := (*s.queue)[0]
+		if at, ok := s.due[next.id]; !ok || !at.Equal(next.at) {
+			heap.Pop(s.queue)
+			continue
+		}
+		if next.at.After(now) {
+			break
+		}
+		heap.Pop(s.queue)
+		delete(s.due, next.id)
+		ids = append(ids, next.id)
+	}
+	return ids
+}
+
+func (s *Scheduler) untilNext(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queue.Len() == 0 {
+		return time.Hour
+	}
+	return max((*s.queue)[0].at.Sub(now), 0)
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// startScheduler runs a scheduler whose jobs report their IDs on a channel.
+func startScheduler(t *testing.T) (*Scheduler, <-chan int) {
+	t.Helper()
+	ran := make(chan int, 10)
+	s := New(func(id int) { ran <- id })
+	go s.Run()
+	return s, ran
+}
+
+func expectRuns(t *testing.T, ran <-chan int, want ...int) {
+	t.Helper()
+	got := []int{}
+	for range want {
+		select {
+		case id := <-ran:
+			got = append(got, id)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("ran %v, want %v", got, want)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ran %v, want %v", got, want)
+	}
+}
+
+func expectNoRuns(t *testing.T, ran <-chan int, wait time.Duration) {
+	t.Helper()
+	select {
+	case id := <-ran:
+		t.Errorf("job %d ran unexpectedly", id)
+	case <-time.After(wait):
+	}
+}
+
+func TestRunsJobsInDueOrder(t *testing.T) {
+	s, ran := startScheduler(t)
+	now := time.Now()
+	s.Schedule(1, now.Add(150*time.Millisecond))
+	s.Schedule(2, now.Add(50*time.Millisecond))
+	s.Schedule(3, now.Add(100*time.Millisecond))
+	expectRuns(t, ran, 2, 3, 1)
+	expectNoRuns(t, ran, 100*time.Millisecond)
+}
+
+func TestCancelAndReschedule(t *testing.T) {
+	s, ran := startScheduler(t)
+	now := time.Now()
+	s.Schedule(1, now.Add(50*time.Millisecond))
+	s.Schedule(2, now.Add(50*time.Millisecond))
+	s.Cancel(1)
+	s.Schedule(2, now.Add(150*time.Millisecond))
+	s.Schedule(3, now.Add(100*time.Millisecond))
+	expectRuns(t, ran, 3, 2)
+	expectNoRuns(t, ran, 100*time.Millisecond)
+}
+
+// A job scheduled sooner than the one the scheduler is sleeping towards must
+// wake it rather than wait.
+func TestEarlierJobWakesScheduler(t *testing.T) {
+	s, ran := startScheduler(t)
+	s.Schedule(1, time.Now().Add(time.Hour))
+	time.Sleep(20 * time.Millisecond)
+	s.Schedule(2, time.Now().Add(20*time.Millisecond))
+	expectRuns(t, ran, 2)
+}
+
+func TestRebuildRunsOverdueJobs(t *testing.T) {
+	s, ran := startScheduler(t)
+	s.Schedule(9, time.Now().Add(50*time.Millisecond))
+	s.Rebuild(map[int]time.Time{
+		1: time.Now().Add(-time.Hour),
+		2: time.Now().Add(time.Hour),
+	})
+	expectRuns(t, ran, 1)
+	expectNoRuns(t, ran, 100*time.Millisecond)
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("query has no search terms")
+var ErrUnterminatedPhrase = errors.New("unterminated phrase")
+
+// Query is a conjunction of clauses: a chirp must match all of them.
+type Query struct {
+	Clauses []Clause
+}
+
+// Clause matches a single term, a phrase of consecutive terms, or any term
+// starting with a prefix.
+type Clause struct {
+	Terms  []string
+	Prefix bool
+}
+
+// ParseQuery parses a search string. Words are matched individually,
+// "quoted text" is matched as a phrase and a trailing * matches by prefix,
+// as in chirp*.
+func ParseQuery(q string) (Query, error) {
+	query := Query{}
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			if end == -1 {
+				return Query{}, ErrUnterminatedPhrase
+			}
+			if terms := Tokenize(q[1 : end+1]); len(terms) > 0 {
+				query.Clauses = append(query.Clauses, Clause{Terms: terms})
+			}
+			q = q[end+2:]
+			continue
+		}
+
+		end := strings.IndexFunc(q, unicode.IsSpace)
+		if end == -1 {
+			end = len(q)
+		}
+		word := q[:end]
+		q = q[end:]
+		terms := Tokenize(word)
+		if len(terms) == 0 {
+			continue
+		}
+		if strings.HasSuffix(word, "*") && len(terms) == 1 {
+			query.Clauses = append(query.Clauses, Clause{Terms: terms, Prefix: true})
+			continue
+		}
+		// Words that tokenize into several terms, like don't, are phrases.
+		query.Clauses = append(query.Clauses, Clause{Terms: terms})
+	}
+	if len(query.Clauses) == 0 {
+		return Query{}, ErrEmptyQuery
+	}
+	return query, nil
+}
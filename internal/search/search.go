@@ -0,0 +1,207 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Index is an in-memory inverted index over chirp bodies. It implements
+// db.ChirpObserver so it can be kept up to date as chirps change.
+type Index struct {
+	mu       *sync.RWMutex
+	postings map[string]map[int][]int
+	docTerms map[int][]string
+	docLen   map[int]int
+	totalLen int
+}
+
+type Result struct {
+	ID    int
+	Score float64
+}
+
+func New() *Index {
+	return &Index{
+		mu:       &sync.RWMutex{},
+		postings: make(map[string]map[int][]int),
+		docTerms: make(map[int][]string),
+		docLen:   make(map[int]int),
+	}
+}
+
+// Rebuild replaces the contents of the index with the given chirps.
+func (idx *Index) Rebuild(chirps map[int]db.Chirp) {
+	idx.mu.Lock()
+	idx.postings = make(map[string]map[int][]int)
+	idx.docTerms = make(map[int][]string)
+	idx.docLen = make(map[int]int)
+	idx.totalLen = 0
+	for _, chirp := range chirps {
+		idx.add(chirp.ID, chirp.Body)
+	}
+	idx.mu.Unlock()
+}
+
+func (idx *Index) ChirpSaved(chirp db.Chirp) {
+	idx.Add(chirp.ID, chirp.Body)
+}
+
+func (idx *Index) ChirpDeleted(chirpID int) {
+	idx.Remove(chirpID)
+}
+
+// Add indexes a document, replacing any previous version of it.
+func (idx *Index) Add(id int, text string) {
+	idx.mu.Lock()
+	idx.remove(id)
+	idx.add(id, text)
+	idx.mu.Unlock()
+}
+
+func (idx *Index) Remove(id int) {
+	idx.mu.Lock()
+	idx.remove(id)
+	idx.mu.Unlock()
+}
+
+func (idx *Index) add(id int, text string) {
+	tokens := Tokenize(text)
+	for position, term := range tokens {
+		docs, ok := idx.postings[term]
+		if !ok {
+			docs = make(map[int][]int)
+			idx.postings[term] = docs
+		}
+		if len(docs[id]) == 0 {
+			idx.docTerms[id] = append(idx.docTerms[id], term)
+		}
+		docs[id] = append(docs[id], position)
+	}
+	idx.docLen[id] = len(tokens)
+	idx.totalLen += len(tokens)
+}
+
+func (idx *Index) remove(id int) {
+	for _, term := range idx.docTerms[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLen -= idx.docLen[id]
+	delete(idx.docTerms, id)
+	delete(idx.docLen, id)
+}
+
+// Search returns the documents matching every clause of the query, most
+// relevant first. Ties go to the newest document.
+func (idx *Index) Search(query Query) []Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var scores map[int]float64
+	for _, clause := range query.Clauses {
+		clauseScores := idx.scoreClause(clause)
+		if scores == nil {
+			scores = clauseScores
+			continue
+		}
+		for id, score := range scores {
+			if clauseScore, ok := clauseScores[id]; ok {
+				scores[id] = score + clauseScore
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID > results[j].ID
+	})
+	return results
+}
+
+func (idx *Index) scoreClause(clause Clause) map[int]float64 {
+	scores := map[int]float64{}
+	switch {
+	case clause.Prefix:
+		// A prefix matches several terms; a document scores by its best one.
+		for term, docs := range idx.postings {
+			if !strings.HasPrefix(term, clause.Terms[0]) {
+				continue
+			}
+			for id := range docs {
+				scores[id] = max(scores[id], idx.bm25(term, id))
+			}
+		}
+	case len(clause.Terms) == 1:
+		for id := range idx.postings[clause.Terms[0]] {
+			scores[id] = idx.bm25(clause.Terms[0], id)
+		}
+	default:
+		for id := range idx.postings[clause.Terms[0]] {
+			if !idx.containsPhrase(id, clause.Terms) {
+				continue
+			}
+			for _, term := range clause.Terms {
+				scores[id] += idx.bm25(term, id)
+			}
+		}
+	}
+	return scores
+}
+
+func (idx *Index) containsPhrase(id int, terms []string) bool {
+	for _, start := range idx.postings[terms[0]][id] {
+		matched := true
+		for offset, term := range terms[1:] {
+			if !containsInt(idx.postings[term][id], start+offset+1) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (idx *Index) bm25(term string, id int) float64 {
+	docs := idx.postings[term]
+	n := float64(len(idx.docLen))
+	idf := math.Log(1 + (n-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+	tf := float64(len(docs[id]))
+	avgLen := float64(idx.totalLen) / n
+	return idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(idx.docLen[id])/avgLen))
+}
+
+// Tokenize splits text into lower-cased runs of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func containsInt(sorted []int, value int) bool {
+	i := sort.SearchInts(sorted, value)
+	return i < len(sorted) && sorted[i] == value
+}
@@ -0,0 +1,194 @@
+package search
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func newTestIndex(docs map[int]string) *Index {
+	idx := New()
+	for id, text := range docs {
+		idx.Add(id, text)
+	}
+	return idx
+}
+
+func search(t *testing.T, idx *Index, q string) []int {
+	t.Helper()
+	query, err := ParseQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, result := range idx.Search(query) {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	idx := newTestIndex(map[int]string{
+		1: "The quick brown fox jumps",
+		2: "A brown quick fox sleeps",
+		3: "Chirpy is chirping about chirps",
+		4: "birdchirp is not a prefix match",
+		5: "go go go",
+		6: "go and then a long tail of other words to dilute it",
+		7: "Don't stop",
+		8: "do not stop",
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"fox", []int{2, 1}},
+		{"FOX", []int{2, 1}},
+		{`"quick brown"`, []int{1}},
+		{`"brown quick"`, []int{2}},
+		{`"quick fox"`, []int{2}},
+		{`"fox quick"`, []int{}},
+		{"quick fox jumps", []int{1}},
+		{"chirp*", []int{3}},
+		{"birdchirp*", []int{4}},
+		{"go", []int{5, 6}},
+		{"don't", []int{7}},
+		{"stop", []int{8, 7}},
+		{"fox chirpy", []int{}},
+		{"unknown", []int{}},
+	}
+	for _, tt := range tests {
+		if got := search(t, idx, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// Shorter chirps and rarer terms rank higher, and equal scores go to the
+// newest chirp.
+func TestSearchRanking(t *testing.T) {
+	idx := newTestIndex(map[int]string{
+		1: "rare word in a chirp with quite a few other words",
+		2: "rare word",
+		3: "common common",
+		4: "common",
+		5: "common rare words here",
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"rare", []int{2, 5, 1}},
+		{"common", []int{3, 4, 5}},
+		{"common rare", []int{5}},
+	}
+	for _, tt := range tests {
+		if got := search(t, idx, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	idx = newTestIndex(map[int]string{1: "same words", 2: "same words", 3: "same words"})
+	if got := search(t, idx, "same"); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("tied results = %v, want newest first", got)
+	}
+}
+
+func TestAddReplacesAndRemoveForgets(t *testing.T) {
+	idx := newTestIndex(map[int]string{1: "original text", 2: "other text"})
+	idx.Add(1, "edited body")
+	if got := search(t, idx, "original"); len(got) != 0 {
+		t.Errorf("old body still matches: %v", got)
+	}
+	if got := search(t, idx, "edited"); !slices.Equal(got, []int{1}) {
+		t.Errorf("new body = %v, want [1]", got)
+	}
+	if idx.totalLen != 4 {
+		t.Errorf("totalLen = %d after reindexing, want 4", idx.totalLen)
+	}
+
+	idx.Remove(1)
+	if got := search(t, idx, "edited"); len(got) != 0 {
+		t.Errorf("removed chirp still matches: %v", got)
+	}
+	if _, ok := idx.postings["edited"]; ok {
+		t.Error("postings for a removed chirp's terms were left behind")
+	}
+	if got := search(t, idx, "text"); !slices.Equal(got, []int{2}) {
+		t.Errorf("remaining chirp = %v, want [2]", got)
+	}
+}
+
+// The index follows the database: edits reindex, and deleting or hiding a
+// chirp removes it.
+func TestIndexObservesChirps(t *testing.T) {
+	database := db.InitialiseDatabase(filepath.Join(t.TempDir(), "database.json"))
+	idx := New()
+	database.ObserveChirps(idx)
+	user, err := database.AddUser("a@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited, _ := database.CreateChirp(db.Chirp{UserID: user.ID, Body: "first draft"})
+	deleted, _ := database.CreateChirp(db.Chirp{UserID: user.ID, Body: "draft to delete"})
+	hidden, _ := database.CreateChirp(db.Chirp{UserID: user.ID, Body: "draft to hide"})
+
+	_, err = database.UpdateChirp(edited.ID, db.Chirp{Body: "final version"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = database.DeleteChirp(deleted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = database.SetChirpHidden(hidden.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := search(t, idx, "draft"); len(got) != 0 {
+		t.Errorf("draft = %v, want no matches", got)
+	}
+	if got := search(t, idx, "final"); !slices.Equal(got, []int{edited.ID}) {
+		t.Errorf("final = %v, want [%d]", got, edited.ID)
+	}
+
+	_, err = database.SetChirpHidden(hidden.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := search(t, idx, "hide"); !slices.Equal(got, []int{hidden.ID}) {
+		t.Errorf("unhidden chirp = %v, want [%d]", got, hidden.ID)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	query, err := ParseQuery(`hello "big world" chirp* don't`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Clause{
+		{Terms: []string{"hello"}},
+		{Terms: []string{"big", "world"}},
+		{Terms: []string{"chirp"}, Prefix: true},
+		{Terms: []string{"don", "t"}},
+	}
+	if !slices.EqualFunc(query.Clauses, want, func(a, b Clause) bool {
+		return a.Prefix == b.Prefix && slices.Equal(a.Terms, b.Terms)
+	}) {
+		t.Errorf("clauses = %+v, want %+v", query.Clauses, want)
+	}
+
+	errorTests := map[string]error{
+		`"open phrase`: ErrUnterminatedPhrase,
+		"   ":          ErrEmptyQuery,
+		`!! ""`:        ErrEmptyQuery,
+	}
+	for q, wantErr := range errorTests {
+		if _, err := ParseQuery(q); !errors.Is(err, wantErr) {
+			t.Errorf("ParseQuery(%q) err = %v, want %v", q, err, wantErr)
+		}
+	}
+}
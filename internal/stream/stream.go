@@ -0,0 +1,165 @@
+package stream
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+const (
+	EventCreated = "chirp.created"
+	EventUpdated = "chirp.updated"
+	EventDeleted = "chirp.deleted"
+)
+
+// Hub broadcasts chirp changes to live subscribers. It implements
+// db.ChirpObserver and keeps a backlog of recent events so clients that
+// reconnect can resume from the last event they saw.
+type Hub struct {
+	mu          *sync.Mutex
+	cfg         Config
+	epoch       string
+	seq         uint64
+	backlog     []Event
+	authors     map[int]int
+	subscribers map[*Subscription]bool
+}
+
+type Config struct {
+	// Backlog is how many recent events are kept for resuming.
+	Backlog int
+	// BufferSize is how many events may wait for a subscriber before it is
+	// considered too slow and dropped.
+	BufferSize int
+}
+
+// Event is a change to a chirp. Deleted events carry no chirp, only its ID
+// and author.
+type Event struct {
+	ID      string
+	Type    string
+	ChirpID int
+	UserID  int
+	Chirp   *db.Chirp
+	seq     uint64
+}
+
+type Subscription struct {
+	Events  <-chan Event
+	events  chan Event
+	hub     *Hub
+	dropped bool
+}
+
+func New(cfg Config) *Hub {
+	return &Hub{
+		mu:          &sync.Mutex{},
+		cfg:         cfg,
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		authors:     make(map[int]int),
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+// Rebuild records the authors of existing chirps so their deletion can be
+// filtered by author.
+func (h *Hub) Rebuild(chirps map[int]db.Chirp) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authors = make(map[int]int, len(chirps))
+	for id, chirp := range chirps {
+		h.authors[id] = chirp.UserID
+	}
+}
+
+func (h *Hub) ChirpSaved(chirp db.Chirp) {
+	eventType := EventUpdated
+	if chirp.UpdatedAt.Equal(chirp.CreatedAt) {
+		eventType = EventCreated
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authors[chirp.ID] = chirp.UserID
+	h.publish(Event{Type: eventType, ChirpID: chirp.ID, UserID: chirp.UserID, Chirp: &chirp})
+}
+
+func (h *Hub) ChirpDeleted(chirpID int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	userID := h.authors[chirpID]
+	delete(h.authors, chirpID)
+	h.publish(Event{Type: EventDeleted, ChirpID: chirpID, UserID: userID})
+}
+
+// publish numbers an event and hands it to every subscriber. Subscribers
+// whose buffers are full are dropped rather than allowed to hold up the
+// writer; they can reconnect and resume from the backlog. Callers must hold
+// the lock.
+func (h *Hub) publish(event Event) {
+	h.seq++
+	event.seq = h.seq
+	event.ID = h.epoch + "-" + strconv.FormatUint(h.seq, 10)
+	h.backlog = append(h.backlog, event)
+	if len(h.backlog) > h.cfg.Backlog {
+		h.backlog = h.backlog[len(h.backlog)-h.cfg.Backlog:]
+	}
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped = true
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts a subscription. If lastEventID is set, the events after it
+// are returned to be sent first; complete is false if some of them are no
+// longer in the backlog, or the ID is from before a restart.
+func (h *Hub) Subscribe(lastEventID string) (sub *Subscription, missed []Event, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	events := make(chan Event, h.cfg.BufferSize)
+	sub = &Subscription{Events: events, events: events, hub: h}
+	h.subscribers[sub] = true
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+
+	epoch, seqPart, _ := strings.Cut(lastEventID, "-")
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil || epoch != h.epoch || seq > h.seq {
+		return sub, nil, false
+	}
+	complete = seq == h.seq || (len(h.backlog) > 0 && h.backlog[0].seq <= seq+1)
+	for _, event := range h.backlog {
+		if event.seq > seq {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed, complete
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Dropped reports whether the subscription was ended for falling behind.
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if h.subscribers[sub] {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
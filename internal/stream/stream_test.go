@@ -0,0 +1,176 @@
+package stream
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func testChirp(id, userID int) db.Chirp {
+	now := time.Now()
+	return db.Chirp{ID: id, UserID: userID, CreatedAt: now, UpdatedAt: now}
+}
+
+func chirpIDs(events []Event) []int {
+	ids := make([]int, len(events))
+	for i, event := range events {
+		ids[i] = event.ChirpID
+	}
+	return ids
+}
+
+// receive takes the events waiting on a subscription without blocking.
+func receive(sub *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case event, open := <-sub.Events:
+			if !open {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestPublishTypesAndNumbersEvents(t *testing.T) {
+	hub := New(Config{Backlog: 10, BufferSize: 10})
+	hub.Rebuild(map[int]db.Chirp{1: testChirp(1, 7)})
+	sub, missed, complete := hub.Subscribe("")
+	if missed != nil || !complete {
+		t.Fatalf("new subscription: missed %v, complete %v", missed, complete)
+	}
+
+	created := testChirp(2, 8)
+	hub.ChirpSaved(created)
+	edited := created
+	edited.UpdatedAt = created.UpdatedAt.Add(time.Minute)
+	hub.ChirpSaved(edited)
+	hub.ChirpDeleted(2)
+	hub.ChirpDeleted(1)
+
+	events := receive(sub)
+	want := []struct {
+		eventType string
+		chirpID   int
+		userID    int
+	}{
+		{EventCreated, 2, 8},
+		{EventUpdated, 2, 8},
+		{EventDeleted, 2, 8},
+		{EventDeleted, 1, 7},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		event := events[i]
+		if event.Type != w.eventType || event.ChirpID != w.chirpID || event.UserID != w.userID {
+			t.Errorf("event %d = %s %d by %d, want %s %d by %d", i, event.Type, event.ChirpID, event.UserID, w.eventType, w.chirpID, w.userID)
+		}
+		if (event.Chirp == nil) != (event.Type == EventDeleted) {
+			t.Errorf("event %d: only deletions should omit the chirp", i)
+		}
+		if i > 0 && event.seq != events[i-1].seq+1 {
+			t.Errorf("event %d: seq %d does not follow %d", i, event.seq, events[i-1].seq)
+		}
+	}
+}
+
+func TestSubscribeResumesFromLastEventID(t *testing.T) {
+	hub := New(Config{Backlog: 10, BufferSize: 10})
+	first, _, _ := hub.Subscribe("")
+	for id := 1; id <= 3; id++ {
+		hub.ChirpSaved(testChirp(id, 1))
+	}
+	seen := receive(first)
+	first.Close()
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		missed      []int
+	}{
+		{"after first event", seen[0].ID, []int{2, 3}},
+		{"after last event", seen[2].ID, nil},
+	}
+	for _, tt := range tests {
+		sub, missed, complete := hub.Subscribe(tt.lastEventID)
+		if !complete || !slices.Equal(chirpIDs(missed), tt.missed) {
+			t.Errorf("%s: missed %v, complete %v, want %v", tt.name, chirpIDs(missed), complete, tt.missed)
+		}
+		sub.Close()
+	}
+}
+
+// Clients that cannot be brought fully up to date are told to resync rather
+// than silently missing changes.
+func TestSubscribeReportsIncompleteResumes(t *testing.T) {
+	hub := New(Config{Backlog: 2, BufferSize: 10})
+	first, _, _ := hub.Subscribe("")
+	for id := 1; id <= 5; id++ {
+		hub.ChirpSaved(testChirp(id, 1))
+	}
+	seen := receive(first)
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		missed      []int
+		complete    bool
+	}{
+		{"within backlog", seen[2].ID, []int{4, 5}, true},
+		{"backlog exceeded", seen[0].ID, []int{4, 5}, false},
+		{"before a restart", "otherepoch-3", nil, false},
+		{"from the future", hub.epoch + "-99", nil, false},
+		{"malformed", "garbage", nil, false},
+	}
+	for _, tt := range tests {
+		sub, missed, complete := hub.Subscribe(tt.lastEventID)
+		if complete != tt.complete || !slices.Equal(chirpIDs(missed), tt.missed) {
+			t.Errorf("%s: missed %v, complete %v, want %v, %v", tt.name, chirpIDs(missed), complete, tt.missed, tt.complete)
+		}
+		sub.Close()
+	}
+
+	restarted := New(Config{Backlog: 2, BufferSize: 10})
+	if _, _, complete := restarted.Subscribe(seen[4].ID); complete {
+		t.Error("an ID from a previous run was treated as complete")
+	}
+}
+
+func TestSlowSubscribersAreDropped(t *testing.T) {
+	hub := New(Config{Backlog: 10, BufferSize: 2})
+	slow, _, _ := hub.Subscribe("")
+	fast, _, _ := hub.Subscribe("")
+	for id := 1; id <= 3; id++ {
+		hub.ChirpSaved(testChirp(id, 1))
+		receive(fast)
+	}
+
+	if events := receive(slow); !slices.Equal(chirpIDs(events), []int{1, 2}) {
+		t.Errorf("slow subscriber got %v, want the buffered [1 2]", chirpIDs(events))
+	}
+	if _, open := <-slow.Events; open {
+		t.Error("slow subscriber's channel is still open")
+	}
+	if !slow.Dropped() {
+		t.Error("slow subscriber is not marked as dropped")
+	}
+	if fast.Dropped() {
+		t.Error("subscriber that kept up was dropped")
+	}
+
+	fast.Close()
+	fast.Close()
+	if _, open := <-fast.Events; open || fast.Dropped() {
+		t.Error("closing a subscription should end it without marking it dropped")
+	}
+	if len(hub.subscribers) != 0 {
+		t.Errorf("hub still has %d subscribers", len(hub.subscribers))
+	}
+}
@@ -0,0 +1,215 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The server side of RFC 6455, limited to what the stream needs: sending
+// text messages and pings, and answering the client's control frames.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+const (
+	CloseNormal    = 1000
+	CloseGoingAway = 1001
+	CloseTooBig    = 1009
+	// ClosePolicy is sent to clients dropped for reading too slowly.
+	ClosePolicy = 1008
+)
+
+// maxClientMessage bounds the frames accepted from clients, which have
+// nothing to send but control frames.
+const maxClientMessage = 4096
+
+var ErrNotWebSocket = errors.New("not a websocket handshake")
+
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu *sync.Mutex
+	closed  chan struct{}
+	once    *sync.Once
+}
+
+// Upgrade completes the opening handshake and takes over the connection.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" ||
+		r.Header.Get("Sec-WebSocket-Key") == "" {
+		return nil, ErrNotWebSocket
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection cannot be hijacked")
+	}
+	netConn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	_, err = netConn.Write([]byte(response))
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return &Conn{
+		conn:    netConn,
+		reader:  buf.Reader,
+		writeMu: &sync.Mutex{},
+		closed:  make(chan struct{}),
+		once:    &sync.Once{},
+	}, nil
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Closed is closed once the connection ends, from either side.
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a close frame with the given status and closes the connection.
+func (c *Conn) Close(code int) {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	c.writeFrame(opClose, payload)
+	c.shutdown()
+}
+
+func (c *Conn) shutdown() {
+	c.once.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(append(header, payload...))
+	if err != nil {
+		c.shutdown()
+	}
+	return err
+}
+
+// ReadLoop reads frames from the client until the connection closes,
+// answering pings and close frames and discarding messages. Run it in its
+// own goroutine.
+func (c *Conn) ReadLoop() {
+	defer c.shutdown()
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, errFrameTooBig) {
+				c.Close(CloseTooBig)
+			}
+			return
+		}
+		switch opcode {
+		case opPing:
+			c.writeFrame(opPong, payload)
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return
+		}
+	}
+}
+
+var errFrameTooBig = errors.New("frame too big")
+
+func (c *Conn) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(c.reader, extended)
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		_, err = io.ReadFull(c.reader, extended)
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if !masked {
+		return 0, nil, errors.New("client frames must be masked")
+	}
+	if length > maxClientMessage {
+		return 0, nil, errFrameTooBig
+	}
+	mask := make([]byte, 4)
+	_, err = io.ReadFull(c.reader, mask)
+	if err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
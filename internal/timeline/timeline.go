@@ -0,0 +1,211 @@
+package timeline
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// Timeline builds home timelines with a hybrid fan-out. New chirps are pushed
+// into each follower's inbox when they are written, except for authors with
+// more than FanoutLimit followers, whose chirps are pulled in when the
+// timeline is read. It implements db.ChirpObserver.
+type Timeline struct {
+	mu        *sync.RWMutex
+	cfg       Config
+	following map[int]map[int]bool
+	followers map[int]map[int]bool
+	authors   map[int]int
+	authored  map[int][]int
+	inboxes   map[int][]int
+}
+
+type Config struct {
+	// FanoutLimit is the follower count above which an author's chirps are
+	// no longer pushed to followers on write.
+	FanoutLimit int
+	// InboxSize caps the chirps kept per user and per author.
+	InboxSize int
+}
+
+func New(cfg Config) *Timeline {
+	return &Timeline{
+		mu:        &sync.RWMutex{},
+		cfg:       cfg,
+		following: make(map[int]map[int]bool),
+		followers: make(map[int]map[int]bool),
+		authors:   make(map[int]int),
+		authored:  make(map[int][]int),
+		inboxes:   make(map[int][]int),
+	}
+}
+
+// Rebuild replaces the follow graph and inboxes with the database contents.
+func (t *Timeline) Rebuild(follows map[int]map[int]time.Time, chirps map[int]db.Chirp) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.following = make(map[int]map[int]bool)
+	t.followers = make(map[int]map[int]bool)
+	t.authors = make(map[int]int)
+	t.authored = make(map[int][]int)
+	t.inboxes = make(map[int][]int)
+
+	for followerID, following := range follows {
+		for followedID := range following {
+			t.addEdge(followerID, followedID)
+		}
+	}
+	for _, chirp := range chirps {
+		t.authors[chirp.ID] = chirp.UserID
+		t.authored[chirp.UserID] = append(t.authored[chirp.UserID], chirp.ID)
+	}
+	for authorID, chirpIDs := range t.authored {
+		sort.Ints(chirpIDs)
+		t.authored[authorID] = t.trim(chirpIDs)
+	}
+	for followerID, following := range t.following {
+		for followedID := range following {
+			if !t.isCelebrity(followedID) {
+				t.inboxes[followerID] = t.trim(mergeIDs(t.inboxes[followerID], t.authored[followedID]))
+			}
+		}
+	}
+}
+
+func (t *Timeline) ChirpSaved(chirp db.Chirp) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.authors[chirp.ID]; ok {
+		return
+	}
+	t.authors[chirp.ID] = chirp.UserID
+	t.authored[chirp.UserID] = t.trim(insertID(t.authored[chirp.UserID], chirp.ID))
+	if t.isCelebrity(chirp.UserID) {
+		return
+	}
+	for followerID := range t.followers[chirp.UserID] {
+		t.inboxes[followerID] = t.trim(insertID(t.inboxes[followerID], chirp.ID))
+	}
+}
+
+func (t *Timeline) ChirpDeleted(chirpID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	authorID, ok := t.authors[chirpID]
+	if !ok {
+		return
+	}
+	delete(t.authors, chirpID)
+	t.authored[authorID] = removeID(t.authored[authorID], chirpID)
+	for followerID := range t.followers[authorID] {
+		t.inboxes[followerID] = removeID(t.inboxes[followerID], chirpID)
+	}
+}
+
+// Follow records a new edge and backfills the follower's inbox with the
+// followed user's recent chirps.
+func (t *Timeline) Follow(followerID, followedID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.addEdge(followerID, followedID)
+	if !t.isCelebrity(followedID) {
+		t.inboxes[followerID] = t.trim(mergeIDs(t.inboxes[followerID], t.authored[followedID]))
+	}
+}
+
+// Unfollow removes an edge and the followed user's chirps from the follower's
+// inbox. If that takes the followed user back under FanoutLimit, the chirps
+// that were being pulled are pushed into the remaining followers' inboxes.
+func (t *Timeline) Unfollow(followerID, followedID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wasCelebrity := t.isCelebrity(followedID)
+	delete(t.following[followerID], followedID)
+	delete(t.followers[followedID], followerID)
+	t.inboxes[followerID] = slices.DeleteFunc(t.inboxes[followerID], func(chirpID int) bool {
+		return t.authors[chirpID] == followedID
+	})
+	if wasCelebrity && !t.isCelebrity(followedID) {
+		for id := range t.followers[followedID] {
+			t.inboxes[id] = t.trim(mergeIDs(t.inboxes[id], t.authored[followedID]))
+		}
+	}
+}
+
+// Home returns the IDs of the chirps on a user's home timeline, newest first:
+// their inbox merged with their own chirps and those of the high-follower
+// accounts they follow.
+func (t *Timeline) Home(userID int) []int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	home := mergeIDs(t.inboxes[userID], t.authored[userID])
+	for followedID := range t.following[userID] {
+		if t.isCelebrity(followedID) {
+			home = mergeIDs(home, t.authored[followedID])
+		}
+	}
+	home = t.trim(home)
+	slices.Reverse(home)
+	return home
+}
+
+func (t *Timeline) addEdge(followerID, followedID int) {
+	if t.following[followerID] == nil {
+		t.following[followerID] = make(map[int]bool)
+	}
+	if t.followers[followedID] == nil {
+		t.followers[followedID] = make(map[int]bool)
+	}
+	t.following[followerID][followedID] = true
+	t.followers[followedID][followerID] = true
+}
+
+func (t *Timeline) isCelebrity(userID int) bool {
+	return len(t.followers[userID]) > t.cfg.FanoutLimit
+}
+
+// trim keeps the newest InboxSize IDs of an ascending list.
+func (t *Timeline) trim(ids []int) []int {
+	if len(ids) <= t.cfg.InboxSize {
+		return ids
+	}
+	return slices.Clone(ids[len(ids)-t.cfg.InboxSize:])
+}
+
+// mergeIDs merges two ascending lists into a new one without duplicates.
+func mergeIDs(a, b []int) []int {
+	merged := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		var next int
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			next, i = a[i], i+1
+		case i == len(a) || b[j] < a[i]:
+			next, j = b[j], j+1
+		default:
+			next, i, j = a[i], i+1, j+1
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
+
+func insertID(ids []int, id int) []int {
+	i, found := slices.BinarySearch(ids, id)
+	if found {
+		return ids
+	}
+	return slices.Insert(ids, i, id)
+}
+
+func removeID(ids []int, id int) []int {
+	i, found := slices.BinarySearch(ids, id)
+	if !found {
+		return ids
+	}
+	return slices.Delete(ids, i, i+1)
+}
@@ -0,0 +1,73 @@
+package timeline
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+func TestHomeMergesPushedAndPulledAuthors(t *testing.T) {
+	tl := New(Config{FanoutLimit: 1, InboxSize: 10})
+	tl.Follow(1, 2)
+	tl.Follow(1, 3)
+	tl.Follow(4, 3)
+	tl.ChirpSaved(db.Chirp{ID: 1, UserID: 2})
+	tl.ChirpSaved(db.Chirp{ID: 2, UserID: 3})
+	tl.ChirpSaved(db.Chirp{ID: 3, UserID: 1})
+	tl.ChirpSaved(db.Chirp{ID: 4, UserID: 5})
+
+	if got := tl.Home(1); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("Home(1) = %v, want [3 2 1]", got)
+	}
+	if len(tl.inboxes[1]) != 1 {
+		t.Errorf("inbox holds %v, want only the pushed chirp", tl.inboxes[1])
+	}
+}
+
+// An author pulled for having too many followers must be pushed again once
+// unfollows take them back under the limit, or their chirps disappear.
+func TestUnfollowBelowFanoutLimitPushesPulledChirps(t *testing.T) {
+	tl := New(Config{FanoutLimit: 1, InboxSize: 10})
+	tl.Follow(1, 3)
+	tl.Follow(2, 3)
+	tl.ChirpSaved(db.Chirp{ID: 1, UserID: 3})
+
+	tl.Unfollow(2, 3)
+	if got := tl.Home(1); !slices.Equal(got, []int{1}) {
+		t.Errorf("Home(1) = %v, want [1]", got)
+	}
+	if got := tl.Home(2); len(got) != 0 {
+		t.Errorf("Home(2) = %v after unfollowing, want none", got)
+	}
+	tl.ChirpSaved(db.Chirp{ID: 2, UserID: 3})
+	if got := tl.Home(1); !slices.Equal(got, []int{2, 1}) {
+		t.Errorf("Home(1) = %v, want [2 1]", got)
+	}
+}
+
+func TestUnfollowAndDeleteRemoveChirps(t *testing.T) {
+	tl := New(Config{FanoutLimit: 5, InboxSize: 10})
+	tl.Follow(1, 2)
+	tl.Follow(1, 3)
+	tl.ChirpSaved(db.Chirp{ID: 1, UserID: 2})
+	tl.ChirpSaved(db.Chirp{ID: 2, UserID: 3})
+	tl.ChirpSaved(db.Chirp{ID: 3, UserID: 3})
+
+	tl.ChirpDeleted(3)
+	tl.Unfollow(1, 2)
+	if got := tl.Home(1); !slices.Equal(got, []int{2}) {
+		t.Errorf("Home(1) = %v, want [2]", got)
+	}
+}
+
+func TestInboxSizeCapsHome(t *testing.T) {
+	tl := New(Config{FanoutLimit: 5, InboxSize: 2})
+	tl.Follow(1, 2)
+	for id := 1; id <= 4; id++ {
+		tl.ChirpSaved(db.Chirp{ID: id, UserID: 2})
+	}
+	if got := tl.Home(1); !slices.Equal(got, []int{4, 3}) {
+		t.Errorf("Home(1) = %v, want [4 3]", got)
+	}
+}
@@ -0,0 +1,108 @@
+package trending
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+)
+
+// Tracker counts hashtag use over a sliding window. It implements
+// db.ChirpObserver; chirps older than the window are forgotten.
+type Tracker struct {
+	mu     *sync.Mutex
+	window time.Duration
+	uses   map[int]use
+}
+
+type use struct {
+	tags []string
+	at   time.Time
+}
+
+type Topic struct {
+	Tag      string    `json:"tag"`
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+func New(window time.Duration) *Tracker {
+	return &Tracker{
+		mu:     &sync.Mutex{},
+		window: window,
+		uses:   make(map[int]use),
+	}
+}
+
+func (t *Tracker) Rebuild(chirps map[int]db.Chirp, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.uses = make(map[int]use)
+	for _, chirp := range chirps {
+		t.record(chirp, now)
+	}
+}
+
+func (t *Tracker) ChirpSaved(chirp db.Chirp) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.record(chirp, time.Now())
+}
+
+func (t *Tracker) ChirpDeleted(chirpID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.uses, chirpID)
+}
+
+// Top returns the most used hashtags in the window ending at now. Ties go to
+// the most recently used tag.
+func (t *Tracker) Top(limit int, now time.Time) []Topic {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	topics := map[string]*Topic{}
+	for chirpID, u := range t.uses {
+		if now.Sub(u.at) > t.window {
+			delete(t.uses, chirpID)
+			continue
+		}
+		for _, tag := range u.tags {
+			topic, ok := topics[tag]
+			if !ok {
+				topic = &Topic{Tag: tag}
+				topics[tag] = topic
+			}
+			topic.Count++
+			if u.at.After(topic.LastUsed) {
+				topic.LastUsed = u.at
+			}
+		}
+	}
+
+	top := make([]Topic, 0, len(topics))
+	for _, topic := range topics {
+		top = append(top, *topic)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		if !top[i].LastUsed.Equal(top[j].LastUsed) {
+			return top[i].LastUsed.After(top[j].LastUsed)
+		}
+		return top[i].Tag < top[j].Tag
+	})
+	return top[:min(limit, len(top))]
+}
+
+// record counts a chirp's tags at its creation time, replacing the tags of an
+// earlier version if it was edited.
+func (t *Tracker) record(chirp db.Chirp, now time.Time) {
+	tags := chirp.Entities.Tags()
+	if len(tags) == 0 || now.Sub(chirp.CreatedAt) > t.window {
+		delete(t.uses, chirp.ID)
+		return
+	}
+	t.uses[chirp.ID] = use{tags: tags, at: chirp.CreatedAt}
+}
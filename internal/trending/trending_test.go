@@ -0,0 +1,69 @@
+package trending
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entities"
+)
+
+var start = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func tagged(id int, body string, at time.Time) db.Chirp {
+	return db.Chirp{ID: id, Body: body, Entities: entities.Parse(body), CreatedAt: at}
+}
+
+func tags(topics []Topic) []string {
+	names := make([]string, len(topics))
+	for i, topic := range topics {
+		names[i] = topic.Tag
+	}
+	return names
+}
+
+func TestTopRanksByCountThenRecency(t *testing.T) {
+	tracker := New(time.Hour)
+	tracker.Rebuild(map[int]db.Chirp{
+		1: tagged(1, "#go #rust", start),
+		2: tagged(2, "#go #go", start.Add(time.Minute)),
+		3: tagged(3, "#zig", start.Add(2*time.Minute)),
+		4: tagged(4, "#rust", start.Add(3*time.Minute)),
+		5: tagged(5, "#old", start.Add(-2*time.Hour)),
+		6: tagged(6, "no tags", start),
+	}, start.Add(5*time.Minute))
+
+	top := tracker.Top(10, start.Add(5*time.Minute))
+	want := []Topic{
+		{Tag: "rust", Count: 2, LastUsed: start.Add(3 * time.Minute)},
+		{Tag: "go", Count: 2, LastUsed: start.Add(time.Minute)},
+		{Tag: "zig", Count: 1, LastUsed: start.Add(2 * time.Minute)},
+	}
+	if len(top) != len(want) {
+		t.Fatalf("Top = %+v, want %+v", top, want)
+	}
+	for i := range want {
+		if top[i].Tag != want[i].Tag || top[i].Count != want[i].Count || !top[i].LastUsed.Equal(want[i].LastUsed) {
+			t.Errorf("Top[%d] = %+v, want %+v", i, top[i], want[i])
+		}
+	}
+	if got := tags(tracker.Top(1, start.Add(5*time.Minute))); len(got) != 1 || got[0] != "rust" {
+		t.Errorf("Top(1) = %v, want [rust]", got)
+	}
+}
+
+func TestTopFollowsEditsDeletesAndTheWindow(t *testing.T) {
+	tracker := New(time.Hour)
+	tracker.ChirpSaved(tagged(1, "#go", time.Now()))
+	tracker.ChirpSaved(tagged(2, "#go", time.Now()))
+	tracker.ChirpSaved(tagged(1, "now about #rust", time.Now()))
+	tracker.ChirpDeleted(2)
+
+	top := tracker.Top(10, time.Now())
+	if len(top) != 1 || top[0].Tag != "rust" || top[0].Count != 1 {
+		t.Errorf("Top = %+v, want rust once", top)
+	}
+	if top := tracker.Top(10, time.Now().Add(2*time.Hour)); len(top) != 0 {
+		t.Errorf("Top after the window = %+v, want none", top)
+	}
+}
@@ -0,0 +1,134 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var errMalformedCBOR = errors.New("malformed cbor")
+
+// decodeCBOR decodes the subset of CBOR (RFC 8949) used by WebAuthn
+// attestation objects and COSE keys. It returns the decoded value and the
+// number of bytes consumed. Maps decode to map[any]any with int64 or string
+// keys; byte strings to []byte; integers to int64.
+func decodeCBOR(data []byte) (any, int, error) {
+	return decodeItem(data, 0)
+}
+
+func decodeItem(data []byte, depth int) (any, int, error) {
+	if len(data) == 0 || depth > 16 {
+		return nil, 0, errMalformedCBOR
+	}
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, 1, nil
+		case 21:
+			return true, 1, nil
+		case 22, 23:
+			return nil, 1, nil
+		case 26:
+			if len(data) < 5 {
+				return nil, 0, errMalformedCBOR
+			}
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(data[1:5]))), 5, nil
+		case 27:
+			if len(data) < 9 {
+				return nil, 0, errMalformedCBOR
+			}
+			return math.Float64frombits(binary.BigEndian.Uint64(data[1:9])), 9, nil
+		}
+		return nil, 0, errMalformedCBOR
+	}
+
+	arg, n, err := readArgument(data, info)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, 0, errMalformedCBOR
+		}
+		return int64(arg), n, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, 0, errMalformedCBOR
+		}
+		return -1 - int64(arg), n, nil
+	case 2, 3:
+		if uint64(len(data)-n) < arg {
+			return nil, 0, errMalformedCBOR
+		}
+		end := n + int(arg)
+		if major == 2 {
+			return append([]byte{}, data[n:end]...), end, nil
+		}
+		return string(data[n:end]), end, nil
+	case 4:
+		if arg > uint64(len(data)) {
+			return nil, 0, errMalformedCBOR
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, size, err := decodeItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			n += size
+		}
+		return items, n, nil
+	case 5:
+		if arg > uint64(len(data)) {
+			return nil, 0, errMalformedCBOR
+		}
+		items := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, size, err := decodeItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += size
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, errMalformedCBOR
+			}
+			value, size, err := decodeItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += size
+			items[key] = value
+		}
+		return items, n, nil
+	case 6:
+		// Tags carry no meaning for WebAuthn; return the tagged item.
+		item, size, err := decodeItem(data[n:], depth+1)
+		return item, n + size, err
+	}
+	return nil, 0, errMalformedCBOR
+}
+
+func readArgument(data []byte, info byte) (uint64, int, error) {
+	switch {
+	case info < 24:
+		return uint64(info), 1, nil
+	case info == 24 && len(data) >= 2:
+		return uint64(data[1]), 2, nil
+	case info == 25 && len(data) >= 3:
+		return uint64(binary.BigEndian.Uint16(data[1:3])), 3, nil
+	case info == 26 && len(data) >= 5:
+		return uint64(binary.BigEndian.Uint32(data[1:5])), 5, nil
+	case info == 27 && len(data) >= 9:
+		return binary.BigEndian.Uint64(data[1:9]), 9, nil
+	}
+	// Indefinite-length items are not produced by authenticators.
+	return 0, 0, errMalformedCBOR
+}
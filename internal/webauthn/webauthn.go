@@ -0,0 +1,323 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/LoreviQ/PrivateWebServer/internal/auth"
+)
+
+var ErrUnknownChallenge = errors.New("unknown or expired challenge")
+var ErrCredentialCloned = errors.New("signature counter did not increase")
+
+const (
+	ceremonyTimeout = 5 * time.Minute
+
+	flagUserPresent  = 0x01
+	flagAttestedData = 0x40
+
+	algES256 = -7
+	algEdDSA = -8
+	algRS256 = -257
+)
+
+var SupportedAlgorithms = []int{algES256, algEdDSA, algRS256}
+
+type Config struct {
+	RPID   string
+	RPName string
+	Origin string
+}
+
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type session struct {
+	userID  int
+	create  bool
+	expires time.Time
+}
+
+// WebAuthn verifies registration and assertion ceremonies for a single
+// relying party. Outstanding challenges are held in memory until used.
+type WebAuthn struct {
+	cfg      Config
+	sessions map[string]session
+	mu       *sync.Mutex
+}
+
+func New(cfg Config) *WebAuthn {
+	return &WebAuthn{
+		cfg:      cfg,
+		sessions: make(map[string]session),
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (wa *WebAuthn) Config() Config {
+	return wa.cfg
+}
+
+func (wa *WebAuthn) BeginRegistration(userID int) (string, error) {
+	return wa.newChallenge(userID, true)
+}
+
+// BeginLogin issues an assertion challenge. userID is zero for usernameless
+// logins with discoverable credentials.
+func (wa *WebAuthn) BeginLogin(userID int) (string, error) {
+	return wa.newChallenge(userID, false)
+}
+
+// FinishRegistration verifies a registration response and returns the new
+// credential. Attestation statements are not verified, matching the "none"
+// conveyance preference sent to clients.
+func (wa *WebAuthn) FinishRegistration(userID int, clientDataJSON, attestationObject []byte) (Credential, error) {
+	err := wa.verifyClientData(clientDataJSON, "webauthn.create", userID)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return Credential{}, err
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return Credential{}, errors.New("attestation object is not a map")
+	}
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Credential{}, errors.New("attestation object has no authData")
+	}
+
+	signCount, flags, err := wa.verifyAuthData(authData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if flags&flagAttestedData == 0 {
+		return Credential{}, errors.New("authenticator data has no attested credential")
+	}
+	rest := authData[37:]
+	if len(rest) < 18 {
+		return Credential{}, errors.New("attested credential data too short")
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return Credential{}, errors.New("attested credential data too short")
+	}
+	credentialID := append([]byte{}, rest[:idLength]...)
+	_, keyLength, err := decodeCBOR(rest[idLength:])
+	if err != nil {
+		return Credential{}, err
+	}
+	publicKey := append([]byte{}, rest[idLength:idLength+keyLength]...)
+	_, err = parsePublicKey(publicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:        credentialID,
+		PublicKey: publicKey,
+		SignCount: signCount,
+	}, nil
+}
+
+// FinishLogin verifies an assertion made with credential and returns the
+// authenticator's new signature counter.
+func (wa *WebAuthn) FinishLogin(userID int, credential Credential, clientDataJSON, authData, signature []byte) (uint32, error) {
+	err := wa.verifyClientData(clientDataJSON, "webauthn.get", userID)
+	if err != nil {
+		return 0, err
+	}
+	signCount, _, err := wa.verifyAuthData(authData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	err = verifySignature(key, signed, signature)
+	if err != nil {
+		return 0, err
+	}
+
+	// Authenticators without counters always report zero.
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		return 0, ErrCredentialCloned
+	}
+	return signCount, nil
+}
+
+func (wa *WebAuthn) newChallenge(userID int, create bool) (string, error) {
+	challenge, err := auth.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	now := time.Now()
+	for key, s := range wa.sessions {
+		if now.After(s.expires) {
+			delete(wa.sessions, key)
+		}
+	}
+	wa.sessions[challenge] = session{
+		userID:  userID,
+		create:  create,
+		expires: now.Add(ceremonyTimeout),
+	}
+	return challenge, nil
+}
+
+// verifyClientData checks the collected client data and consumes the
+// challenge it contains. A challenge issued for a specific user can only be
+// answered by that user; userID is zero when the caller does not yet know who
+// is logging in.
+func (wa *WebAuthn) verifyClientData(clientDataJSON []byte, ceremony string, userID int) error {
+	var clientData struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	err := json.Unmarshal(clientDataJSON, &clientData)
+	if err != nil {
+		return err
+	}
+	if clientData.Type != ceremony {
+		return errors.New("client data has wrong type")
+	}
+	if clientData.Origin != wa.cfg.Origin {
+		return errors.New("client data has wrong origin")
+	}
+
+	wa.mu.Lock()
+	s, ok := wa.sessions[clientData.Challenge]
+	delete(wa.sessions, clientData.Challenge)
+	wa.mu.Unlock()
+	if !ok || time.Now().After(s.expires) || s.create != (ceremony == "webauthn.create") {
+		return ErrUnknownChallenge
+	}
+	if s.userID != 0 && userID != 0 && s.userID != userID {
+		return ErrUnknownChallenge
+	}
+	return nil
+}
+
+func (wa *WebAuthn) verifyAuthData(authData []byte) (uint32, byte, error) {
+	if len(authData) < 37 {
+		return 0, 0, errors.New("authenticator data too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(wa.cfg.RPID))
+	if !bytes.Equal(authData[:32], rpIDHash[:]) {
+		return 0, 0, errors.New("authenticator data has wrong rp id")
+	}
+	flags := authData[32]
+	if flags&flagUserPresent == 0 {
+		return 0, 0, errors.New("user was not present")
+	}
+	return binary.BigEndian.Uint32(authData[33:37]), flags, nil
+}
+
+func parsePublicKey(coseKey []byte) (crypto.PublicKey, error) {
+	decoded, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, errors.New("public key is not a map")
+	}
+	alg, _ := key[int64(3)].(int64)
+	switch alg {
+	case algES256:
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if crv, _ := key[int64(-1)].(int64); crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("unsupported ec2 key")
+		}
+		_, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, errors.New("ec2 key is not on curve")
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case algEdDSA:
+		x, _ := key[int64(-2)].([]byte)
+		if crv, _ := key[int64(-1)].(int64); crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("unsupported okp key")
+		}
+		return ed25519.PublicKey(x), nil
+	case algRS256:
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 {
+			return nil, errors.New("unsupported rsa key")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+	return nil, errors.New("unsupported public key algorithm")
+}
+
+func verifySignature(key crypto.PublicKey, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, signed, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature)
+	}
+	return errors.New("unsupported key type")
+}
+
+func Encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode accepts base64url with or without padding, as browsers and
+// libraries disagree on which to send.
+func Decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(trimPadding(s))
+}
+
+func trimPadding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,380 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// encodeCBOR encodes the types decodeCBOR produces, with map keys sorted so
+// output is deterministic.
+func encodeCBOR(value any) []byte {
+	header := func(major byte, arg uint64) []byte {
+		switch {
+		case arg < 24:
+			return []byte{major<<5 | byte(arg)}
+		case arg <= 0xff:
+			return []byte{major<<5 | 24, byte(arg)}
+		case arg <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+		case arg <= 0xffffffff:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+		}
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+	}
+	switch v := value.(type) {
+	case int:
+		return encodeCBOR(int64(v))
+	case int64:
+		if v < 0 {
+			return header(1, uint64(-1-v))
+		}
+		return header(0, uint64(v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case []any:
+		out := header(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case map[any]any:
+		keys := make([][]byte, 0, len(v))
+		encoded := map[string][]byte{}
+		for key, item := range v {
+			k := encodeCBOR(key)
+			keys = append(keys, k)
+			encoded[string(k)] = encodeCBOR(item)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		out := header(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(append(out, k...), encoded[string(k)]...)
+		}
+		return out
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case nil:
+		return []byte{0xf6}
+	}
+	panic("unsupported cbor value")
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  any
+	}{
+		{"small int", []byte{0x17}, int64(23)},
+		{"one byte int", []byte{0x18, 0x18}, int64(24)},
+		{"two byte int", []byte{0x19, 0x01, 0x00}, int64(256)},
+		{"negative int", []byte{0x20}, int64(-1)},
+		{"cose rs256", []byte{0x39, 0x01, 0x00}, int64(-257)},
+		{"byte string", []byte{0x43, 1, 2, 3}, []byte{1, 2, 3}},
+		{"text string", []byte{0x63, 'f', 'm', 't'}, "fmt"},
+		{"array", []byte{0x82, 0x01, 0x61, 'a'}, []any{int64(1), "a"}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x20, 0x41, 0xff}, map[any]any{int64(1): int64(2), int64(-1): []byte{0xff}}},
+		{"true", []byte{0xf5}, true},
+		{"null", []byte{0xf6}, nil},
+		{"float32", []byte{0xfa, 0x3f, 0xc0, 0x00, 0x00}, float64(1.5)},
+		{"tag", []byte{0xc2, 0x41, 0x01}, []byte{0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := decodeCBOR(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(tt.input) {
+				t.Errorf("consumed %d bytes, want %d", n, len(tt.input))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORConsumesOneItem(t *testing.T) {
+	got, n, err := decodeCBOR([]byte{0x01, 0x02})
+	if err != nil || got != int64(1) || n != 1 {
+		t.Errorf("got %v, %d, %v; want 1, 1, nil", got, n, err)
+	}
+}
+
+func TestDecodeCBORRejectsMalformed(t *testing.T) {
+	deep := bytes.Repeat([]byte{0x81}, 20)
+	deep = append(deep, 0x01)
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty", nil},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"truncated byte string", []byte{0x45, 1, 2}},
+		{"huge byte string", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge array", []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{"truncated map", []byte{0xa1, 0x01}},
+		{"array map key", []byte{0xa1, 0x80, 0x01}},
+		{"indefinite length", []byte{0x5f, 0x41, 0x01, 0xff}},
+		{"unsigned overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"unknown simple", []byte{0xf8, 0x20}},
+		{"too deep", deep},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeCBOR(tt.input)
+			if err == nil {
+				t.Error("decodeCBOR accepted malformed input")
+			}
+		})
+	}
+}
+
+// authenticator is a software authenticator holding one credential.
+type authenticator struct {
+	rpID      string
+	origin    string
+	id        []byte
+	signCount uint32
+	coseKey   []byte
+	sign      func(message []byte) []byte
+}
+
+func newES256Authenticator(t *testing.T, rpID, origin string) *authenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coseKey := encodeCBOR(map[any]any{
+		1:  2,
+		3:  algES256,
+		-1: 1,
+		-2: key.X.FillBytes(make([]byte, 32)),
+		-3: key.Y.FillBytes(make([]byte, 32)),
+	})
+	return &authenticator{rpID: rpID, origin: origin, id: []byte("es256-credential"), coseKey: coseKey, sign: func(message []byte) []byte {
+		digest := sha256.Sum256(message)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}}
+}
+
+func newEd25519Authenticator(t *testing.T, rpID, origin string) *authenticator {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coseKey := encodeCBOR(map[any]any{1: 1, 3: algEdDSA, -1: 6, -2: []byte(public)})
+	return &authenticator{rpID: rpID, origin: origin, id: []byte("ed25519-credential"), coseKey: coseKey, sign: func(message []byte) []byte {
+		return ed25519.Sign(private, message)
+	}}
+}
+
+func (a *authenticator) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": a.origin})
+	return data
+}
+
+func (a *authenticator) authData(flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.id)))
+		data = append(append(data, a.id...), a.coseKey...)
+	}
+	return data
+}
+
+func (a *authenticator) register(challenge string) (clientData, attestationObject []byte) {
+	a.signCount++
+	attestation := map[any]any{
+		"fmt":      "none",
+		"attStmt":  map[any]any{},
+		"authData": a.authData(flagUserPresent|flagAttestedData, true),
+	}
+	return a.clientData("webauthn.create", challenge), encodeCBOR(attestation)
+}
+
+func (a *authenticator) assert(challenge string) (clientData, authData, signature []byte) {
+	a.signCount++
+	clientData = a.clientData("webauthn.get", challenge)
+	authData = a.authData(flagUserPresent, false)
+	clientDataHash := sha256.Sum256(clientData)
+	return clientData, authData, a.sign(append(append([]byte{}, authData...), clientDataHash[:]...))
+}
+
+func newTestWebAuthn() *WebAuthn {
+	return New(Config{RPID: "localhost", RPName: "Chirpy", Origin: "http://localhost:8080"})
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	for _, newAuthenticator := range []func(*testing.T, string, string) *authenticator{newES256Authenticator, newEd25519Authenticator} {
+		wa := newTestWebAuthn()
+		device := newAuthenticator(t, "localhost", "http://localhost:8080")
+
+		challenge, err := wa.BeginRegistration(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientData, attestationObject := device.register(challenge)
+		credential, err := wa.FinishRegistration(1, clientData, attestationObject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(credential.ID, device.id) || credential.SignCount != 1 {
+			t.Fatalf("unexpected credential %+v", credential)
+		}
+
+		// Usernameless login, as with a discoverable credential.
+		challenge, err = wa.BeginLogin(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientData, authData, signature := device.assert(challenge)
+		signCount, err := wa.FinishLogin(1, credential, clientData, authData, signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signCount != 2 {
+			t.Errorf("signCount = %d, want 2", signCount)
+		}
+	}
+}
+
+func TestLoginRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		device  func(t *testing.T) *authenticator
+		userID  int
+		tamper  func(clientData, authData, signature []byte) ([]byte, []byte, []byte)
+		counter uint32
+		wantErr error
+	}{
+		{name: "wrong origin", device: func(t *testing.T) *authenticator {
+			return newES256Authenticator(t, "localhost", "https://evil.example")
+		}},
+		{name: "wrong rp id", device: func(t *testing.T) *authenticator {
+			return newES256Authenticator(t, "evil.example", "http://localhost:8080")
+		}},
+		{name: "challenge for another user", userID: 2, wantErr: ErrUnknownChallenge},
+		{name: "user not present", tamper: func(c, a, s []byte) ([]byte, []byte, []byte) {
+			a[32] &^= flagUserPresent
+			return c, a, s
+		}},
+		{name: "bad signature", tamper: func(c, a, s []byte) ([]byte, []byte, []byte) {
+			s[len(s)-1] ^= 0xff
+			return c, a, s
+		}},
+		{name: "signed different client data", tamper: func(c, a, s []byte) ([]byte, []byte, []byte) {
+			return bytes.Replace(c, []byte(`"origin"`), []byte(`"origin" `), 1), a, s
+		}},
+		{name: "counter went backwards", counter: 10, wantErr: ErrCredentialCloned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := newTestWebAuthn()
+			device := newES256Authenticator(t, "localhost", "http://localhost:8080")
+			if tt.device != nil {
+				device = tt.device(t)
+			}
+			credential := Credential{ID: device.id, PublicKey: device.coseKey, SignCount: tt.counter}
+			challenge, err := wa.BeginLogin(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientData, authData, signature := device.assert(challenge)
+			if tt.tamper != nil {
+				clientData, authData, signature = tt.tamper(clientData, authData, signature)
+			}
+			userID := 1
+			if tt.userID != 0 {
+				userID = tt.userID
+			}
+			_, err = wa.FinishLogin(userID, credential, clientData, authData, signature)
+			if err == nil {
+				t.Fatal("FinishLogin accepted the assertion")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChallengesAreSingleUse(t *testing.T) {
+	wa := newTestWebAuthn()
+	device := newES256Authenticator(t, "localhost", "http://localhost:8080")
+	credential := Credential{ID: device.id, PublicKey: device.coseKey}
+	challenge, err := wa.BeginLogin(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientData, authData, signature := device.assert(challenge)
+	_, err = wa.FinishLogin(1, credential, clientData, authData, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = wa.FinishLogin(1, credential, clientData, authData, signature)
+	if !errors.Is(err, ErrUnknownChallenge) {
+		t.Errorf("replayed assertion: err = %v, want ErrUnknownChallenge", err)
+	}
+}
+
+func TestRegistrationChallengeCannotLogIn(t *testing.T) {
+	wa := newTestWebAuthn()
+	device := newES256Authenticator(t, "localhost", "http://localhost:8080")
+	challenge, err := wa.BeginRegistration(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientData, authData, signature := device.assert(challenge)
+	credential := Credential{ID: device.id, PublicKey: device.coseKey}
+	_, err = wa.FinishLogin(1, credential, clientData, authData, signature)
+	if !errors.Is(err, ErrUnknownChallenge) {
+		t.Errorf("err = %v, want ErrUnknownChallenge", err)
+	}
+}
+
+func TestParsePublicKeyRejectsBadKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  map[any]any
+	}{
+		{"unknown algorithm", map[any]any{1: 2, 3: -35}},
+		{"wrong curve", map[any]any{1: 2, 3: algES256, -1: 2, -2: make([]byte, 32), -3: make([]byte, 32)}},
+		{"point off curve", map[any]any{1: 2, 3: algES256, -1: 1, -2: make([]byte, 32), -3: make([]byte, 32)}},
+		{"short ed25519", map[any]any{1: 1, 3: algEdDSA, -1: 6, -2: make([]byte, 16)}},
+		{"short rsa", map[any]any{1: 3, 3: algRS256, -1: make([]byte, 128), -2: []byte{1, 0, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePublicKey(encodeCBOR(tt.key))
+			if err == nil {
+				t.Error("parsePublicKey accepted a bad key")
+			}
+		})
+	}
+}
@@ -4,9 +4,23 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/LoreviQ/PrivateWebServer/internal/activitypub"
+	"github.com/LoreviQ/PrivateWebServer/internal/audit"
 	"github.com/LoreviQ/PrivateWebServer/internal/db"
+	"github.com/LoreviQ/PrivateWebServer/internal/entitlements"
 	"github.com/LoreviQ/PrivateWebServer/internal/hdl"
+	"github.com/LoreviQ/PrivateWebServer/internal/moderation"
+	"github.com/LoreviQ/PrivateWebServer/internal/oidc"
+	"github.com/LoreviQ/PrivateWebServer/internal/scheduler"
+	"github.com/LoreviQ/PrivateWebServer/internal/search"
+	"github.com/LoreviQ/PrivateWebServer/internal/stream"
+	"github.com/LoreviQ/PrivateWebServer/internal/timeline"
+	"github.com/LoreviQ/PrivateWebServer/internal/trending"
+	"github.com/LoreviQ/PrivateWebServer/internal/webauthn"
 	"github.com/joho/godotenv"
 )
 
@@ -26,7 +40,92 @@ func initialiseServer(cfg hdl.ApiConfig, mux *http.ServeMux) *http.Server {
 	mux.HandleFunc("POST /api/refresh", cfg.PostRefreshHandler)
 	mux.HandleFunc("POST /api/revoke", cfg.PostRevokeHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.DeleteChirpHandler)
+	mux.HandleFunc("PATCH /api/chirps/{chirpID}", cfg.PatchChirpHandler)
+	mux.HandleFunc("GET /api/chirps/{id}/history", cfg.GetChirpHistoryHandler)
+	mux.HandleFunc("GET /api/chirps/{id}/thread", cfg.GetChirpThreadHandler)
+	mux.HandleFunc("POST /api/drafts", cfg.PostDraftHandler)
+	mux.HandleFunc("GET /api/drafts", cfg.GetDraftsHandler)
+	mux.HandleFunc("PUT /api/drafts/{id}", cfg.PutDraftHandler)
+	mux.HandleFunc("DELETE /api/drafts/{id}", cfg.DeleteDraftHandler)
+	mux.HandleFunc("POST /api/drafts/{id}/publish", cfg.PostPublishDraftHandler)
+	mux.HandleFunc("POST /api/scheduled", cfg.PostScheduledChirpHandler)
+	mux.HandleFunc("GET /api/scheduled", cfg.GetScheduledChirpsHandler)
+	mux.HandleFunc("DELETE /api/scheduled/{id}", cfg.DeleteScheduledChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", cfg.PostLikeHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", cfg.DeleteLikeHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/repost", cfg.PostRepostHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/repost", cfg.DeleteRepostHandler)
 	mux.HandleFunc("POST /api/polka/webhooks", cfg.PostPolkaWebhook)
+	mux.HandleFunc("GET /api/oidc/login", cfg.GetOIDCLoginHandler)
+	mux.HandleFunc("GET /api/oidc/callback", cfg.GetOIDCCallbackHandler)
+	mux.HandleFunc("POST /api/oauth/clients", cfg.PostOAuthClientHandler)
+	mux.HandleFunc("GET /oauth/authorize", cfg.GetOAuthAuthorizeHandler)
+	mux.HandleFunc("POST /oauth/authorize", cfg.PostOAuthAuthorizeHandler)
+	mux.HandleFunc("POST /oauth/token", cfg.PostOAuthTokenHandler)
+	mux.HandleFunc("POST /oauth/introspect", cfg.PostOAuthIntrospectHandler)
+	mux.HandleFunc("POST /oauth/revoke", cfg.PostOAuthRevokeHandler)
+	mux.HandleFunc("POST /api/webauthn/register/begin", cfg.PostWebAuthnRegisterBeginHandler)
+	mux.HandleFunc("POST /api/webauthn/register/finish", cfg.PostWebAuthnRegisterFinishHandler)
+	mux.HandleFunc("POST /api/webauthn/login/begin", cfg.PostWebAuthnLoginBeginHandler)
+	mux.HandleFunc("POST /api/webauthn/login/finish", cfg.PostWebAuthnLoginFinishHandler)
+	mux.HandleFunc("GET /admin/audit", cfg.GetAuditLogHandler)
+	mux.HandleFunc("GET /api/users/activity", cfg.GetUserActivityHandler)
+	mux.HandleFunc("GET /admin/webhooks", cfg.GetWebhooksHandler)
+	mux.HandleFunc("GET /api/users/subscription", cfg.GetSubscriptionHandler)
+	mux.HandleFunc("GET /api/users/entitlements", cfg.GetEntitlementsHandler)
+	mux.HandleFunc("GET /api/search", cfg.GetSearchHandler)
+	mux.HandleFunc("POST /api/users/{id}/follow", cfg.PostFollowHandler)
+	mux.HandleFunc("DELETE /api/users/{id}/follow", cfg.DeleteFollowHandler)
+	mux.HandleFunc("GET /api/users/{id}/{list}", cfg.GetFollowListHandler)
+	mux.HandleFunc("GET /api/users/{id}", cfg.GetProfileHandler)
+	mux.HandleFunc("GET /api/users/by-handle/{handle}", cfg.GetProfileByHandleHandler)
+	mux.HandleFunc("PATCH /api/users/profile", cfg.PatchProfileHandler)
+	mux.HandleFunc("POST /api/users/{id}/block", cfg.PostBlockHandler)
+	mux.HandleFunc("DELETE /api/users/{id}/block", cfg.DeleteBlockHandler)
+	mux.HandleFunc("POST /api/users/{id}/mute", cfg.PostMuteHandler)
+	mux.HandleFunc("DELETE /api/users/{id}/mute", cfg.DeleteMuteHandler)
+	mux.HandleFunc("GET /api/users/blocks", cfg.GetBlocksHandler)
+	mux.HandleFunc("GET /api/users/mutes", cfg.GetMutesHandler)
+	mux.HandleFunc("GET /api/timeline", cfg.GetTimelineHandler)
+	mux.HandleFunc("GET /api/stream", cfg.GetStreamHandler)
+	mux.HandleFunc("GET /api/stream/ws", cfg.GetStreamWebSocketHandler)
+	mux.HandleFunc("GET /api/hashtags/trending", cfg.GetTrendingHandler)
+	mux.HandleFunc("GET /api/hashtags/{tag}", cfg.GetHashtagHandler)
+	mux.HandleFunc("GET /users/{id}/{feed}", cfg.GetUserFeedHandler)
+	mux.HandleFunc("GET /.well-known/webfinger", cfg.GetWebFingerHandler)
+	mux.HandleFunc("GET /users/{id}", cfg.GetActorHandler)
+	mux.HandleFunc("GET /users/{id}/outbox", cfg.GetOutboxHandler)
+	mux.HandleFunc("GET /users/{id}/followers", cfg.GetActorFollowersHandler)
+	mux.HandleFunc("POST /users/{id}/inbox", cfg.PostInboxHandler)
+	mux.HandleFunc("POST /inbox", cfg.PostInboxHandler)
+	mux.HandleFunc("GET /notes/{id}", cfg.GetNoteHandler)
+	mux.HandleFunc("POST /api/federation/follows", cfg.PostRemoteFollowHandler)
+	mux.HandleFunc("DELETE /api/federation/follows", cfg.DeleteRemoteFollowHandler)
+	mux.HandleFunc("GET /api/federation/follows", cfg.GetRemoteFollowingHandler)
+	mux.HandleFunc("GET /api/federation/notes", cfg.GetRemoteNotesHandler)
+	mux.HandleFunc("GET /api/chirps/{id}/remote-replies", cfg.GetRemoteRepliesHandler)
+	mux.HandleFunc("GET /hashtags/{tag}/{feed}", cfg.GetHashtagFeedHandler)
+	mux.HandleFunc("GET /api/notifications", cfg.GetNotificationsHandler)
+	mux.HandleFunc("POST /api/notifications/read", cfg.PostNotificationsReadHandler)
+	mux.HandleFunc("GET /api/notifications/preferences", cfg.GetNotificationPreferencesHandler)
+	mux.HandleFunc("PUT /api/notifications/preferences", cfg.PutNotificationPreferencesHandler)
+	mux.HandleFunc("POST /api/reports", cfg.PostReportHandler)
+	mux.HandleFunc("GET /api/moderation/actions", cfg.GetMyModerationActionsHandler)
+	mux.HandleFunc("POST /api/appeals", cfg.PostAppealHandler)
+	mux.HandleFunc("GET /api/appeals", cfg.GetMyAppealsHandler)
+	mux.HandleFunc("GET /admin/reports", cfg.GetReportsHandler)
+	mux.HandleFunc("POST /admin/reports/{id}/claim", cfg.PostClaimReportHandler)
+	mux.HandleFunc("POST /admin/reports/{id}/resolve", cfg.PostResolveReportHandler)
+	mux.HandleFunc("POST /admin/chirps/{id}/hide", cfg.PostHideChirpHandler)
+	mux.HandleFunc("POST /admin/chirps/{id}/unhide", cfg.PostUnhideChirpHandler)
+	mux.HandleFunc("DELETE /admin/chirps/{id}", cfg.DeleteModeratedChirpHandler)
+	mux.HandleFunc("POST /admin/users/{id}/suspend", cfg.PostSuspendUserHandler)
+	mux.HandleFunc("POST /admin/users/{id}/unsuspend", cfg.PostUnsuspendUserHandler)
+	mux.HandleFunc("GET /admin/moderation/actions", cfg.GetModerationActionsHandler)
+	mux.HandleFunc("GET /admin/appeals", cfg.GetAppealsHandler)
+	mux.HandleFunc("POST /admin/appeals/{id}/resolve", cfg.PostResolveAppealHandler)
+	mux.HandleFunc("PUT /admin/moderators/{id}", cfg.PutModeratorHandler)
+	mux.HandleFunc("DELETE /admin/moderators/{id}", cfg.DeleteModeratorHandler)
 
 	corsMux := cfg.CorsMiddleware(mux)
 
@@ -37,19 +136,127 @@ func initialiseServer(cfg hdl.ApiConfig, mux *http.ServeMux) *http.Server {
 	return server
 }
 
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseIDs(list string) []int {
+	ids := []int{}
+	for _, field := range strings.Split(list, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func main() {
 	godotenv.Load()
 	cfg := hdl.ApiConfig{
-		Port:           "8080",
-		DB_Directory:   "./database/database.json",
-		JWT_Secret:     []byte(os.Getenv("JWT_SECRET")),
-		FileserverHits: 0,
+		Port:            getEnv("PORT", "8080"),
+		DB_Directory:    "./database/database.json",
+		Audit_Directory: "./database/audit.log",
+		JWT_Secret:      []byte(os.Getenv("JWT_SECRET")),
+		Polka_Secret:    []byte(os.Getenv("POLKA_WEBHOOK_SECRET")),
+		FileserverHits:  0,
+		Admins:          parseIDs(os.Getenv("ADMIN_USER_IDS")),
+	}
+	if len(cfg.Polka_Secret) == 0 {
+		log.Panic("POLKA_WEBHOOK_SECRET must be set")
 	}
 	cfg.HandleFlags()
+	cfg.BaseURL = strings.TrimSuffix(getEnv("BASE_URL", "http://localhost:"+cfg.Port), "/")
 	cfg.DB = db.InitialiseDatabase(cfg.DB_Directory)
+	visibleChirps := cfg.DB.VisibleChirps()
+	cfg.Search = search.New()
+	cfg.Search.Rebuild(visibleChirps)
+	cfg.DB.ObserveChirps(cfg.Search)
+	fanoutLimit, err := strconv.Atoi(getEnv("TIMELINE_FANOUT_LIMIT", "1000"))
+	if err != nil {
+		log.Panicf("Invalid TIMELINE_FANOUT_LIMIT: %s", err)
+	}
+	cfg.Timeline = timeline.New(timeline.Config{FanoutLimit: fanoutLimit, InboxSize: 800})
+	cfg.Timeline.Rebuild(cfg.DB.Follows, visibleChirps)
+	cfg.DB.ObserveChirps(cfg.Timeline)
+	trendingWindow, err := time.ParseDuration(getEnv("TRENDING_WINDOW", "24h"))
+	if err != nil {
+		log.Panicf("Invalid TRENDING_WINDOW: %s", err)
+	}
+	cfg.Trending = trending.New(trendingWindow)
+	cfg.Trending.Rebuild(visibleChirps, time.Now())
+	cfg.DB.ObserveChirps(cfg.Trending)
+	cfg.Stream = stream.New(stream.Config{Backlog: 1000, BufferSize: 64})
+	cfg.Stream.Rebuild(visibleChirps)
+	cfg.DB.ObserveChirps(cfg.Stream)
+	retentionDays, err := strconv.Atoi(getEnv("AUDIT_RETENTION_DAYS", "90"))
+	if err != nil {
+		log.Panicf("Invalid AUDIT_RETENTION_DAYS: %s", err)
+	}
+	graceDays, err := strconv.Atoi(getEnv("CHIRPY_RED_GRACE_DAYS", "3"))
+	if err != nil {
+		log.Panicf("Invalid CHIRPY_RED_GRACE_DAYS: %s", err)
+	}
+	cfg.Subscriptions = db.SubscriptionPolicy{
+		Period:      db.DefaultSubscriptionPeriod,
+		GracePeriod: time.Duration(graceDays) * 24 * time.Hour,
+	}
+	insecureFederation := os.Getenv("FEDERATION_INSECURE") == "true"
+	if insecureFederation {
+		log.Printf("Federating over plain HTTP and private addresses\n")
+	}
+	cfg.Federation = activitypub.NewClient(insecureFederation)
+	cfg.Deliveries = activitypub.NewQueue(8, cfg.SendDelivery)
+	cfg.DeliveryRetries = []time.Duration{time.Minute, 10 * time.Minute, time.Hour}
+	cfg.Scheduler = scheduler.New(cfg.PublishScheduled)
+	cfg.Scheduler.Rebuild(cfg.DB.ScheduledChirps())
+	cfg.Plans, err = entitlements.Load(getEnv("PLANS_CONFIG", "./config/plans.json"))
+	if err != nil {
+		log.Panicf("Error Loading Plans: %s", err)
+	}
+	cfg.Moderation, err = moderation.Load(getEnv("MODERATION_CONFIG", "./config/moderation.json"))
+	if err != nil {
+		log.Panicf("Error Loading Moderation Config: %s", err)
+	}
+	cfg.Audit, err = audit.Open(cfg.Audit_Directory, time.Duration(retentionDays)*24*time.Hour)
+	if err != nil {
+		log.Panic(err)
+	}
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		cfg.OIDC = oidc.NewProvider(oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		})
+	}
+	cfg.WebAuthn = webauthn.New(webauthn.Config{
+		RPID:   getEnv("WEBAUTHN_RP_ID", "localhost"),
+		RPName: "Chirpy",
+		Origin: getEnv("WEBAUTHN_ORIGIN", "http://localhost:"+cfg.Port),
+	})
 	mux := http.NewServeMux()
 	server := initialiseServer(cfg, mux)
 
+	// Background jobs read cfg, so they only start once it is fully built.
+	go cfg.ExpireSubscriptions(time.Minute)
+	go cfg.Scheduler.Run()
+	cfg.QueueDeliveries()
+	go cfg.Moderation.Watch(10*time.Second, func(err error) {
+		log.Printf("Error Reloading Moderation Filter: %s", err)
+	})
+	go func() {
+		for range time.Tick(time.Hour) {
+			err := cfg.Audit.Prune()
+			if err != nil {
+				log.Printf("Error Pruning Audit Log: %s", err)
+			}
+		}
+	}()
+
 	log.Printf("Serving on port: %s\n", cfg.Port)
 	log.Panic(server.ListenAndServe())
 }